@@ -9,11 +9,14 @@ type domAttr struct {
 	attributes    NamedNodeMap
 	ownerDocument Document
 	namespaceURI  string
+	state         nodeState // The state the DOM keeps for this node.
 
 	// Attr specific things:
 	ownerElement Element
 	attrName     XMLName
 	attrValue    string
+	specified    bool // False if the attribute is a default value from the DTD.
+	isID         bool // True if the attribute is of type ID.
 }
 
 func newAttr(owner Document, name string, namespaceURI string) Attr {
@@ -21,6 +24,7 @@ func newAttr(owner Document, name string, namespaceURI string) Attr {
 	a.ownerDocument = owner
 	a.attrName = XMLName(name)
 	a.namespaceURI = namespaceURI
+	a.specified = true
 	return a
 }
 
@@ -142,18 +146,59 @@ func (da *domAttr) SetTextContent(content string) {
 
 // CloneNode on an individual Attr will have no owner element.
 func (da *domAttr) CloneNode(deep bool) Node {
-	clone, err := da.ownerDocument.CreateAttributeNS(da.namespaceURI, string(da.attrName))
+	return da.cloneNode(da.ownerDocument, deep, NodeCloned)
+}
+
+func (da *domAttr) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	clone, err := owner.CreateAttributeNS(da.namespaceURI, string(da.attrName))
 	if err != nil {
 		panic("crap!")
 	}
 	clone.SetValue(da.attrValue)
+	notifyUserDataHandlers(operation, da, clone)
 	return clone
 }
 
+func (da *domAttr) Normalize() {
+	normalize(da)
+}
+
+func (da *domAttr) IsSameNode(other Node) bool {
+	return isSameNode(da, other)
+}
+
+func (da *domAttr) IsEqualNode(other Node) bool {
+	return isEqualNode(da, other)
+}
+
+func (da *domAttr) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(da, other)
+}
+
+func (da *domAttr) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(da, key, data, handler)
+}
+
+func (da *domAttr) GetUserData(key string) interface{} {
+	return getUserData(da, key)
+}
+
 func (da *domAttr) ImportNode(n Node, deep bool) Node {
 	return importNode(da.ownerDocument, n, deep)
 }
 
+func (da *domAttr) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(da, eventType, listener, useCapture)
+}
+
+func (da *domAttr) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(da, eventType, listener, useCapture)
+}
+
+func (da *domAttr) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(da, evt)
+}
+
 // Private functions:
 func (da *domAttr) setParentNode(parent Node) {
 	// no-op
@@ -163,9 +208,29 @@ func (da *domAttr) GetName() string {
 	return da.GetNodeName()
 }
 
+// IsSpecified returns false if the attribute was not given a value in the document, but
+// has a default value from the DTD instead. Setting the value makes it specified.
 func (da *domAttr) IsSpecified() bool {
-	// TODO: what?
-	return true
+	return da.specified
+}
+
+// IsId returns true if the attribute is known to be of type ID, for example by its
+// declaration in the DTD, or because it is an xml:id attribute.
+func (da *domAttr) IsId() bool {
+	return da.isID || isXMLID(da)
+}
+
+func (da *domAttr) setSpecified(specified bool) {
+	da.specified = specified
+}
+
+func (da *domAttr) setID(isID bool) {
+	if da.isID == isID {
+		return
+	}
+	attributeChanging(da)
+	da.isID = isID
+	attributeChanged(da)
 }
 
 func (da *domAttr) setName(name string) {
@@ -183,7 +248,13 @@ func (da *domAttr) GetValue() string {
 }
 
 func (da *domAttr) SetValue(val string) {
+	if da.ownerElement != nil {
+		queueAttributeMutation(da.ownerElement, da, da.attrValue)
+	}
+	attributeChanging(da)
 	da.attrValue = val
+	da.specified = true
+	attributeChanged(da)
 }
 
 func (da *domAttr) setOwnerElement(owner Element) {
@@ -194,6 +265,10 @@ func (da *domAttr) setOwnerDocument(doc Document) {
 	da.ownerDocument = doc
 }
 
+func (da *domAttr) getState() *nodeState {
+	return &da.state
+}
+
 func (da *domAttr) String() string {
 	return fmt.Sprintf("%v, %v=%v", da.GetNodeType(), da.attrName, da.attrValue)
 }
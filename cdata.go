@@ -0,0 +1,30 @@
+package dom
+
+// domCDATASection is, like in the spec, for all intents and purposes a Text node.
+// The only difference is how it is serialized: the content is written as-is, within
+// a <![CDATA[ ... ]]> block, instead of being escaped. Therefore it embeds the domText
+// and only overrides the type, the name and the cloning.
+type domCDATASection struct {
+	domText
+}
+
+func newCDATASection(owner Document) CDATASection {
+	c := &domCDATASection{}
+	c.ownerDocument = owner
+	c.self = c
+	return c
+}
+
+func (dc *domCDATASection) GetNodeName() string {
+	return "#cdata-section"
+}
+
+func (dc *domCDATASection) GetNodeType() NodeType {
+	return CDATASectionNode
+}
+
+func (dc *domCDATASection) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	clone := owner.CreateCDATASection(dc.data)
+	notifyUserDataHandlers(operation, dc, clone)
+	return clone
+}
@@ -0,0 +1,80 @@
+package dom
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCDATASectionGetters(t *testing.T) {
+	doc := NewDocument()
+	parent, _ := doc.CreateElement("element")
+	cdata := doc.CreateCDATASection("<script>if (a < b) {}</script>")
+	doc.AppendChild(parent)
+	parent.AppendChild(cdata)
+
+	if cdata.GetNodeName() != "#cdata-section" {
+		t.Errorf("expected '#cdata-section', got '%v'", cdata.GetNodeName())
+	}
+	if cdata.GetNodeType() != CDATASectionNode {
+		t.Errorf("expected %v, got %v", CDATASectionNode, cdata.GetNodeType())
+	}
+	if cdata.GetNodeValue() != "<script>if (a < b) {}</script>" {
+		t.Errorf("unexpected node value '%v'", cdata.GetNodeValue())
+	}
+	if cdata.GetParentNode() != parent {
+		t.Error("incorrect parent node")
+	}
+	if cdata.GetOwnerDocument() != doc {
+		t.Error("incorrect owner document")
+	}
+	if cdata.HasChildNodes() || cdata.GetFirstChild() != nil || cdata.GetLastChild() != nil {
+		t.Error("CDATA sections cannot have children")
+	}
+	if err := cdata.AppendChild(doc.CreateText("meh")); err == nil {
+		t.Error("expected error, but got none")
+	}
+	if parent.GetTextContent() != "<script>if (a < b) {}</script>" {
+		t.Errorf("unexpected text content '%v'", parent.GetTextContent())
+	}
+	// The shared Text implementation must operate on the CDATA section, not on the Text inside it.
+	text := doc.CreateText("after")
+	parent.AppendChild(text)
+	if cdata.GetNextSibling() != text || text.GetPreviousSibling() != cdata || !cdata.IsSameNode(cdata) {
+		t.Error("incorrect siblings")
+	}
+	if s := fmt.Sprint(cdata); s != "CDATA_SECTION_NODE: '<script>if (a < b) {}</script>'" {
+		t.Errorf("unexpected string '%v'", s)
+	}
+	if err := doc.AppendChild(doc.CreateCDATASection("not allowed")); err == nil {
+		t.Error("expected error when appending a CDATA section to the document")
+	}
+
+	clone := cdata.CloneNode(true)
+	if clone.GetNodeType() != CDATASectionNode || clone.GetNodeValue() != cdata.GetNodeValue() {
+		t.Errorf("incorrect clone '%v'", clone)
+	}
+
+	other := NewDocument()
+	imported := other.ImportNode(cdata, true)
+	if imported.GetNodeType() != CDATASectionNode || imported.GetOwnerDocument() != other {
+		t.Errorf("incorrect imported node '%v'", imported)
+	}
+}
+
+func TestCDATASectionSplit(t *testing.T) {
+	var tests = []struct {
+		data     string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"a]]>b", "a]]]]><![CDATA[>b"},
+		{"]]>]]>", "]]]]><![CDATA[>]]]]><![CDATA[>"},
+		{"]]", "]]"},
+	}
+
+	for _, test := range tests {
+		if actual := splitCDATA(test.data); actual != test.expected {
+			t.Errorf("expected '%v', got '%v'", test.expected, actual)
+		}
+	}
+}
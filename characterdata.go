@@ -0,0 +1,258 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// isCharacterData returns true if the Node n holds character data, i.e. if it is a Text,
+// CDATASection, Comment or ProcessingInstruction node.
+func isCharacterData(n Node) bool {
+	switch n.GetNodeType() {
+	case TextNode, CDATASectionNode, CommentNode, ProcessingInstructionNode:
+		return true
+	}
+	return false
+}
+
+// dataLength returns the length of the string s in UTF-16 code units, which is the unit
+// the DOM uses for offsets into character data.
+func dataLength(s string) int {
+	length := 0
+	for _, r := range s {
+		length += utf16.RuneLen(r)
+	}
+	return length
+}
+
+// splitsSurrogatePair returns true if the offset in UTF-16 code units falls between the two
+// code units of a surrogate pair in the string s. Such an offset can not be represented in
+// a Go string, so it is treated as being out of bounds.
+func splitsSurrogatePair(s string, offset int) bool {
+	pos := 0
+	for _, r := range s {
+		if pos >= offset {
+			break
+		}
+		pos += utf16.RuneLen(r)
+	}
+	return pos > offset
+}
+
+// substringData returns count UTF-16 code units of the string s, starting at offset. The
+// offset and count must be within bounds.
+func substringData(s string, offset, count int) string {
+	units := utf16.Encode([]rune(s))
+	return string(utf16.Decode(units[offset : offset+count]))
+}
+
+// replaceData replaces count UTF-16 code units of the character data of the Node n,
+// starting at offset, with the given data. The live objects of the owner document are
+// updated accordingly. It returns an error if the offset is out of bounds, or if either
+// end splits a surrogate pair. A count going past the end of the data is clamped.
+func replaceData(n Node, offset, count int, data string) error {
+	old := n.GetNodeValue()
+	length := dataLength(old)
+	if offset < 0 || offset > length || count < 0 {
+		return ErrorIndexSize
+	}
+	if offset+count > length {
+		count = length - offset
+	}
+	if splitsSurrogatePair(old, offset) || splitsSurrogatePair(old, offset+count) {
+		return ErrorIndexSize
+	}
+
+	if doc := liveDocument(n); doc != nil {
+		doc.dataReplaced(n, offset, count, dataLength(data))
+	}
+	setCharacterData(n, substringData(old, 0, offset)+data+substringData(old, offset+count, length-offset-count))
+	return nil
+}
+
+// setCharacterData sets the character data of the Node n, without notifying anyone.
+func setCharacterData(n Node, data string) {
+	switch c := n.(type) {
+	case *domText:
+		c.data = data
+	case *domCDATASection:
+		c.data = data
+	case *domComment:
+		c.comment = data
+	case *domProcInst:
+		c.data = data
+	}
+}
+
+// splitText splits the Text (or CDATASection) node t in two at the given offset. The
+// data after the offset is moved into a new node of the same type, which is inserted as
+// the next sibling of t. The new node is returned. The offset may not split a surrogate pair.
+func splitText(t Node, offset int) (Node, error) {
+	data := t.GetNodeValue()
+	length := dataLength(data)
+	if offset < 0 || offset > length || splitsSurrogatePair(data, offset) {
+		return nil, ErrorIndexSize
+	}
+
+	// The new node is not a clone, as far as the user data handlers are concerned.
+	var newNode Node
+	if t.GetNodeType() == CDATASectionNode {
+		newNode = t.GetOwnerDocument().CreateCDATASection(substringData(data, offset, length-offset))
+	} else {
+		newNode = t.GetOwnerDocument().CreateText(substringData(data, offset, length-offset))
+	}
+	if parent := t.GetParentNode(); parent != nil {
+		if _, err := parent.InsertBefore(newNode, t.GetNextSibling()); err != nil {
+			return nil, err
+		}
+		if doc := liveDocument(t); doc != nil {
+			doc.textSplit(t, newNode, offset)
+		}
+	}
+	return newNode, replaceData(t, offset, length-offset, "")
+}
+
+// getSubstringData implements CharacterData.SubstringData for the Node n.
+func getSubstringData(n Node, offset, count int) (string, error) {
+	data := n.GetNodeValue()
+	length := dataLength(data)
+	if offset < 0 || offset > length || count < 0 {
+		return "", ErrorIndexSize
+	}
+	if offset+count > length {
+		count = length - offset
+	}
+	if splitsSurrogatePair(data, offset) || splitsSurrogatePair(data, offset+count) {
+		return "", ErrorIndexSize
+	}
+	return substringData(data, offset, count), nil
+}
+
+// modifyData implements the modifications of CharacterData for the Node n. Character data
+// in the replacement text of an entity can not be modified.
+func modifyData(n Node, offset, count int, data string) error {
+	if isReadOnly(n) {
+		return ErrorNoModificationAllowed
+	}
+	return replaceData(n, offset, count, data)
+}
+
+// isText returns true if the Node n is a Text or CDATASection node.
+func isText(n Node) bool {
+	return n.GetNodeType() == TextNode || n.GetNodeType() == CDATASectionNode
+}
+
+// adjacentText returns the Text nodes which are logically adjacent to the Text node t in
+// the given direction, nearest first. Logically adjacent text nodes may be separated by
+// entity references, as long as these only contain text. The siblings of t which hold
+// these nodes are returned as well. Partial is true when an entity reference sibling holds
+// adjacent text, followed by something else, so it can not be replaced as a whole.
+func adjacentText(t Node, forward bool) (texts, siblings []Node, partial bool) {
+	next := func(n Node) Node {
+		if forward {
+			return n.GetNextSibling()
+		}
+		return n.GetPreviousSibling()
+	}
+
+	for n := t; n != nil; n = n.GetParentNode() {
+		for s := next(n); s != nil; s = next(s) {
+			switch {
+			case isText(s):
+				texts = append(texts, s)
+			case s.GetNodeType() == EntityReferenceNode:
+				collected := len(texts)
+				if !collectText(s, forward, &texts) {
+					partial = n == t && len(texts) > collected
+					return
+				}
+			default:
+				return
+			}
+			if n == t {
+				siblings = append(siblings, s)
+			}
+		}
+		// The text continues after the end of an entity reference.
+		if p := n.GetParentNode(); p == nil || p.GetNodeType() != EntityReferenceNode {
+			return
+		}
+	}
+	return
+}
+
+// collectText appends the Text nodes in the entity reference n to texts, in the given
+// direction. It returns false if the entity reference contains anything other than text,
+// after appending the text up to that point.
+func collectText(n Node, forward bool, texts *[]Node) bool {
+	children := n.GetChildNodes()
+	for i := range children {
+		child := children[i]
+		if !forward {
+			child = children[len(children)-1-i]
+		}
+		switch {
+		case isText(child):
+			*texts = append(*texts, child)
+		case child.GetNodeType() == EntityReferenceNode:
+			if !collectText(child, forward, texts) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// wholeText returns the data of the Text node t, and of the text nodes logically adjacent
+// to it, in document order.
+func wholeText(t Node) string {
+	before, _, _ := adjacentText(t, false)
+	after, _, _ := adjacentText(t, true)
+
+	var sb strings.Builder
+	for i := len(before) - 1; i >= 0; i-- {
+		sb.WriteString(before[i].GetNodeValue())
+	}
+	sb.WriteString(t.GetNodeValue())
+	for _, n := range after {
+		sb.WriteString(n.GetNodeValue())
+	}
+	return sb.String()
+}
+
+// replaceWholeText replaces the data of the Text node t with the content, and removes the
+// text nodes logically adjacent to it. Entity references holding only adjacent text are
+// removed as a whole. When the content is empty, t is removed as well, and nil is returned.
+func replaceWholeText(t Node, content string) (Node, error) {
+	if isReadOnly(t) {
+		return nil, ErrorNoModificationAllowed
+	}
+	_, before, partialBefore := adjacentText(t, false)
+	_, after, partialAfter := adjacentText(t, true)
+	if partialBefore || partialAfter {
+		return nil, fmt.Errorf("%v: the adjacent text is part of an entity reference", ErrorNoModificationAllowed)
+	}
+
+	if parent := t.GetParentNode(); parent != nil {
+		for _, n := range append(before, after...) {
+			if _, err := parent.RemoveChild(n); err != nil {
+				return nil, err
+			}
+			notifyUserDataDeleted(n)
+		}
+		if content == "" {
+			if _, err := parent.RemoveChild(t); err != nil {
+				return nil, err
+			}
+			notifyUserDataDeleted(t)
+			return nil, nil
+		}
+	}
+	if content == "" {
+		return nil, nil
+	}
+	return t, replaceData(t, 0, dataLength(t.GetNodeValue()), content)
+}
@@ -11,6 +11,7 @@ type domComment struct {
 	localName     string
 	parentNode    Node
 	ownerDocument Document
+	state         nodeState // The state the DOM keeps for this node.
 
 	// Comment specific things
 	comment string
@@ -131,17 +132,58 @@ func (dc *domComment) SetTextContent(content string) {
 }
 
 func (dc *domComment) CloneNode(deep bool) Node {
-	cloneComment, err := dc.ownerDocument.CreateComment(dc.comment)
-	if err != nil {
-		panic("CreateComment returned error, but was unexpected at this point")
-	}
+	return dc.cloneNode(dc.ownerDocument, deep, NodeCloned)
+}
+
+// cloneNode copies the data directly instead of using CreateComment, since SetComment and
+// friends allow a double hyphen which CreateComment refuses.
+func (dc *domComment) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	cloneComment := newComment(owner)
+	setCharacterData(cloneComment, dc.comment)
+	notifyUserDataHandlers(operation, dc, cloneComment)
 	return cloneComment
 }
 
+func (dc *domComment) Normalize() {
+	normalize(dc)
+}
+
+func (dc *domComment) IsSameNode(other Node) bool {
+	return isSameNode(dc, other)
+}
+
+func (dc *domComment) IsEqualNode(other Node) bool {
+	return isEqualNode(dc, other)
+}
+
+func (dc *domComment) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(dc, other)
+}
+
+func (dc *domComment) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(dc, key, data, handler)
+}
+
+func (dc *domComment) GetUserData(key string) interface{} {
+	return getUserData(dc, key)
+}
+
 func (dc *domComment) ImportNode(n Node, deep bool) Node {
 	return importNode(dc.ownerDocument, n, deep)
 }
 
+func (dc *domComment) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(dc, eventType, listener, useCapture)
+}
+
+func (dc *domComment) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(dc, eventType, listener, useCapture)
+}
+
+func (dc *domComment) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(dc, evt)
+}
+
 // Private functions:
 func (dc *domComment) setParentNode(parent Node) {
 	dc.parentNode = parent
@@ -151,6 +193,10 @@ func (dc *domComment) setOwnerDocument(doc Document) {
 	dc.ownerDocument = doc
 }
 
+func (dc *domComment) getState() *nodeState {
+	return &dc.state
+}
+
 // Text specifics:
 
 // GetComment returns the comment content.
@@ -160,7 +206,38 @@ func (dc *domComment) GetComment() string {
 
 // SetComment sets the character comment data of the XML node.
 func (dc *domComment) SetComment(comment string) {
-	dc.comment = comment
+	replaceData(dc, 0, dataLength(dc.comment), comment)
+}
+
+// GetLength returns the length of the comment in UTF-16 code units.
+func (dc *domComment) GetLength() int {
+	return dataLength(dc.comment)
+}
+
+// SubstringData returns count UTF-16 code units of the comment, starting at offset.
+func (dc *domComment) SubstringData(offset, count int) (string, error) {
+	return getSubstringData(dc, offset, count)
+}
+
+// AppendData appends the string to the end of the comment.
+func (dc *domComment) AppendData(arg string) error {
+	return modifyData(dc, dataLength(dc.comment), 0, arg)
+}
+
+// InsertData inserts the string into the comment at the offset in UTF-16 code units.
+func (dc *domComment) InsertData(offset int, arg string) error {
+	return modifyData(dc, offset, 0, arg)
+}
+
+// DeleteData removes count UTF-16 code units from the comment, starting at offset.
+func (dc *domComment) DeleteData(offset, count int) error {
+	return modifyData(dc, offset, count, "")
+}
+
+// ReplaceData replaces count UTF-16 code units of the comment, starting at offset, with
+// the string.
+func (dc *domComment) ReplaceData(offset, count int, arg string) error {
+	return modifyData(dc, offset, count, arg)
 }
 
 func (dc *domComment) String() string {
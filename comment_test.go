@@ -94,3 +94,21 @@ func TestCommentSiblings(t *testing.T) {
 		t.Errorf("previous sibling of 'comment 3' should be 'comment 2', but was '%v'", sibling)
 	}
 }
+
+func TestCommentCloneDoubleHyphen(t *testing.T) {
+	doc := NewDocument()
+	cmt, _ := doc.CreateComment("a")
+	// CreateComment refuses a double hyphen, but modifying the data does not.
+	if err := cmt.AppendData("--b"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	clone := cmt.CloneNode(false)
+	if clone.GetNodeType() != CommentNode || clone.GetNodeValue() != "a--b" {
+		t.Errorf("incorrect clone '%v'", clone)
+	}
+	imported := NewDocument().ImportNode(cmt, false)
+	if imported == nil || imported.GetNodeValue() != "a--b" {
+		t.Errorf("incorrect imported node '%v'", imported)
+	}
+}
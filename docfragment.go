@@ -0,0 +1,340 @@
+package dom
+
+import (
+	"fmt"
+)
+
+// domDocumentFragment is a lightweight container of nodes. It never has a parent,
+// and when it is inserted into another Node, its children are moved to that Node
+// instead of the fragment itself.
+type domDocumentFragment struct {
+	nodes         []Node    // Child nodes.
+	ownerDocument Document  // Owner document.
+	state         nodeState // The state the DOM keeps for this node.
+}
+
+func newDocumentFragment(owner Document) DocumentFragment {
+	df := &domDocumentFragment{}
+	df.ownerDocument = owner
+	return df
+}
+
+func (df *domDocumentFragment) GetNodeName() string {
+	return "#document-fragment"
+}
+
+func (df *domDocumentFragment) GetNodeType() NodeType {
+	return DocumentFragmentNode
+}
+
+// GetNodeValue returns an empty string, since DocumentFragments have no value.
+func (df *domDocumentFragment) GetNodeValue() string {
+	return ""
+}
+
+func (df *domDocumentFragment) GetLocalName() string {
+	return ""
+}
+
+func (df *domDocumentFragment) GetChildNodes() []Node {
+	return df.nodes
+}
+
+// GetParentNode always returns nil, since a DocumentFragment can never be a child.
+func (df *domDocumentFragment) GetParentNode() Node {
+	return nil
+}
+
+func (df *domDocumentFragment) GetFirstChild() Node {
+	if df.HasChildNodes() {
+		return df.nodes[0]
+	}
+	return nil
+}
+
+func (df *domDocumentFragment) GetLastChild() Node {
+	if df.HasChildNodes() {
+		return df.nodes[len(df.nodes)-1]
+	}
+	return nil
+}
+
+func (df *domDocumentFragment) GetAttributes() NamedNodeMap {
+	return nil
+}
+
+func (df *domDocumentFragment) HasAttributes() bool {
+	return false
+}
+
+func (df *domDocumentFragment) GetOwnerDocument() Document {
+	return df.ownerDocument
+}
+
+// AppendChild appends the child to the fragment. Any kind of Node which is allowed
+// as a child of an Element is allowed as a child of a DocumentFragment.
+func (df *domDocumentFragment) AppendChild(child Node) error {
+	if child == nil {
+		return nil
+	}
+	if df == child {
+		return fmt.Errorf("%v: adding a node to itself as a child", ErrorHierarchyRequest)
+	}
+	if child.GetNodeType() == AttributeNode || child.GetNodeType() == DocumentNode || child.GetNodeType() == DocumentTypeNode {
+		return fmt.Errorf("%v: an attempt was made to insert a node where it is not permitted", ErrorHierarchyRequest)
+	}
+	if child.GetOwnerDocument() != df.GetOwnerDocument() {
+		return ErrorWrongDocument
+	}
+
+	// Appending a fragment to a fragment moves the children.
+	if child.GetNodeType() == DocumentFragmentNode {
+		return insertFragment(df, child, nil)
+	}
+
+	parent := child.GetParentNode()
+	if parent != nil {
+		parent.RemoveChild(child)
+	}
+
+	child.setParentNode(df)
+	df.nodes = append(df.nodes, child)
+	notifyInserted(df, child)
+	return nil
+}
+
+func (df *domDocumentFragment) RemoveChild(oldChild Node) (Node, error) {
+	if oldChild == nil {
+		return nil, nil
+	}
+
+	for i, child := range df.GetChildNodes() {
+		if child == oldChild {
+			notifyRemoving(df, child)
+			df.nodes = append(df.nodes[:i], df.nodes[i+1:]...)
+			child.setParentNode(nil)
+			return child, nil
+		}
+	}
+
+	return nil, ErrorNotFound
+}
+
+func (df *domDocumentFragment) ReplaceChild(newChild, oldChild Node) (Node, error) {
+	if newChild == nil {
+		return nil, fmt.Errorf("%v: given new child is nil", ErrorHierarchyRequest)
+	}
+	if oldChild == nil {
+		return nil, fmt.Errorf("%v: given old child is nil", ErrorHierarchyRequest)
+	}
+	if newChild.GetNodeType() == AttributeNode || newChild.GetNodeType() == DocumentNode || newChild.GetNodeType() == DocumentTypeNode {
+		return nil, ErrorHierarchyRequest
+	}
+	if newChild.GetOwnerDocument() != df.GetOwnerDocument() {
+		return nil, ErrorWrongDocument
+	}
+
+	if newChild.GetNodeType() == DocumentFragmentNode {
+		return replaceWithFragment(df, newChild, oldChild)
+	}
+
+	for i, child := range df.GetChildNodes() {
+		if child == oldChild {
+			if newChild == oldChild {
+				// Replacing a child with itself changes nothing.
+				return oldChild, nil
+			}
+			ncParent := newChild.GetParentNode()
+			if ncParent != nil {
+				ncParent.RemoveChild(newChild)
+			}
+
+			notifyRemoving(df, oldChild)
+
+			// The index may have shifted when newChild was a preceding sibling.
+			i = indexOf(df.nodes, oldChild)
+			df.nodes[i] = newChild
+			newChild.setParentNode(df)
+			oldChild.setParentNode(nil)
+			notifyInserted(df, newChild)
+			return oldChild, nil
+		}
+	}
+
+	return nil, ErrorNotFound
+}
+
+func (df *domDocumentFragment) InsertBefore(newChild, refChild Node) (Node, error) {
+	if newChild == nil {
+		return nil, ErrorHierarchyRequest
+	}
+	if refChild == nil {
+		if err := df.AppendChild(newChild); err != nil {
+			return nil, err
+		}
+		return newChild, nil
+	}
+	if newChild.GetNodeType() == AttributeNode || newChild.GetNodeType() == DocumentNode || newChild.GetNodeType() == DocumentTypeNode {
+		return nil, ErrorHierarchyRequest
+	}
+	if newChild.GetOwnerDocument() != df.GetOwnerDocument() {
+		return nil, ErrorWrongDocument
+	}
+	if indexOf(df.nodes, refChild) < 0 {
+		return nil, ErrorNotFound
+	}
+
+	if newChild.GetNodeType() == DocumentFragmentNode {
+		if err := insertFragment(df, newChild, refChild); err != nil {
+			return nil, err
+		}
+		return newChild, nil
+	}
+
+	ncParent := newChild.GetParentNode()
+	if ncParent != nil {
+		ncParent.RemoveChild(newChild)
+	}
+	i := indexOf(df.nodes, refChild)
+	newChild.setParentNode(df)
+	df.nodes = append(df.nodes[:i], append([]Node{newChild}, df.nodes[i:]...)...)
+	notifyInserted(df, newChild)
+	return newChild, nil
+}
+
+func (df *domDocumentFragment) HasChildNodes() bool {
+	return len(df.nodes) > 0
+}
+
+// GetPreviousSibling always returns nil, since a fragment has no parent.
+func (df *domDocumentFragment) GetPreviousSibling() Node {
+	return nil
+}
+
+// GetNextSibling always returns nil, since a fragment has no parent.
+func (df *domDocumentFragment) GetNextSibling() Node {
+	return nil
+}
+
+func (df *domDocumentFragment) GetNamespaceURI() string {
+	return ""
+}
+
+func (df *domDocumentFragment) GetNamespacePrefix() string {
+	return ""
+}
+
+// LookupPrefix returns false at all times, since the namespace algorithms in the
+// spec regard a DocumentFragment as having an unknown prefix.
+func (df *domDocumentFragment) LookupPrefix(namespace string) (string, bool) {
+	return "", false
+}
+
+// LookupNamespaceURI returns false at all times, see LookupPrefix.
+func (df *domDocumentFragment) LookupNamespaceURI(pfx string) (string, bool) {
+	return "", false
+}
+
+func (df *domDocumentFragment) IsDefaultNamespace(namespace string) bool {
+	return false
+}
+
+// GetTextContent returns the concatenation of the text content of every child,
+// excluding comments and processing instructions.
+func (df *domDocumentFragment) GetTextContent() string {
+	textContent := ""
+	for _, child := range df.GetChildNodes() {
+		if child.GetNodeType() == CommentNode || child.GetNodeType() == ProcessingInstructionNode {
+			continue
+		}
+		textContent += child.GetTextContent()
+	}
+	return textContent
+}
+
+// SetTextContent removes all children of the fragment, and replaces them with a
+// single Text node containing the content, if the content is not empty.
+func (df *domDocumentFragment) SetTextContent(content string) {
+	for len(df.nodes) > 0 {
+		df.RemoveChild(df.nodes[0])
+	}
+
+	if content == "" {
+		return
+	}
+	df.AppendChild(df.GetOwnerDocument().CreateText(content))
+}
+
+// CloneNode creates a new DocumentFragment. If deep is true, all the children are
+// cloned recursively as well.
+func (df *domDocumentFragment) CloneNode(deep bool) Node {
+	return df.cloneNode(df.ownerDocument, deep, NodeCloned)
+}
+
+func (df *domDocumentFragment) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	clone := owner.CreateDocumentFragment()
+	if deep {
+		for _, child := range df.GetChildNodes() {
+			clone.AppendChild(child.cloneNode(owner, true, operation))
+		}
+	}
+	notifyUserDataHandlers(operation, df, clone)
+	return clone
+}
+
+func (df *domDocumentFragment) Normalize() {
+	normalize(df)
+}
+
+func (df *domDocumentFragment) IsSameNode(other Node) bool {
+	return isSameNode(df, other)
+}
+
+func (df *domDocumentFragment) IsEqualNode(other Node) bool {
+	return isEqualNode(df, other)
+}
+
+func (df *domDocumentFragment) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(df, other)
+}
+
+func (df *domDocumentFragment) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(df, key, data, handler)
+}
+
+func (df *domDocumentFragment) GetUserData(key string) interface{} {
+	return getUserData(df, key)
+}
+
+func (df *domDocumentFragment) ImportNode(n Node, deep bool) Node {
+	return importNode(df.ownerDocument, n, deep)
+}
+
+func (df *domDocumentFragment) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(df, eventType, listener, useCapture)
+}
+
+func (df *domDocumentFragment) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(df, eventType, listener, useCapture)
+}
+
+func (df *domDocumentFragment) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(df, evt)
+}
+
+// Private functions:
+func (df *domDocumentFragment) setParentNode(parent Node) {
+	// no-op
+}
+
+func (df *domDocumentFragment) setOwnerDocument(doc Document) {
+	df.ownerDocument = doc
+}
+
+func (df *domDocumentFragment) getState() *nodeState {
+	return &df.state
+}
+
+func (df *domDocumentFragment) String() string {
+	return fmt.Sprintf("%s", df.GetNodeType())
+}
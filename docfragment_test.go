@@ -0,0 +1,267 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocumentFragmentGetters(t *testing.T) {
+	doc := NewDocument()
+	frag := doc.CreateDocumentFragment()
+
+	if frag.GetNodeName() != "#document-fragment" {
+		t.Errorf("expected '#document-fragment', got '%v'", frag.GetNodeName())
+	}
+	if frag.GetNodeType() != DocumentFragmentNode {
+		t.Errorf("expected %v, got %v", DocumentFragmentNode, frag.GetNodeType())
+	}
+	if frag.GetNodeValue() != "" {
+		t.Error("node value should be an empty string")
+	}
+	if frag.GetOwnerDocument() != doc {
+		t.Error("incorrect owner document")
+	}
+	if frag.GetParentNode() != nil {
+		t.Error("fragments cannot have a parent")
+	}
+	if frag.GetAttributes() != nil {
+		t.Error("fragments cannot have attributes")
+	}
+	if frag.HasChildNodes() {
+		t.Error("expected no child nodes")
+	}
+	if frag.GetFirstChild() != nil || frag.GetLastChild() != nil {
+		t.Error("expected nil first and last child")
+	}
+	if err := frag.AppendChild(frag); err == nil {
+		t.Error("expected error when appending the fragment to itself")
+	}
+	attr, _ := doc.CreateAttribute("attr")
+	if err := frag.AppendChild(attr); err == nil {
+		t.Error("expected error when appending an attribute")
+	}
+	other := NewDocument()
+	otherElem, _ := other.CreateElement("other")
+	if err := frag.AppendChild(otherElem); err != ErrorWrongDocument {
+		t.Errorf("expected ErrorWrongDocument, got '%v'", err)
+	}
+}
+
+func TestDocumentFragmentAppendToElement(t *testing.T) {
+	doc := NewDocument()
+	root, _ := doc.CreateElement("root")
+	doc.AppendChild(root)
+	first, _ := doc.CreateElement("first")
+	root.AppendChild(first)
+
+	frag := doc.CreateDocumentFragment()
+	a, _ := doc.CreateElement("a")
+	b := doc.CreateText("b")
+	c, _ := doc.CreateElement("c")
+	frag.AppendChild(a)
+	frag.AppendChild(b)
+	frag.AppendChild(c)
+
+	if a.GetParentNode() != frag {
+		t.Error("expected the fragment as parent")
+	}
+
+	if err := root.AppendChild(frag); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+
+	if frag.HasChildNodes() {
+		t.Error("fragment should be empty after appending")
+	}
+
+	expected := []Node{first, a, b, c}
+	if len(root.GetChildNodes()) != len(expected) {
+		t.Errorf("expected %d children, got %d", len(expected), len(root.GetChildNodes()))
+		t.FailNow()
+	}
+	for i, n := range expected {
+		if root.GetChildNodes()[i] != n {
+			t.Errorf("child %d: expected '%v', got '%v'", i, n, root.GetChildNodes()[i])
+		}
+		if n.GetParentNode() != root {
+			t.Errorf("child %d: incorrect parent '%v'", i, n.GetParentNode())
+		}
+	}
+}
+
+func TestDocumentFragmentInsertBeforeAndReplace(t *testing.T) {
+	doc := NewDocument()
+	root, _ := doc.CreateElement("root")
+	x, _ := doc.CreateElement("x")
+	y, _ := doc.CreateElement("y")
+	root.AppendChild(x)
+	root.AppendChild(y)
+
+	frag := doc.CreateDocumentFragment()
+	a, _ := doc.CreateElement("a")
+	b, _ := doc.CreateElement("b")
+	frag.AppendChild(a)
+	frag.AppendChild(b)
+
+	if _, err := root.InsertBefore(frag, y); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	assertNames := func(expected string) {
+		var names []string
+		for _, c := range root.GetChildNodes() {
+			names = append(names, c.GetNodeName())
+		}
+		if actual := strings.Join(names, ","); actual != expected {
+			t.Errorf("expected '%v', got '%v'", expected, actual)
+		}
+	}
+	assertNames("x,a,b,y")
+
+	frag2 := doc.CreateDocumentFragment()
+	c, _ := doc.CreateElement("c")
+	d, _ := doc.CreateElement("d")
+	frag2.AppendChild(c)
+	frag2.AppendChild(d)
+
+	old, err := root.ReplaceChild(frag2, a)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if old != a {
+		t.Errorf("expected the old child to be returned, got '%v'", old)
+	}
+	if a.GetParentNode() != nil {
+		t.Error("replaced child should not have a parent anymore")
+	}
+	assertNames("x,c,d,b,y")
+
+	// Inserting before an unknown reference child should leave the fragment as-is.
+	frag3 := doc.CreateDocumentFragment()
+	e, _ := doc.CreateElement("e")
+	frag3.AppendChild(e)
+	unrelated, _ := doc.CreateElement("unrelated")
+	if _, err := root.InsertBefore(frag3, unrelated); err != ErrorNotFound {
+		t.Errorf("expected ErrorNotFound, got '%v'", err)
+	}
+	if len(frag3.GetChildNodes()) != 1 {
+		t.Error("fragment should not have been modified")
+	}
+}
+
+func TestDocumentFragmentReplaceChildWithItself(t *testing.T) {
+	doc := NewDocument()
+	frag := doc.CreateDocumentFragment()
+	a, _ := doc.CreateElement("a")
+	b, _ := doc.CreateElement("b")
+	frag.AppendChild(a)
+	frag.AppendChild(b)
+
+	old, err := frag.ReplaceChild(a, a)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if old != a {
+		t.Errorf("expected the old child to be returned, got '%v'", old)
+	}
+	if a.GetParentNode() != frag {
+		t.Error("child should still be attached to the fragment")
+	}
+	children := frag.GetChildNodes()
+	if len(children) != 2 || children[0] != a || children[1] != b {
+		t.Errorf("expected children to be unchanged, got %v", children)
+	}
+}
+
+func TestDocumentFragmentAppendToDocument(t *testing.T) {
+	doc := NewDocument()
+	frag := doc.CreateDocumentFragment()
+	cmt, _ := doc.CreateComment("comment")
+	root, _ := doc.CreateElement("root")
+	frag.AppendChild(cmt)
+	frag.AppendChild(root)
+
+	if err := doc.AppendChild(frag); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if doc.GetDocumentElement() != root {
+		t.Error("expected 'root' as document element")
+	}
+	if doc.GetFirstChild() != cmt {
+		t.Error("expected the comment as first child")
+	}
+
+	// Second document element must fail, and leave the fragment intact.
+	frag2 := doc.CreateDocumentFragment()
+	pi, _ := doc.CreateProcessingInstruction("target", "data")
+	root2, _ := doc.CreateElement("root2")
+	frag2.AppendChild(pi)
+	frag2.AppendChild(root2)
+	if err := doc.AppendChild(frag2); err == nil {
+		t.Error("expected a hierarchy error")
+	}
+	if len(frag2.GetChildNodes()) != 2 {
+		t.Errorf("expected the fragment to be untouched, got %d children", len(frag2.GetChildNodes()))
+	}
+
+	// Text is never allowed in a Document.
+	frag3 := doc.CreateDocumentFragment()
+	frag3.AppendChild(doc.CreateText("text"))
+	if _, err := doc.InsertBefore(frag3, root); err == nil {
+		t.Error("expected a hierarchy error")
+	}
+
+	// Replacing the document element with a fragment containing an element is fine.
+	if _, err := doc.ReplaceChild(frag2, root); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if doc.GetDocumentElement() != root2 {
+		t.Error("expected 'root2' as document element")
+	}
+}
+
+func TestDocumentFragmentCloneImportSerialize(t *testing.T) {
+	doc := NewDocument()
+	frag := doc.CreateDocumentFragment()
+	a, _ := doc.CreateElement("a")
+	a.SetAttribute("attr", "value")
+	a.AppendChild(doc.CreateText("text"))
+	b, _ := doc.CreateElement("b")
+	frag.AppendChild(a)
+	frag.AppendChild(b)
+
+	if frag.GetTextContent() != "text" {
+		t.Errorf("expected 'text', got '%v'", frag.GetTextContent())
+	}
+
+	shallow := frag.CloneNode(false)
+	if shallow.GetNodeType() != DocumentFragmentNode || shallow.HasChildNodes() {
+		t.Error("expected an empty fragment for a shallow clone")
+	}
+	deep := frag.CloneNode(true)
+	if len(deep.GetChildNodes()) != 2 {
+		t.Errorf("expected 2 children, got %d", len(deep.GetChildNodes()))
+		t.FailNow()
+	}
+	if deep.GetFirstChild() == a {
+		t.Error("clone should not contain the original children")
+	}
+
+	other := NewDocument()
+	imported := other.ImportNode(frag, true)
+	if imported.GetOwnerDocument() != other {
+		t.Error("incorrect owner document of imported fragment")
+	}
+	if imported.GetFirstChild().GetOwnerDocument() != other {
+		t.Error("incorrect owner document of imported child")
+	}
+
+	ser := NewSerializer()
+	ser.Configuration.OmitXMLDeclaration = true
+	var sb strings.Builder
+	ser.Serialize(frag, &sb)
+	expected := `<a attr="value">text</a><b/>`
+	if sb.String() != expected {
+		t.Errorf("expected '%v', got '%v'", expected, sb.String())
+	}
+}
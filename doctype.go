@@ -0,0 +1,254 @@
+package dom
+
+import (
+	"fmt"
+)
+
+type domDocumentType struct {
+	parentNode    Node
+	ownerDocument Document
+	state         nodeState // The state the DOM keeps for this node.
+
+	// DocumentType specific things:
+	name           string       // The name following the DOCTYPE keyword.
+	publicID       string       // Public identifier of the external subset.
+	systemID       string       // System identifier of the external subset.
+	internalSubset string       // The internal subset as a string, without the square brackets.
+	entities       NamedNodeMap // General entities declared in the DTD.
+
+	elements   map[string]*elementDecl     // Element type declarations, by element name.
+	attributes map[string][]*attributeDecl // Attribute-list declarations, by element name.
+	external   bool                        // True when the external subset has been read.
+}
+
+func newDocumentType(owner Document, name, publicID, systemID string) *domDocumentType {
+	dt := &domDocumentType{}
+	dt.ownerDocument = owner
+	dt.name = name
+	dt.publicID = publicID
+	dt.systemID = systemID
+	dt.entities = newNamedNodeMapOf(EntityNode)
+	dt.elements = make(map[string]*elementDecl)
+	dt.attributes = make(map[string][]*attributeDecl)
+	return dt
+}
+
+func (dt *domDocumentType) GetNodeName() string {
+	return dt.GetName()
+}
+
+func (dt *domDocumentType) GetNodeType() NodeType {
+	return DocumentTypeNode
+}
+
+// GetNodeValue should return null/nil for DocumentType nodes like the spec says,
+// but Go does not permit nil strings. Returns an empty string at all times.
+func (dt *domDocumentType) GetNodeValue() string {
+	return ""
+}
+
+func (dt *domDocumentType) GetLocalName() string {
+	return ""
+}
+
+func (dt *domDocumentType) GetChildNodes() []Node {
+	return nil
+}
+
+func (dt *domDocumentType) GetParentNode() Node {
+	return dt.parentNode
+}
+
+func (dt *domDocumentType) GetFirstChild() Node {
+	return nil
+}
+
+func (dt *domDocumentType) GetLastChild() Node {
+	return nil
+}
+
+func (dt *domDocumentType) GetAttributes() NamedNodeMap {
+	return nil
+}
+
+func (dt *domDocumentType) HasAttributes() bool {
+	return false
+}
+
+func (dt *domDocumentType) GetOwnerDocument() Document {
+	return dt.ownerDocument
+}
+
+func (dt *domDocumentType) AppendChild(child Node) error {
+	return fmt.Errorf("%v: %v does not allow children", ErrorHierarchyRequest, dt.GetNodeType())
+}
+
+func (dt *domDocumentType) RemoveChild(oldChild Node) (Node, error) {
+	return nil, ErrorHierarchyRequest
+}
+
+func (dt *domDocumentType) ReplaceChild(newChild, oldChild Node) (Node, error) {
+	return nil, ErrorHierarchyRequest
+}
+
+func (dt *domDocumentType) InsertBefore(newChild, refChild Node) (Node, error) {
+	return nil, ErrorHierarchyRequest
+}
+
+func (dt *domDocumentType) HasChildNodes() bool {
+	return false
+}
+
+func (dt *domDocumentType) GetPreviousSibling() Node {
+	return getPreviousSibling(dt)
+}
+
+func (dt *domDocumentType) GetNextSibling() Node {
+	return getNextSibling(dt)
+}
+
+func (dt *domDocumentType) GetNamespaceURI() string {
+	return ""
+}
+
+func (dt *domDocumentType) GetNamespacePrefix() string {
+	return ""
+}
+
+func (dt *domDocumentType) LookupPrefix(namespace string) (string, bool) {
+	return "", false
+}
+
+func (dt *domDocumentType) LookupNamespaceURI(pfx string) (string, bool) {
+	return "", false
+}
+
+func (dt *domDocumentType) IsDefaultNamespace(namespace string) bool {
+	return false
+}
+
+// GetTextContent should return null, but Go doesn't allow null strings so this method
+// will return an empty string.
+func (dt *domDocumentType) GetTextContent() string {
+	return ""
+}
+
+// SetTextContent does nothing on a DocumentType Node.
+func (dt *domDocumentType) SetTextContent(content string) {
+	// no-op.
+}
+
+// CloneNode creates a copy of the DocumentType, including the internal subset and the
+// declarations. The clone has no parent.
+func (dt *domDocumentType) CloneNode(deep bool) Node {
+	return dt.cloneNode(dt.ownerDocument, deep, NodeCloned)
+}
+
+func (dt *domDocumentType) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	clone := newDocumentType(owner, dt.name, dt.publicID, dt.systemID)
+	clone.internalSubset = dt.internalSubset
+	clone.external = dt.external
+	// Declarations are never modified after parsing, so they can be shared.
+	for name, decl := range dt.elements {
+		clone.elements[name] = decl
+	}
+	for name, decls := range dt.attributes {
+		clone.attributes[name] = decls
+	}
+	for i := 0; i < dt.entities.Length(); i++ {
+		entity := dt.entities.Item(i)
+		clone.entities.SetNamedItem(entity.cloneNode(owner, true, operation))
+	}
+	notifyUserDataHandlers(operation, dt, clone)
+	return clone
+}
+
+func (dt *domDocumentType) Normalize() {
+	normalize(dt)
+}
+
+func (dt *domDocumentType) IsSameNode(other Node) bool {
+	return isSameNode(dt, other)
+}
+
+func (dt *domDocumentType) IsEqualNode(other Node) bool {
+	return isEqualNode(dt, other)
+}
+
+func (dt *domDocumentType) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(dt, other)
+}
+
+func (dt *domDocumentType) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(dt, key, data, handler)
+}
+
+func (dt *domDocumentType) GetUserData(key string) interface{} {
+	return getUserData(dt, key)
+}
+
+func (dt *domDocumentType) ImportNode(n Node, deep bool) Node {
+	return importNode(dt.ownerDocument, n, deep)
+}
+
+func (dt *domDocumentType) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(dt, eventType, listener, useCapture)
+}
+
+func (dt *domDocumentType) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(dt, eventType, listener, useCapture)
+}
+
+func (dt *domDocumentType) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(dt, evt)
+}
+
+// Private functions:
+func (dt *domDocumentType) setParentNode(parent Node) {
+	dt.parentNode = parent
+}
+
+func (dt *domDocumentType) setOwnerDocument(doc Document) {
+	dt.ownerDocument = doc
+	for i := 0; i < dt.entities.Length(); i++ {
+		entity := dt.entities.Item(i)
+		setOwnerDocumentDeep(entity, doc)
+	}
+}
+
+func (dt *domDocumentType) getState() *nodeState {
+	return &dt.state
+}
+
+// DocumentType specifics:
+
+// GetName returns the name of the DTD, i.e. the name immediately following the DOCTYPE keyword.
+func (dt *domDocumentType) GetName() string {
+	return dt.name
+}
+
+// GetPublicID returns the public identifier of the external subset, if any.
+func (dt *domDocumentType) GetPublicID() string {
+	return dt.publicID
+}
+
+// GetSystemID returns the system identifier of the external subset, if any.
+func (dt *domDocumentType) GetSystemID() string {
+	return dt.systemID
+}
+
+// GetInternalSubset returns the internal subset as a string, without the delimiting
+// square brackets. Returns an empty string if there is no internal subset.
+func (dt *domDocumentType) GetInternalSubset() string {
+	return dt.internalSubset
+}
+
+// GetEntities returns a NamedNodeMap containing the general entities, both external
+// and internal, declared in the DTD. Parameter entities are not contained.
+func (dt *domDocumentType) GetEntities() NamedNodeMap {
+	return dt.entities
+}
+
+func (dt *domDocumentType) String() string {
+	return fmt.Sprintf("%s, name=%s, public=%s, system=%s", dt.GetNodeType(), dt.name, dt.publicID, dt.systemID)
+}
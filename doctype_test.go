@@ -0,0 +1,128 @@
+package dom
+
+import (
+	"testing"
+)
+
+func TestDocumentTypeGetters(t *testing.T) {
+	doc := NewDocument()
+	doctype := newDocumentType(doc, "html", "-//W3C//DTD XHTML 1.0 Strict//EN", "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd")
+	if err := doc.AppendChild(doctype); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if doctype.GetNodeName() != "html" {
+		t.Errorf("expected 'html', got '%v'", doctype.GetNodeName())
+	}
+	if doctype.GetNodeType() != DocumentTypeNode {
+		t.Errorf("expected %v, got %v", DocumentTypeNode, doctype.GetNodeType())
+	}
+	if doctype.GetPublicID() != "-//W3C//DTD XHTML 1.0 Strict//EN" {
+		t.Errorf("unexpected public ID '%v'", doctype.GetPublicID())
+	}
+	if doctype.GetSystemID() != "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd" {
+		t.Errorf("unexpected system ID '%v'", doctype.GetSystemID())
+	}
+	if doctype.GetParentNode() != doc {
+		t.Error("incorrect parent node")
+	}
+	if doc.GetDoctype() != doctype {
+		t.Error("expected the doctype to be returned by GetDoctype()")
+	}
+	if doctype.HasChildNodes() || doctype.GetAttributes() != nil {
+		t.Error("document types cannot have children or attributes")
+	}
+	if err := doctype.AppendChild(doc.CreateText("nope")); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	clone := doctype.CloneNode(true).(DocumentType)
+	if clone.GetName() != "html" || clone.GetSystemID() != doctype.GetSystemID() {
+		t.Errorf("incorrect clone '%v'", clone)
+	}
+}
+
+func TestDocumentTypeHierarchy(t *testing.T) {
+	doc := NewDocument()
+	root, _ := doc.CreateElement("root")
+	doc.AppendChild(root)
+
+	// Doctype after the document element is not allowed.
+	if err := doc.AppendChild(newDocumentType(doc, "root", "", "")); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	// Before the document element is OK, but only once.
+	doctype := newDocumentType(doc, "root", "", "")
+	if _, err := doc.InsertBefore(doctype, root); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := doc.InsertBefore(newDocumentType(doc, "root", "", ""), root); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	// The document element cannot be moved before the doctype.
+	cmt, _ := doc.CreateComment("comment")
+	doc.InsertBefore(cmt, doctype)
+	doc.RemoveChild(root)
+	if _, err := doc.InsertBefore(root, doctype); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	// Document types cannot be inserted into elements.
+	elem, _ := doc.CreateElement("elem")
+	if err := elem.AppendChild(newDocumentType(doc, "root", "", "")); err == nil {
+		t.Error("expected error, got none")
+	}
+}
+
+func TestDocumentTypeParseDoctype(t *testing.T) {
+	var tests = []struct {
+		directive      string
+		name           string
+		publicID       string
+		systemID       string
+		internalSubset string
+	}{
+		{"DOCTYPE html", "html", "", "", ""},
+		{"DOCTYPE root SYSTEM \"root.dtd\"", "root", "", "root.dtd", ""},
+		{"DOCTYPE root PUBLIC '-//Example//EN' 'root.dtd'", "root", "-//Example//EN", "root.dtd", ""},
+		{"DOCTYPE root [<!ELEMENT root (#PCDATA)>]", "root", "", "", "<!ELEMENT root (#PCDATA)>"},
+		{"DOCTYPE root SYSTEM \"root.dtd\" [\n  <!ENTITY a \"[b]\">\n] ", "root", "", "root.dtd", "\n  <!ENTITY a \"[b]\">\n"},
+	}
+
+	doc := NewDocument()
+	for _, test := range tests {
+		doctype, err := parseDoctype(doc, test.directive)
+		if err != nil {
+			t.Errorf("unexpected error for '%v': %v", test.directive, err)
+			continue
+		}
+		if doctype.GetName() != test.name {
+			t.Errorf("expected name '%v', got '%v'", test.name, doctype.GetName())
+		}
+		if doctype.GetPublicID() != test.publicID {
+			t.Errorf("expected public ID '%v', got '%v'", test.publicID, doctype.GetPublicID())
+		}
+		if doctype.GetSystemID() != test.systemID {
+			t.Errorf("expected system ID '%v', got '%v'", test.systemID, doctype.GetSystemID())
+		}
+		if doctype.GetInternalSubset() != test.internalSubset {
+			t.Errorf("expected internal subset '%v', got '%v'", test.internalSubset, doctype.GetInternalSubset())
+		}
+	}
+
+	var errTests = []string{
+		"DOCTYPE",
+		"DOCTYPE <invalid",
+		"DOCTYPE root SYSTEM",
+		"DOCTYPE root PUBLIC \"pub\"",
+		"DOCTYPE root [ unterminated",
+		"DOCTYPE root trailing",
+	}
+	for _, directive := range errTests {
+		if _, err := parseDoctype(doc, directive); err == nil {
+			t.Errorf("expected error for '%v'", directive)
+		}
+	}
+}
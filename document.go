@@ -17,6 +17,14 @@ import (
 
 type domDocument struct {
 	nodes []Node
+	state nodeState // The state the DOM keeps for this node.
+
+	iterators []*domNodeIterator // The NodeIterators which are not detached.
+	ranges    []*domRange        // The Ranges which are not detached.
+
+	pendingObservers []*domMutationObserver // The MutationObservers with queued records.
+	ids              map[string][]Element   // The elements by ID, or nil when the index is not built yet.
+	config           *domConfiguration      // The configuration of NormalizeDocument, created when first used.
 }
 
 // NewDocument creates a new Document which can be used to create
@@ -98,6 +106,15 @@ func (dd *domDocument) AppendChild(child Node) error {
 		return ErrorWrongDocument
 	}
 
+	// Appending a DocumentFragment appends all its children, as long as the
+	// result is still a valid Document.
+	if child.GetNodeType() == DocumentFragmentNode {
+		if err := dd.checkFragment(child, nil); err != nil {
+			return err
+		}
+		return insertFragment(dd, child, nil)
+	}
+
 	if child.GetNodeType() == ElementNode {
 		// Check if a Document element is already appended.
 		if dd.GetDocumentElement() != nil {
@@ -105,7 +122,17 @@ func (dd *domDocument) AppendChild(child Node) error {
 		}
 	}
 
-	if child.GetNodeType() == AttributeNode || child.GetNodeType() == TextNode {
+	// Only one DocumentType is allowed, and it must precede the document element.
+	if child.GetNodeType() == DocumentTypeNode {
+		if dd.GetDoctype() != nil {
+			return fmt.Errorf("%v: a DocumentType already exists", ErrorHierarchyRequest)
+		}
+		if dd.GetDocumentElement() != nil {
+			return fmt.Errorf("%v: the DocumentType must precede the document element", ErrorHierarchyRequest)
+		}
+	}
+
+	if child.GetNodeType() == AttributeNode || child.GetNodeType() == TextNode || child.GetNodeType() == CDATASectionNode {
 		return ErrorHierarchyRequest
 	}
 
@@ -117,6 +144,7 @@ func (dd *domDocument) AppendChild(child Node) error {
 
 	child.setParentNode(dd)
 	dd.nodes = append(dd.nodes, child)
+	dd.nodeInserted(child)
 	return nil
 }
 
@@ -127,8 +155,10 @@ func (dd *domDocument) RemoveChild(oldChild Node) (Node, error) {
 
 	for i, child := range dd.GetChildNodes() {
 		if child == oldChild {
+			dd.nodeRemoving(child)
 			// Slice trickery to remove the node at the found index:
 			dd.nodes = append(dd.nodes[:i], dd.nodes[i+1:]...)
+			child.setParentNode(nil)
 			return child, nil
 		}
 	}
@@ -143,7 +173,7 @@ func (dd *domDocument) ReplaceChild(newChild, oldChild Node) (Node, error) {
 	if oldChild == nil {
 		return nil, fmt.Errorf("%v: given old child is nil", ErrorHierarchyRequest)
 	}
-	if newChild.GetNodeType() == AttributeNode || newChild.GetNodeType() == TextNode {
+	if newChild.GetNodeType() == AttributeNode || newChild.GetNodeType() == TextNode || newChild.GetNodeType() == CDATASectionNode {
 		return nil, ErrorHierarchyRequest
 	}
 
@@ -152,26 +182,46 @@ func (dd *domDocument) ReplaceChild(newChild, oldChild Node) (Node, error) {
 		return nil, ErrorWrongDocument
 	}
 
+	if newChild.GetNodeType() == DocumentFragmentNode {
+		if err := dd.checkFragment(newChild, oldChild); err != nil {
+			return nil, err
+		}
+		return replaceWithFragment(dd, newChild, oldChild)
+	}
+
 	// Replacing a Node (which is not an element) with an element when there's already an element, should fail.
 	if dd.GetDocumentElement() != nil && newChild.GetNodeType() == ElementNode && oldChild.GetNodeType() != ElementNode {
 		return nil, ErrorHierarchyRequest
 	}
+	// Same goes for the DocumentType.
+	if dd.GetDoctype() != nil && newChild.GetNodeType() == DocumentTypeNode && oldChild.GetNodeType() != DocumentTypeNode {
+		return nil, ErrorHierarchyRequest
+	}
 
 	// Find the old child, and replace it with the new child.
 	for i, child := range dd.GetChildNodes() {
 		if child == oldChild {
+			if newChild == oldChild {
+				// Replacing a child with itself changes nothing.
+				return oldChild, nil
+			}
 			// Check if newChild has a parent (i.e., it's in the tree).
 			ncParent := newChild.GetParentNode()
 			if ncParent != nil {
 				// Remove the newChild from its parent.
 				ncParent.RemoveChild(newChild)
 			}
+			dd.nodeRemoving(oldChild)
 
+			// The index may have shifted when newChild was a preceding sibling.
+			i = indexOf(dd.nodes, oldChild)
 			// Slice trickery, again. It will make a new underlying slice with one element,
 			// the 'newChild', and then append the rest of the de.nodes to that.
 			dd.nodes = append(dd.nodes[:i], append([]Node{newChild}, dd.nodes[i+1:]...)...)
 			// Change the parent node:
 			newChild.setParentNode(dd)
+			oldChild.setParentNode(nil)
+			dd.nodeInserted(newChild)
 
 			return oldChild, nil
 		}
@@ -197,12 +247,28 @@ func (dd *domDocument) InsertBefore(newChild, refChild Node) (Node, error) {
 		return newChild, nil
 	}
 
+	if newChild.GetNodeType() == DocumentFragmentNode {
+		if newChild.GetOwnerDocument() != dd {
+			return nil, ErrorWrongDocument
+		}
+		if refChild.GetParentNode() != dd {
+			return nil, ErrorNotFound
+		}
+		if err := dd.checkFragment(newChild, nil); err != nil {
+			return nil, err
+		}
+		if err := insertFragment(dd, newChild, refChild); err != nil {
+			return nil, err
+		}
+		return newChild, nil
+	}
+
 	// Cannot insert an element if there's already one element.
 	if newChild.GetNodeType() == ElementNode && dd.GetDocumentElement() != nil {
 		return nil, fmt.Errorf("%v: a Document element already exists (<%v>)", ErrorHierarchyRequest, dd.GetDocumentElement())
 	}
 
-	if newChild.GetNodeType() == AttributeNode || newChild.GetNodeType() == TextNode {
+	if newChild.GetNodeType() == AttributeNode || newChild.GetNodeType() == TextNode || newChild.GetNodeType() == CDATASectionNode {
 		return nil, ErrorHierarchyRequest
 	}
 
@@ -210,6 +276,22 @@ func (dd *domDocument) InsertBefore(newChild, refChild Node) (Node, error) {
 		return nil, ErrorWrongDocument
 	}
 
+	// The DocumentType must precede the document element, and there can only be one.
+	refIndex := indexOf(dd.nodes, refChild)
+	if newChild.GetNodeType() == DocumentTypeNode {
+		if dd.GetDoctype() != nil {
+			return nil, fmt.Errorf("%v: a DocumentType already exists", ErrorHierarchyRequest)
+		}
+		if docElem := dd.GetDocumentElement(); docElem != nil && indexOf(dd.nodes, docElem) < refIndex {
+			return nil, fmt.Errorf("%v: the DocumentType must precede the document element", ErrorHierarchyRequest)
+		}
+	}
+	if newChild.GetNodeType() == ElementNode {
+		if doctype := dd.GetDoctype(); doctype != nil && refIndex >= 0 && refIndex <= indexOf(dd.nodes, doctype) {
+			return nil, fmt.Errorf("%v: the document element must follow the DocumentType", ErrorHierarchyRequest)
+		}
+	}
+
 	// Find the reference child, insert newChild before that one.
 	for i, child := range dd.GetChildNodes() {
 		if child == refChild {
@@ -218,8 +300,11 @@ func (dd *domDocument) InsertBefore(newChild, refChild Node) (Node, error) {
 			if ncParent != nil {
 				ncParent.RemoveChild(newChild)
 			}
+			// The index may have shifted when newChild was a preceding sibling.
+			i = indexOf(dd.nodes, refChild)
 			newChild.setParentNode(dd)
 			dd.nodes = append(dd.nodes[:i], append([]Node{newChild}, dd.nodes[i:]...)...)
+			dd.nodeInserted(newChild)
 			return newChild, nil
 		}
 	}
@@ -227,6 +312,33 @@ func (dd *domDocument) InsertBefore(newChild, refChild Node) (Node, error) {
 	return nil, ErrorNotFound
 }
 
+// checkFragment verifies whether the children of the DocumentFragment frag may be
+// inserted into the Document, so the fragment is either moved completely or not at
+// all. The replaced node is the child which is about to be replaced by the fragment,
+// and may be nil.
+func (dd *domDocument) checkFragment(frag, replaced Node) error {
+	elements := 0
+	for _, child := range frag.GetChildNodes() {
+		switch child.GetNodeType() {
+		case ElementNode:
+			elements++
+		case TextNode, CDATASectionNode:
+			return fmt.Errorf("%v: text is not allowed as a child of a Document", ErrorHierarchyRequest)
+		case DocumentTypeNode:
+			return fmt.Errorf("%v: a DocumentFragment cannot contain a DocumentType", ErrorHierarchyRequest)
+		}
+	}
+
+	if elements > 1 {
+		return fmt.Errorf("%v: the fragment contains more than one element", ErrorHierarchyRequest)
+	}
+	docElem := dd.GetDocumentElement()
+	if elements == 1 && docElem != nil && Node(docElem) != replaced {
+		return fmt.Errorf("%v: a Document element already exists (<%v>)", ErrorHierarchyRequest, docElem)
+	}
+	return nil
+}
+
 func (dd *domDocument) HasChildNodes() bool {
 	return len(dd.nodes) > 0
 }
@@ -258,6 +370,10 @@ func (dd *domDocument) setOwnerDocument(doc Document) {
 	// no-op
 }
 
+func (dd *domDocument) getState() *nodeState {
+	return &dd.state
+}
+
 // DOCUMENT SPECIFIC FUNCTIONS
 func (dd *domDocument) CreateElement(tagName string) (Element, error) {
 	name := XMLName(tagName)
@@ -283,12 +399,42 @@ func (dd *domDocument) CreateElementNS(namespaceURI, tagName string) (Element, e
 	return e, nil
 }
 
+// CreateDocumentFragment creates an empty DocumentFragment owned by this Document.
+func (dd *domDocument) CreateDocumentFragment() DocumentFragment {
+	return newDocumentFragment(dd)
+}
+
 func (dd *domDocument) CreateText(text string) Text {
 	t := newText(dd)
 	t.SetText(text)
 	return t
 }
 
+// CreateCDATASection creates a CDATASection node with the given data. The data may
+// contain the ]]> sequence, since the section is split during serialization.
+func (dd *domDocument) CreateCDATASection(data string) CDATASection {
+	c := newCDATASection(dd)
+	c.SetText(data)
+	return c
+}
+
+// CreateEntityReference creates an EntityReference node. If the referenced entity is
+// declared in the DocumentType of this Document, the reference gets a copy of the
+// children of the entity, i.e. its expansion.
+func (dd *domDocument) CreateEntityReference(name string) (EntityReference, error) {
+	if !XMLName(name).IsValid() {
+		return nil, fmt.Errorf("%v: '%v'", ErrorInvalidCharacter, name)
+	}
+
+	ref := newEntityReference(dd, name)
+	if doctype := dd.GetDoctype(); doctype != nil {
+		if entity := doctype.GetEntities().GetNamedItem(name); entity != nil {
+			ref.setChildren(cloneChildren(entity))
+		}
+	}
+	return ref, nil
+}
+
 // CreateComment creates a comment node and returns it. When the comment string contains
 // a double-hyphen (--) it will return an error and the Comment will be nil. The spec
 // says something differently though:
@@ -337,6 +483,35 @@ func (dd *domDocument) CreateProcessingInstruction(target, data string) (Process
 	return pi, nil
 }
 
+// GetDoctype returns the DocumentType child of this Document, or nil if the Document
+// has no document type declaration.
+func (dd *domDocument) GetDoctype() DocumentType {
+	for _, node := range dd.nodes {
+		if dt, ok := node.(DocumentType); ok && node.GetNodeType() == DocumentTypeNode {
+			return dt
+		}
+	}
+	return nil
+}
+
+// GetImplementation returns the DOMImplementation of this package.
+func (dd *domDocument) GetImplementation() DOMImplementation {
+	return implementation
+}
+
+// GetDomConfig returns the DOMConfiguration of this Document, which controls NormalizeDocument.
+func (dd *domDocument) GetDomConfig() DOMConfiguration {
+	return dd.domConfig()
+}
+
+// domConfig returns the configuration of this Document, creating it when necessary.
+func (dd *domDocument) domConfig() *domConfiguration {
+	if dd.config == nil {
+		dd.config = newDOMConfiguration()
+	}
+	return dd.config
+}
+
 // GetDocumentElement traverses through the child nodes and finds the first Element.
 // That one will be returned as the Document element. The AppendChild function must
 // take care that no two root nodes can be added to this Document.
@@ -355,6 +530,12 @@ func (dd *domDocument) GetDocumentElement() Element {
 	return nil
 }
 
+// GetElementById returns the first Element in document order which has an attribute of type
+// ID with the given value, or nil if there is none.
+func (dd *domDocument) GetElementById(elementID string) Element {
+	return dd.elementByID(elementID)
+}
+
 // GetElementsByTagName finds all descendant elements of the current element,
 // with the given tag name, in document order.
 func (dd *domDocument) GetElementsByTagName(tagname string) []Element {
@@ -367,13 +548,282 @@ func (dd *domDocument) GetElementsByTagNameNS(namespaceURI, tagname string) []El
 	return getElementsBy(dd, namespaceURI, tagname, true)
 }
 
+// QuerySelector finds the first descendant element matching the CSS selectors.
+func (dd *domDocument) QuerySelector(selectors string) (Element, error) {
+	return querySelector(dd, selectors)
+}
+
+// QuerySelectorAll finds all descendant elements matching the CSS selectors, in
+// document order.
+func (dd *domDocument) QuerySelectorAll(selectors string) ([]Element, error) {
+	return querySelectorAll(dd, selectors)
+}
+
+// CreateNodeIterator creates a NodeIterator, positioned before the root. The iterator is
+// kept up to date when nodes are removed, until it is detached. Detaching is therefore
+// mandatory: the Document holds on to the iterator until then.
+func (dd *domDocument) CreateNodeIterator(root Node, whatToShow WhatToShow, filter NodeFilter, entityReferenceExpansion bool) (NodeIterator, error) {
+	if root == nil {
+		return nil, fmt.Errorf("%v: the root can not be nil", ErrorNotSupported)
+	}
+	it := &domNodeIterator{}
+	it.traversal = traversal{root: root, whatToShow: whatToShow, filter: filter, expand: entityReferenceExpansion}
+	it.referenceNode = root
+	it.beforeNode = true
+
+	// Removals are reported to the owner document of the nodes.
+	it.document, _ = root.(*domDocument)
+	if it.document == nil {
+		it.document, _ = root.GetOwnerDocument().(*domDocument)
+	}
+	if it.document != nil {
+		it.document.iterators = append(it.document.iterators, it)
+	}
+	return it, nil
+}
+
+// CreateTreeWalker creates a TreeWalker, with the root as current node.
+func (dd *domDocument) CreateTreeWalker(root Node, whatToShow WhatToShow, filter NodeFilter, entityReferenceExpansion bool) (TreeWalker, error) {
+	if root == nil {
+		return nil, fmt.Errorf("%v: the root can not be nil", ErrorNotSupported)
+	}
+	tw := &domTreeWalker{}
+	tw.traversal = traversal{root: root, whatToShow: whatToShow, filter: filter, expand: entityReferenceExpansion}
+	tw.currentNode = root
+	return tw, nil
+}
+
+// removeIterator removes the detached NodeIterator from the Document.
+func (dd *domDocument) removeIterator(it *domNodeIterator) {
+	for i, iterator := range dd.iterators {
+		if iterator == it {
+			dd.iterators = append(dd.iterators[:i], dd.iterators[i+1:]...)
+			return
+		}
+	}
+}
+
+// CreateRange creates a Range with both its boundary points at the start of this Document.
+// The Range is kept up to date on mutations, until it is detached. Detaching is therefore
+// mandatory: the Document holds on to the Range until then.
+func (dd *domDocument) CreateRange() Range {
+	r := &domRange{document: dd, startContainer: dd, endContainer: dd}
+	dd.ranges = append(dd.ranges, r)
+	return r
+}
+
+// CreateEvent creates an uninitialized Event of the given interface, which is either "Event"
+// or "CustomEvent". The Event must be initialized before it can be dispatched.
+func (dd *domDocument) CreateEvent(eventInterface string) (Event, error) {
+	switch eventInterface {
+	case "Event", "Events":
+		return newEvent(), nil
+	case "CustomEvent":
+		return &domCustomEvent{domEvent: newEvent()}, nil
+	}
+	return nil, fmt.Errorf("%v: unknown event interface '%v'", ErrorNotSupported, eventInterface)
+}
+
+// removeRange removes the detached Range from the Document.
+func (dd *domDocument) removeRange(r *domRange) {
+	for i, rng := range dd.ranges {
+		if rng == r {
+			dd.ranges = append(dd.ranges[:i], dd.ranges[i+1:]...)
+			return
+		}
+	}
+}
+
+// nodeRemoving is called right before the Node n is removed from its parent, to update
+// the live objects of the Document.
+func (dd *domDocument) nodeRemoving(n Node) {
+	for _, it := range dd.iterators {
+		it.nodeRemoving(n)
+	}
+	for _, r := range dd.ranges {
+		r.nodeRemoving(n)
+	}
+	dd.unindexIDs(n)
+	queueChildListMutation(n.GetParentNode(), nil, []Node{n}, n.GetPreviousSibling(), n.GetNextSibling())
+	dd.addTransientObservers(n)
+}
+
+// nodeInserted is called right after the Node n is inserted into its parent, to update
+// the live objects of the Document.
+func (dd *domDocument) nodeInserted(n Node) {
+	for _, r := range dd.ranges {
+		r.nodeInserted(n)
+	}
+	dd.indexIDs(n)
+	if n.GetNodeType() == DocumentTypeNode {
+		// The new DTD applies to all the nodes of this Document.
+		dd.typeIDs(dd)
+	} else {
+		dd.typeIDs(n)
+	}
+	queueChildListMutation(n.GetParentNode(), []Node{n}, nil, n.GetPreviousSibling(), n.GetNextSibling())
+}
+
+// dataReplaced is called right before count code units of the character data of the
+// Node n, starting at offset, are replaced by data of the given length.
+func (dd *domDocument) dataReplaced(n Node, offset, count, length int) {
+	for _, r := range dd.ranges {
+		r.dataReplaced(n, offset, count, length)
+	}
+	queueMutationRecord(&MutationRecord{Type: MutationCharacterData, Target: n, OldValue: n.GetNodeValue()})
+}
+
+// textSplit is called when the Text node n is split at the offset, after the newNode
+// has been inserted but before the data of n is truncated.
+func (dd *domDocument) textSplit(n, newNode Node, offset int) {
+	for _, r := range dd.ranges {
+		r.textSplit(n, newNode, offset)
+	}
+}
+
+// NormalizeDocument puts the Document in the normal form, as if it was saved and loaded, using
+// the parameters of the DOMConfiguration returned by GetDomConfig. Adjacent Text nodes are
+// merged, and namespace declarations are fixed up. See
+// https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#Document3-normalizeDocument
+//
+// The problems found are reported to the DOMErrorHandler of the error-handler parameter, which
+// decides whether the normalization continues.
 func (dd *domDocument) NormalizeDocument() {
-	counter := 0
-	for _, c := range dd.GetChildNodes() {
-		if e, ok := c.(Element); ok {
-			e.normalizeNamespaces(&counter)
+	config := dd.domConfig()
+	dn := &documentNormalizer{config: config}
+	if doctype := dd.GetDoctype(); doctype != nil {
+		dn.entities = doctype.GetEntities()
+	}
+	dn.normalizeContent(dd)
+	if dn.stopped {
+		return
+	}
+	dd.Normalize()
+	if !config.getBoolean("element-content-whitespace") {
+		removeWhitespace(dd)
+	}
+	if config.getBoolean("namespaces") {
+		counter := 0
+		for _, c := range dd.GetChildNodes() {
+			if e, ok := c.(Element); ok {
+				e.normalizeNamespaces(&counter)
+			}
+		}
+	}
+	if !config.getBoolean("namespace-declarations") {
+		removeNamespaceDeclarations(dd)
+	}
+	if config.getBoolean("well-formed") {
+		dn.checkWellFormed(dd)
+	}
+}
+
+// AdoptNode moves the source Node to this Document. The listeners, mutation observers and
+// user data of the moved nodes move along. See
+// https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#Document3-adoptNode
+func (dd *domDocument) AdoptNode(source Node) (Node, error) {
+	if source == nil {
+		return nil, fmt.Errorf("%v: the node can not be nil", ErrorNotSupported)
+	}
+	switch source.GetNodeType() {
+	case DocumentNode, DocumentTypeNode, EntityNode, XPathNamespaceNode:
+		return nil, fmt.Errorf("%v: can not adopt a %v", ErrorNotSupported, source.GetNodeType())
+	}
+	if isReadOnly(source) {
+		return nil, ErrorNoModificationAllowed
+	}
+
+	if attr, ok := source.(Attr); ok && source.GetNodeType() == AttributeNode {
+		if owner := attr.GetOwnerElement(); owner != nil {
+			if _, err := owner.RemoveAttributeNode(attr); err != nil {
+				return nil, err
+			}
+		}
+		attr.setSpecified(true)
+	} else if parent := source.GetParentNode(); parent != nil {
+		if _, err := parent.RemoveChild(source); err != nil {
+			return nil, err
+		}
+	}
+
+	if source.GetOwnerDocument() != dd {
+		dd.adoptSubtree(source)
+		dd.typeIDs(source)
+	}
+	return source, nil
+}
+
+// RenameNode renames an Element or Attr, in place. An attribute stays at its position in the
+// attributes of its owner element, and replaces the attribute which has the new name, if any.
+// See https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#Document3-renameNode
+func (dd *domDocument) RenameNode(n Node, namespaceURI, qualifiedName string) (Node, error) {
+	if n == nil {
+		return nil, fmt.Errorf("%v: the node can not be nil", ErrorNotSupported)
+	}
+	if n.GetNodeType() != ElementNode && n.GetNodeType() != AttributeNode {
+		return nil, fmt.Errorf("%v: can not rename a %v", ErrorNotSupported, n.GetNodeType())
+	}
+	if n.GetOwnerDocument() != dd {
+		return nil, ErrorWrongDocument
+	}
+	if isReadOnly(n) {
+		return nil, ErrorNoModificationAllowed
+	}
+	if err := checkQualifiedName(namespaceURI, qualifiedName); err != nil {
+		return nil, err
+	}
+
+	switch t := n.(type) {
+	case *domElement:
+		t.setTagName(qualifiedName)
+		t.namespaceURI = namespaceURI
+		for _, attr := range attributeList(t) {
+			dd.typeIDs(attr)
+		}
+	case *domAttr:
+		if owner := t.GetOwnerElement(); owner != nil {
+			// Remove the attributes which have the new name, other than the renamed one.
+			attrs := owner.GetAttributes()
+			if other := attrs.GetNamedItemNS(namespaceURI, XMLName(qualifiedName).GetLocalPart()); other != nil && other != n {
+				attrs.RemoveNamedItemNS(namespaceURI, XMLName(qualifiedName).GetLocalPart())
+			}
+			if other := attrs.GetNamedItem(qualifiedName); other != nil && other != n {
+				attrs.RemoveNamedItem(qualifiedName)
+			}
+		}
+		attributeChanging(t)
+		t.setName(qualifiedName)
+		t.namespaceURI = namespaceURI
+		attributeChanged(t)
+		dd.typeIDs(t)
+	}
+
+	notifyUserDataHandlers(NodeRenamed, n, nil)
+	return n, nil
+}
+
+// adoptSubtree sets the owner document of the Node n, its attributes and its descendants to
+// this Document. The listeners, mutation observers and user data are kept by the nodes, so
+// these move along.
+func (dd *domDocument) adoptSubtree(n Node) {
+	n.setOwnerDocument(dd)
+	for _, attr := range attributeList(n) {
+		dd.adoptSubtree(attr)
+	}
+	if ref, ok := n.(*domEntityReference); ok {
+		// The expansion of the entity is taken from the DTD of this Document.
+		ref.setChildren(nil)
+		if doctype := dd.GetDoctype(); doctype != nil {
+			if entity := doctype.GetEntities().GetNamedItem(ref.GetNodeName()); entity != nil {
+				ref.setChildren(cloneChildren(entity))
+			}
+		}
+	} else {
+		for _, child := range n.GetChildNodes() {
+			dd.adoptSubtree(child)
 		}
 	}
+	notifyUserDataHandlers(NodeAdopted, n, nil)
 }
 
 func (dd *domDocument) LookupPrefix(namespace string) (string, bool) {
@@ -405,27 +855,73 @@ func (dd *domDocument) SetTextContent(content string) {
 
 // CloneNode creates a copy of the Document instance. When deep is true, it will create a complete copy
 // of the whole Document, recursively. When false, it's pretty useless since it will return just a plain new
-// empty Document.
+// empty Document. A child which can not be copied is reported to the DOMErrorHandler of the
+// error-handler parameter of this Document, which decides whether copying continues.
 func (dd *domDocument) CloneNode(deep bool) Node {
+	return dd.cloneNode(nil, deep, NodeCloned)
+}
+
+// cloneNode ignores the owner, since the clone of a Document owns itself.
+func (dd *domDocument) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
 	cloneDoc := NewDocument()
 
 	if deep {
 		for _, c := range dd.GetChildNodes() {
 			cloneChild := cloneDoc.ImportNode(c, true)
-			err := cloneDoc.AppendChild(cloneChild)
-			if err != nil {
-				fmt.Println(err)
+			if err := cloneDoc.AppendChild(cloneChild); err != nil {
+				domErr := newNodeError(SeverityError, "clone-failed", c, "can not copy the %v: %v", c.GetNodeType(), err)
+				domErr.exception = err
+				if !reportError(dd.domConfig().errorHandler, domErr) {
+					break
+				}
 			}
 		}
 	}
 
+	notifyUserDataHandlers(operation, dd, cloneDoc)
 	return cloneDoc
 }
 
+func (dd *domDocument) Normalize() {
+	normalize(dd)
+}
+
+func (dd *domDocument) IsSameNode(other Node) bool {
+	return isSameNode(dd, other)
+}
+
+func (dd *domDocument) IsEqualNode(other Node) bool {
+	return isEqualNode(dd, other)
+}
+
+func (dd *domDocument) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(dd, other)
+}
+
+func (dd *domDocument) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(dd, key, data, handler)
+}
+
+func (dd *domDocument) GetUserData(key string) interface{} {
+	return getUserData(dd, key)
+}
+
 func (dd *domDocument) ImportNode(n Node, deep bool) Node {
 	return importNode(dd, n, deep)
 }
 
+func (dd *domDocument) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(dd, eventType, listener, useCapture)
+}
+
+func (dd *domDocument) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(dd, eventType, listener, useCapture)
+}
+
+func (dd *domDocument) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(dd, evt)
+}
+
 func (dd *domDocument) String() string {
 	return fmt.Sprintf("%s", dd.GetNodeType())
 }
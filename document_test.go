@@ -1,6 +1,10 @@
 package dom
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
 
 // Test the plain getters of the Document. Also some no-op setters.
 func TestDocumentGetters(t *testing.T) {
@@ -619,3 +623,273 @@ func TestDocumentCloneNode(t *testing.T) {
 		t.Error("type assertion failed (want: Document)")
 	}
 }
+
+func TestDocumentAdoptNode(t *testing.T) {
+	src := mustParse(t, `<!DOCTYPE r [<!ENTITY e "source">]><r><moved a="1"><child/>&e;</moved><kept/></r>`)
+	dst := mustParse(t, `<!DOCTYPE d [<!ENTITY e "target">]><d>&e;</d>`)
+	moved := src.GetDocumentElement().GetFirstChild().(Element)
+	child := moved.GetFirstChild()
+	attr := moved.GetAttributeNode("a")
+
+	var log []string
+	listener := EventListenerFunc(func(evt Event) {
+		log = append(log, "event:"+evt.GetCurrentTarget().GetNodeName())
+	})
+	child.AddEventListener("change", &listener, false)
+	child.SetUserData("key", "value", UserDataHandlerFunc(func(op UserDataOperation, key string, data interface{}, src, dst Node) {
+		log = append(log, fmt.Sprintf("%v:%v", op, src.GetNodeName()))
+	}))
+
+	adopted, err := dst.AdoptNode(moved)
+	if err != nil || adopted != moved {
+		t.Errorf("unexpected result %v, %v", adopted, err)
+		t.FailNow()
+	}
+	if moved.GetParentNode() != nil || len(src.GetDocumentElement().GetChildNodes()) != 1 {
+		t.Error("expected the node to be removed from its parent")
+	}
+	for _, n := range []Node{moved, child, attr, moved.GetLastChild()} {
+		if n.GetOwnerDocument() != dst {
+			t.Errorf("expected %v to be owned by the target document", n)
+		}
+	}
+	if child.GetUserData("key") != "value" {
+		t.Error("expected the user data to move along")
+	}
+	if text := moved.GetLastChild().GetTextContent(); text != "target" {
+		t.Errorf("expected the entity reference to be expanded from the target DTD, got '%v'", text)
+	}
+
+	// The node keeps its identity, and its listeners, once inserted.
+	dst.GetDocumentElement().AppendChild(moved)
+	evt, _ := dst.CreateEvent("Event")
+	evt.InitEvent("change", true, false)
+	child.DispatchEvent(evt)
+	if actual := strings.Join(log, " "); actual != "NODE_ADOPTED:child event:child" {
+		t.Errorf("unexpected log '%v'", actual)
+	}
+
+	// An attribute is removed from its owner element.
+	kept := src.GetDocumentElement().GetFirstChild().(Element)
+	kept.SetAttribute("b", "2")
+	b := kept.GetAttributeNode("b")
+	if _, err := dst.AdoptNode(b); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if kept.HasAttribute("b") || b.GetOwnerElement() != nil || b.GetOwnerDocument() != dst {
+		t.Error("expected the attribute to be removed from its element")
+	}
+
+	for _, n := range []Node{src, src.GetDoctype()} {
+		if _, err := dst.AdoptNode(n); err == nil || !strings.HasPrefix(err.Error(), ErrorNotSupported.Error()) {
+			t.Errorf("%v: expected %v, got %v", n, ErrorNotSupported, err)
+		}
+	}
+}
+
+func TestDocumentRenameNode(t *testing.T) {
+	doc := mustParse(t, `<r a="1" b="2" c="3"><old/></r>`)
+	root := doc.GetDocumentElement()
+	old := root.GetFirstChild()
+
+	var log []string
+	old.SetUserData("key", "value", UserDataHandlerFunc(func(op UserDataOperation, key string, data interface{}, src, dst Node) {
+		log = append(log, fmt.Sprintf("%v:%v:%v", op, src.GetNodeName(), dst))
+	}))
+	renamed, err := doc.RenameNode(old, "urn:x", "x:new")
+	if err != nil || renamed != old {
+		t.Errorf("unexpected result %v, %v", renamed, err)
+		t.FailNow()
+	}
+	if old.GetNodeName() != "x:new" || old.GetNamespaceURI() != "urn:x" || old.GetLocalName() != "new" {
+		t.Errorf("unexpected name %v in %v", old.GetNodeName(), old.GetNamespaceURI())
+	}
+	if fmt.Sprint(log) != "[NODE_RENAMED:x:new:<nil>]" {
+		t.Errorf("unexpected handler calls %v", log)
+	}
+
+	// Renaming an attribute keeps its position, and replaces the attribute with the new name.
+	a := root.GetAttributeNode("a")
+	if _, err := doc.RenameNode(a, "", "c"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	attrs := root.GetAttributes()
+	if attrs.Length() != 2 || attrs.Item(0) != a || attrs.Item(1).GetNodeName() != "b" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+	if root.GetAttributeNode("c") != a || root.GetAttribute("c") != "1" {
+		t.Errorf("expected the renamed attribute to be found by its new name")
+	}
+	if _, err := doc.RenameNode(a, "urn:x", "x:c"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if root.GetAttributeNodeNS("urn:x", "c") != a || root.GetAttributeNode("c") != nil {
+		t.Errorf("expected the renamed attribute to be found by its namespace")
+	}
+
+	other := mustParse(t, `<o/>`)
+	tests := []struct {
+		node  Node
+		ns    string
+		qname string
+		err   error
+	}{
+		{doc.CreateText("t"), "", "t", ErrorNotSupported},
+		{doc, "", "d", ErrorNotSupported},
+		{other.GetDocumentElement(), "", "o", ErrorWrongDocument},
+		{old, "", "in valid", ErrorInvalidCharacter},
+		{old, "", "p:l", ErrorNamespace},
+		{old, "urn:y", "xml:l", ErrorNamespace},
+	}
+	for _, test := range tests {
+		if _, err := doc.RenameNode(test.node, test.ns, test.qname); err == nil || !strings.HasPrefix(err.Error(), test.err.Error()) {
+			t.Errorf("renaming %v to %v: expected %v, got %v", test.node, test.qname, test.err, err)
+		}
+	}
+	if old.GetNodeName() != "x:new" {
+		t.Errorf("expected a failed rename to keep the name, got %v", old.GetNodeName())
+	}
+}
+
+func TestDocumentGetElementById(t *testing.T) {
+	doc := mustParse(t, `<!DOCTYPE r [<!ATTLIST a key ID #IMPLIED>]>
+<r xmlns:xml="http://www.w3.org/XML/1998/namespace"><a key="k1"/><b xml:id="x1"/><c name="n1"/><a key="k1"/></r>`)
+	root := doc.GetDocumentElement()
+	a, b, c := root.GetChildNodes()[0].(Element), root.GetChildNodes()[1].(Element), root.GetChildNodes()[2].(Element)
+
+	if doc.GetElementById("k1") != a {
+		t.Error("expected the first element with the DTD-declared ID")
+	}
+	if doc.GetElementById("x1") != b || !b.GetAttributeNodeNS(XMLNamespaceURI, "id").IsId() {
+		t.Error("expected the element with the xml:id attribute")
+	}
+	if doc.GetElementById("n1") != nil || doc.GetElementById("") != nil {
+		t.Error("expected no element for an attribute which is not of type ID")
+	}
+
+	// The index follows marking, changing, removing and inserting.
+	if err := c.SetIdAttribute("name", true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if doc.GetElementById("n1") != c {
+		t.Error("expected the element with the attribute marked as ID")
+	}
+	c.GetAttributeNode("name").SetValue("n2")
+	if doc.GetElementById("n1") != nil || doc.GetElementById("n2") != c {
+		t.Error("expected the index to follow the changed value")
+	}
+	if err := c.SetIdAttributeNode(c.GetAttributeNode("name"), false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if doc.GetElementById("n2") != nil {
+		t.Error("expected the index to follow the unmarked attribute")
+	}
+	root.RemoveChild(a)
+	if doc.GetElementById("k1") != root.GetLastChild() {
+		t.Error("expected the next element with the ID after removing the first")
+	}
+	root.InsertBefore(a, b)
+	if doc.GetElementById("k1") != a {
+		t.Error("expected the inserted element with the ID")
+	}
+	if _, err := doc.RenameNode(b.GetAttributeNodeNS(XMLNamespaceURI, "id"), "", "id"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if doc.GetElementById("x1") != nil {
+		t.Error("expected the index to follow the renamed xml:id attribute")
+	}
+	if err := b.SetIdAttributeNS("", "id", true); err != nil || doc.GetElementById("x1") != b {
+		t.Errorf("expected the attribute marked by namespace to be found, got %v", err)
+	}
+	detached := mustCreateElement(doc, "d")
+	detached.SetAttributeNS(XMLNamespaceURI, "xml:id", "d1")
+	if doc.GetElementById("d1") != nil {
+		t.Error("expected no element which is not in the document")
+	}
+
+	if err := c.SetIdAttribute("missing", true); err != ErrorNotFound {
+		t.Errorf("expected %v, got %v", ErrorNotFound, err)
+	}
+	if err := c.SetIdAttributeNode(a.GetAttributeNode("key"), true); err != ErrorNotFound {
+		t.Errorf("expected %v, got %v", ErrorNotFound, err)
+	}
+}
+
+func TestDocumentGetElementByIdInMemory(t *testing.T) {
+	doc := mustParse(t, `<!DOCTYPE r [<!ATTLIST e id ID #IMPLIED>]><r/>`)
+	dd := doc.(*domDocument)
+	root := doc.GetDocumentElement()
+	if doc.GetElementById("a") != nil {
+		t.Error("expected no element in an empty document")
+	}
+
+	// The attributes are typed by the DTD when set, and indexed when inserted.
+	e := mustCreateElement(doc, "e")
+	e.SetAttribute("id", "b")
+	root.AppendChild(e)
+	if doc.GetElementById("b") != e || !e.GetAttributeNode("id").IsId() {
+		t.Error("expected the inserted element with the ID from the DTD")
+	}
+	e.SetAttribute("id", "c")
+	if doc.GetElementById("b") != nil || doc.GetElementById("c") != e {
+		t.Error("expected the index to follow the changed value")
+	}
+	attr, _ := doc.CreateAttribute("id")
+	attr.SetValue("d")
+	other := mustCreateElement(doc, "e")
+	other.SetAttributeNode(attr)
+	wrapper := mustCreateElement(doc, "w")
+	wrapper.AppendChild(other)
+	root.InsertBefore(wrapper, e)
+	if doc.GetElementById("d") != other {
+		t.Error("expected the element inserted as part of a subtree")
+	}
+
+	// Duplicates are found in document order.
+	other.SetAttribute("id", "c")
+	if doc.GetElementById("c") != other {
+		t.Error("expected the first element in document order")
+	}
+	root.RemoveChild(wrapper)
+	if doc.GetElementById("c") != e {
+		t.Error("expected the remaining element after removing the first")
+	}
+
+	// Adopted nodes are typed by the DTD of their new document.
+	foreign := NewDocument()
+	adopted := mustCreateElement(foreign, "e")
+	adopted.SetAttribute("id", "f")
+	if _, err := doc.AdoptNode(adopted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root.AppendChild(adopted)
+	if doc.GetElementById("f") != adopted {
+		t.Error("expected the adopted element with the ID from the DTD")
+	}
+	if _, err := doc.RenameNode(adopted.GetAttributeNode("id"), "", "key"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if doc.GetElementById("f") != adopted {
+		t.Error("expected the renamed attribute to stay of type ID")
+	}
+	adopted.SetAttribute("key", "g")
+	if doc.GetElementById("f") != nil || doc.GetElementById("g") != adopted {
+		t.Error("expected the index to follow the renamed attribute")
+	}
+
+	// The index is kept up to date instead of being rebuilt, and matches a fresh one.
+	if dd.ids == nil {
+		t.Fatal("expected the index to be kept")
+	}
+	kept := dd.ids
+	dd.ids = nil
+	doc.GetElementById("")
+	if len(kept) != len(dd.ids) {
+		t.Errorf("expected %v, got %v", dd.ids, kept)
+	}
+	for id, elems := range dd.ids {
+		if len(kept[id]) != len(elems) {
+			t.Errorf("%v: expected %v, got %v", id, elems, kept[id])
+		}
+	}
+}
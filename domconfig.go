@@ -0,0 +1,209 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DOMConfiguration holds the parameters which control NormalizeDocument. Parameter names
+// are case-insensitive. The configuration of a Document is returned by GetDomConfig.
+// See https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#DOMConfiguration
+//
+// The following parameters are recognized, with their default value first:
+//
+//	canonical-form                 false
+//	cdata-sections                 true, false: replace CDATASections by Text nodes
+//	check-character-normalization  false
+//	comments                       true, false: discard Comment nodes
+//	datatype-normalization         false
+//	element-content-whitespace     true, false: discard Text nodes with only whitespace
+//	entities                       true, false: replace EntityReferences of internal entities by their expansion
+//	error-handler                  nil, or the DOMErrorHandler which is notified of the problems found
+//	infoset                        true when the other parameters match the XML Information Set
+//	namespaces                     true, false: do not fix up namespace declarations and prefixes
+//	namespace-declarations         true, false: discard namespace declaration attributes
+//	normalize-characters           false
+//	split-cdata-sections           true, false: keep CDATASections containing the ]]> marker
+//	validate                       false
+//	validate-if-schema             false
+//	well-formed                    true, false: do not check whether the Document is well-formed
+type DOMConfiguration interface {
+	// SetParameter sets the value of the parameter. A nil value restores the default. It
+	// returns an ErrorNotFound for an unknown parameter, an ErrorNotSupported for a value
+	// which is not supported, and an ErrorTypeMismatch for a value of the wrong type.
+	SetParameter(name string, value interface{}) error
+	// GetParameter returns the value of the parameter, or an ErrorNotFound if it is unknown.
+	GetParameter(name string) (interface{}, error)
+	// CanSetParameter returns true if SetParameter would succeed with the value.
+	CanSetParameter(name string, value interface{}) bool
+	// GetParameterNames returns the names of the parameters, in alphabetical order.
+	GetParameterNames() []string
+}
+
+// booleanParameter is the definition of a boolean parameter of a DOMConfiguration.
+type booleanParameter struct {
+	value bool // The default value.
+	fixed bool // True if only the default value is supported.
+}
+
+// booleanParameters are the boolean parameters of a DOMConfiguration, by name. The
+// infoset parameter is not included, since it is derived from the others.
+var booleanParameters = map[string]booleanParameter{
+	"canonical-form":                {value: false, fixed: true},
+	"cdata-sections":                {value: true},
+	"check-character-normalization": {value: false, fixed: true},
+	"comments":                      {value: true},
+	"datatype-normalization":        {value: false, fixed: true},
+	"element-content-whitespace":    {value: true},
+	"entities":                      {value: true},
+	"namespaces":                    {value: true},
+	"namespace-declarations":        {value: true},
+	"normalize-characters":          {value: false, fixed: true},
+	"split-cdata-sections":          {value: true},
+	"validate":                      {value: false, fixed: true},
+	"validate-if-schema":            {value: false, fixed: true},
+	"well-formed":                   {value: true},
+}
+
+// infosetParameters are the values of the parameters when the infoset parameter is true.
+var infosetParameters = map[string]bool{
+	"validate-if-schema":         false,
+	"entities":                   false,
+	"datatype-normalization":     false,
+	"cdata-sections":             false,
+	"namespace-declarations":     true,
+	"well-formed":                true,
+	"element-content-whitespace": true,
+	"comments":                   true,
+	"namespaces":                 true,
+}
+
+// parameterNames are the names of all parameters, in alphabetical order.
+var parameterNames = []string{
+	"canonical-form",
+	"cdata-sections",
+	"check-character-normalization",
+	"comments",
+	"datatype-normalization",
+	"element-content-whitespace",
+	"entities",
+	"error-handler",
+	"infoset",
+	"namespace-declarations",
+	"namespaces",
+	"normalize-characters",
+	"split-cdata-sections",
+	"validate",
+	"validate-if-schema",
+	"well-formed",
+}
+
+// domConfiguration is the DOMConfiguration of a Document.
+type domConfiguration struct {
+	booleans     map[string]bool // The boolean parameters which differ from their default.
+	errorHandler DOMErrorHandler
+}
+
+func newDOMConfiguration() *domConfiguration {
+	return &domConfiguration{booleans: make(map[string]bool)}
+}
+
+func (dc *domConfiguration) SetParameter(name string, value interface{}) error {
+	if err := dc.checkParameter(name, value); err != nil {
+		return err
+	}
+	name = strings.ToLower(name)
+	switch name {
+	case "error-handler":
+		dc.errorHandler, _ = value.(DOMErrorHandler)
+	case "infoset":
+		if value == true {
+			for param, v := range infosetParameters {
+				dc.setBoolean(param, v)
+			}
+		}
+	default:
+		if value == nil {
+			delete(dc.booleans, name)
+		} else {
+			dc.setBoolean(name, value.(bool))
+		}
+	}
+	return nil
+}
+
+func (dc *domConfiguration) GetParameter(name string) (interface{}, error) {
+	name = strings.ToLower(name)
+	switch name {
+	case "error-handler":
+		return dc.errorHandler, nil
+	case "infoset":
+		for param, v := range infosetParameters {
+			if dc.getBoolean(param) != v {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	if _, ok := booleanParameters[name]; !ok {
+		return nil, fmt.Errorf("%v: unknown parameter '%v'", ErrorNotFound, name)
+	}
+	return dc.getBoolean(name), nil
+}
+
+func (dc *domConfiguration) CanSetParameter(name string, value interface{}) bool {
+	return dc.checkParameter(name, value) == nil
+}
+
+func (dc *domConfiguration) GetParameterNames() []string {
+	return append([]string(nil), parameterNames...)
+}
+
+// checkParameter returns the error SetParameter returns for the parameter and value, if any.
+func (dc *domConfiguration) checkParameter(name string, value interface{}) error {
+	name = strings.ToLower(name)
+	switch name {
+	case "error-handler":
+		if _, ok := value.(DOMErrorHandler); !ok && value != nil {
+			return fmt.Errorf("%v: parameter '%v' expects a DOMErrorHandler, got %T", ErrorTypeMismatch, name, value)
+		}
+		return nil
+	case "infoset":
+		if _, ok := value.(bool); !ok && value != nil {
+			return fmt.Errorf("%v: parameter '%v' expects a bool, got %T", ErrorTypeMismatch, name, value)
+		}
+		return nil
+	}
+	param, ok := booleanParameters[name]
+	if !ok {
+		return fmt.Errorf("%v: unknown parameter '%v'", ErrorNotFound, name)
+	}
+	if value == nil {
+		return nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return fmt.Errorf("%v: parameter '%v' expects a bool, got %T", ErrorTypeMismatch, name, value)
+	}
+	if param.fixed && b != param.value {
+		return fmt.Errorf("%v: parameter '%v' can not be %v", ErrorNotSupported, name, b)
+	}
+	return nil
+}
+
+// setBoolean sets the boolean parameter, which is only kept when it differs from the default.
+func (dc *domConfiguration) setBoolean(name string, value bool) {
+	if booleanParameters[name].value == value {
+		delete(dc.booleans, name)
+	} else {
+		dc.booleans[name] = value
+	}
+}
+
+// getBoolean returns the value of the boolean parameter.
+func (dc *domConfiguration) getBoolean(name string) bool {
+	if value, ok := dc.booleans[name]; ok {
+		return value
+	}
+	return booleanParameters[name].value
+}
@@ -0,0 +1,170 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDOMConfigurationParameters(t *testing.T) {
+	config := NewDocument().GetDomConfig()
+
+	if names := config.GetParameterNames(); len(names) != 16 || names[0] != "canonical-form" {
+		t.Errorf("unexpected parameter names %v", names)
+	}
+	for _, name := range config.GetParameterNames() {
+		if _, err := config.GetParameter(name); err != nil {
+			t.Errorf("unexpected error for parameter %v: %v", name, err)
+		}
+	}
+
+	if err := config.SetParameter("Comments", false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if value, _ := config.GetParameter("comments"); value != false {
+		t.Errorf("expected comments to be false, got %v", value)
+	}
+	if err := config.SetParameter("comments", nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if value, _ := config.GetParameter("comments"); value != true {
+		t.Errorf("expected comments to be restored to true, got %v", value)
+	}
+
+	if value, _ := config.GetParameter("infoset"); value != false {
+		t.Errorf("expected infoset to be false by default, got %v", value)
+	}
+	if err := config.SetParameter("infoset", true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for name, expected := range map[string]interface{}{"infoset": true, "entities": false, "cdata-sections": false, "comments": true} {
+		if value, _ := config.GetParameter(name); value != expected {
+			t.Errorf("expected %v to be %v, got %v", name, expected, value)
+		}
+	}
+	config.SetParameter("comments", false)
+	if value, _ := config.GetParameter("infoset"); value != false {
+		t.Errorf("expected infoset to be false after changing comments, got %v", value)
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		err   error
+	}{
+		{"unknown", true, ErrorNotFound},
+		{"comments", "yes", ErrorTypeMismatch},
+		{"infoset", 1, ErrorTypeMismatch},
+		{"validate", true, ErrorNotSupported},
+		{"canonical-form", true, ErrorNotSupported},
+	}
+	for _, test := range tests {
+		err := config.SetParameter(test.name, test.value)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err.Error()) {
+			t.Errorf("setting %v to %v: expected %v, got %v", test.name, test.value, test.err, err)
+		}
+		if config.CanSetParameter(test.name, test.value) {
+			t.Errorf("expected %v not to be settable to %v", test.name, test.value)
+		}
+	}
+	if !config.CanSetParameter("validate", false) || !config.CanSetParameter("well-formed", false) {
+		t.Error("expected supported values to be settable")
+	}
+	if _, err := config.GetParameter("unknown"); err == nil || !strings.HasPrefix(err.Error(), ErrorNotFound.Error()) {
+		t.Errorf("expected %v, got %v", ErrorNotFound, err)
+	}
+}
+
+func TestDOMConfigurationNormalizeDocument(t *testing.T) {
+	input := `<!DOCTYPE r [<!ENTITY e "<i>x</i>y"><!ENTITY ext SYSTEM "ext.xml">]>` +
+		`<r xmlns:p="urn:p"><!--c--> <![CDATA[a]]>b<c><![CDATA[1]]></c>&e;&ext;</r>`
+	tests := []struct {
+		params   map[string]interface{}
+		expected string
+	}{
+		{
+			map[string]interface{}{},
+			`<r xmlns:p>#comment(c) #text( ) #cdata-section(a) #text(b) <c>#cdata-section(1]]) #cdata-section(>2)</c> &e; &ext;</r>`,
+		},
+		{
+			map[string]interface{}{"comments": false, "cdata-sections": false},
+			`<r xmlns:p>#text( ab) <c>#text(1]]>2)</c> &e; &ext;</r>`,
+		},
+		{
+			map[string]interface{}{"entities": false, "split-cdata-sections": false},
+			`<r xmlns:p>#comment(c) #text( ) #cdata-section(a) #text(b) <c>#cdata-section(1]]>2)</c> <i>#text(x)</i> #text(y) &ext;</r>`,
+		},
+		{
+			map[string]interface{}{"element-content-whitespace": false, "namespace-declarations": false},
+			`<r>#comment(c) #cdata-section(a) #text(b) <c>#cdata-section(1]]) #cdata-section(>2)</c> &e; &ext;</r>`,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			doc := mustParse(t, input)
+			root := doc.GetDocumentElement()
+			// The parser drops namespace declarations, and does not accept the ]]> marker
+			// in a CDATA section, so add these explicitly.
+			root.SetAttribute("xmlns:p", "urn:p")
+			root.GetElementsByTagName("c")[0].GetFirstChild().(Text).SetText("1]]>2")
+			for name, value := range test.params {
+				if err := doc.GetDomConfig().SetParameter(name, value); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			doc.NormalizeDocument()
+			if actual := outline(root); actual != test.expected {
+				t.Errorf("expected\n%v\ngot\n%v", test.expected, actual)
+			}
+		})
+	}
+}
+
+// outline describes the Node n and its descendants, showing the boundaries of text nodes
+// which a serialization would hide.
+func outline(n Node) string {
+	switch n.GetNodeType() {
+	case ElementNode:
+		s := "<" + n.GetNodeName()
+		for _, attr := range attributeList(n) {
+			s += " " + attr.GetNodeName()
+		}
+		var children []string
+		for _, child := range n.GetChildNodes() {
+			children = append(children, outline(child))
+		}
+		return s + ">" + strings.Join(children, " ") + "</" + n.GetNodeName() + ">"
+	case EntityReferenceNode:
+		return "&" + n.GetNodeName() + ";"
+	}
+	return fmt.Sprintf("%v(%v)", n.GetNodeName(), n.GetNodeValue())
+}
+
+func TestDOMConfigurationDisabledChecks(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		doc := NewDocument()
+		root, _ := doc.CreateElementNS("urn:p", "p:root")
+		doc.AppendChild(root)
+		comment, _ := doc.CreateComment("ab")
+		comment.SetComment("a--b")
+		root.AppendChild(comment)
+
+		recorder := &errorRecorder{proceed: true}
+		doc.GetDomConfig().SetParameter("error-handler", recorder)
+		doc.GetDomConfig().SetParameter("well-formed", enabled)
+		doc.GetDomConfig().SetParameter("namespaces", enabled)
+		doc.NormalizeDocument()
+
+		expectedErrors, expectedOutline := "", `<p:root>#comment(a--b)</p:root>`
+		if enabled {
+			expectedErrors = "SEVERITY_ERROR:wf-invalid-character:#comment"
+			expectedOutline = `<p:root xmlns:p>#comment(a--b)</p:root>`
+		}
+		if recorder.String() != expectedErrors {
+			t.Errorf("well-formed %v: expected errors '%v', got '%v'", enabled, expectedErrors, recorder)
+		}
+		if actual := outline(root); actual != expectedOutline {
+			t.Errorf("namespaces %v: expected '%v', got '%v'", enabled, expectedOutline, actual)
+		}
+	}
+}
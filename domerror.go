@@ -0,0 +1,159 @@
+package dom
+
+import "fmt"
+
+// This file contains the errors which are reported to a DOMErrorHandler while parsing,
+// serializing or normalizing a Document. Unlike the errors which are returned, these
+// can be recoverable: the handler decides whether the processing continues.
+// See https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#ERROR-Interfaces-DOMError
+
+// ErrorSeverity is the severity of a DOMError.
+type ErrorSeverity uint8
+
+// Enumeration of the severities of a DOMError.
+const (
+	// SeverityWarning is a problem after which the processing continues normally.
+	SeverityWarning ErrorSeverity = iota + 1
+	// SeverityError is a problem after which the processing may continue, if the
+	// DOMErrorHandler allows so. The result may not be what the application expects.
+	SeverityError
+	// SeverityFatalError is a problem after which the processing stops, regardless of
+	// what the DOMErrorHandler returns.
+	SeverityFatalError
+)
+
+// String returns the string representation of the ErrorSeverity, using the default
+// representation by the W3 specification.
+func (s ErrorSeverity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "SEVERITY_WARNING"
+	case SeverityError:
+		return "SEVERITY_ERROR"
+	case SeverityFatalError:
+		return "SEVERITY_FATAL_ERROR"
+	default:
+		return "???"
+	}
+}
+
+// DOMLocator describes the location where a DOMError occurred.
+type DOMLocator interface {
+	GetLineNumber() int   // The line number, starting at 1, or -1 if unknown.
+	GetColumnNumber() int // The column number in the line, starting at 1, or -1 if unknown.
+	GetByteOffset() int   // The offset in the input in bytes, starting at 0, or -1 if unknown.
+	GetRelatedNode() Node // The Node the error is about, or nil if unknown.
+}
+
+// DOMError is a problem which is reported to a DOMErrorHandler. It implements the error
+// interface, so it can be returned or wrapped as well.
+type DOMError interface {
+	error
+
+	GetSeverity() ErrorSeverity  // The severity of the problem.
+	GetMessage() string          // A description of the problem.
+	GetType() string             // The type of the problem, like "cdata-sections-splitted".
+	GetRelatedException() error  // The error which caused the problem, if any.
+	GetRelatedData() interface{} // Data related to the problem, depending on its type. Usually the Node.
+	GetLocation() DOMLocator     // The location of the problem.
+}
+
+// DOMErrorHandler is notified of the problems found while parsing, serializing or
+// normalizing a Document. Processing continues when HandleError returns true, except
+// after a fatal error. It stops as soon as possible when HandleError returns false.
+type DOMErrorHandler interface {
+	HandleError(err DOMError) bool
+}
+
+// DOMErrorHandlerFunc is an adapter to use ordinary functions as a DOMErrorHandler.
+type DOMErrorHandlerFunc func(err DOMError) bool
+
+// HandleError calls f(err).
+func (f DOMErrorHandlerFunc) HandleError(err DOMError) bool {
+	return f(err)
+}
+
+// domLocator is the DOMLocator of a domError.
+type domLocator struct {
+	line   int
+	column int
+	offset int
+	node   Node
+}
+
+func (dl *domLocator) GetLineNumber() int {
+	return dl.line
+}
+
+func (dl *domLocator) GetColumnNumber() int {
+	return dl.column
+}
+
+func (dl *domLocator) GetByteOffset() int {
+	return dl.offset
+}
+
+func (dl *domLocator) GetRelatedNode() Node {
+	return dl.node
+}
+
+// domError is the DOMError reported by this package.
+type domError struct {
+	severity  ErrorSeverity
+	message   string
+	errType   string
+	exception error
+	data      interface{}
+	location  *domLocator
+}
+
+// newNodeError creates a DOMError about the Node n, which is the related data as well. The
+// location in the input is unknown.
+func newNodeError(severity ErrorSeverity, errType string, n Node, format string, args ...interface{}) *domError {
+	return &domError{
+		severity: severity,
+		message:  fmt.Sprintf(format, args...),
+		errType:  errType,
+		data:     n,
+		location: &domLocator{line: -1, column: -1, offset: -1, node: n},
+	}
+}
+
+func (de *domError) Error() string {
+	return fmt.Sprintf("%v: %v", de.severity, de.message)
+}
+
+func (de *domError) GetSeverity() ErrorSeverity {
+	return de.severity
+}
+
+func (de *domError) GetMessage() string {
+	return de.message
+}
+
+func (de *domError) GetType() string {
+	return de.errType
+}
+
+func (de *domError) GetRelatedException() error {
+	return de.exception
+}
+
+func (de *domError) GetRelatedData() interface{} {
+	return de.data
+}
+
+func (de *domError) GetLocation() DOMLocator {
+	return de.location
+}
+
+// reportError passes the error to the handler, which may be nil, and returns whether the
+// processing should continue. Without a handler, processing continues after warnings and
+// errors. It always stops after a fatal error.
+func reportError(handler DOMErrorHandler, err *domError) bool {
+	proceed := err.severity != SeverityFatalError
+	if handler != nil && !handler.HandleError(err) {
+		proceed = false
+	}
+	return proceed
+}
@@ -0,0 +1,140 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// errorRecorder is a DOMErrorHandler which records the errors, and returns proceed.
+type errorRecorder struct {
+	errors  []DOMError
+	proceed bool
+}
+
+func (er *errorRecorder) HandleError(err DOMError) bool {
+	er.errors = append(er.errors, err)
+	return er.proceed
+}
+
+// String describes the recorded errors by their severity, type and related node.
+func (er *errorRecorder) String() string {
+	var s []string
+	for _, err := range er.errors {
+		related := "<nil>"
+		if n := err.GetLocation().GetRelatedNode(); n != nil {
+			related = n.GetNodeName()
+		}
+		s = append(s, fmt.Sprintf("%v:%v:%v", err.GetSeverity(), err.GetType(), related))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestDOMErrorNormalizeDocument(t *testing.T) {
+	for _, proceed := range []bool{true, false} {
+		doc := mustParse(t, `<r><![CDATA[a]]><!--c--><?pi data?></r>`)
+		root := doc.GetDocumentElement()
+		root.GetFirstChild().(Text).SetText("1]]>2")
+		root.GetChildNodes()[1].(Comment).SetComment("a--b")
+		root.GetLastChild().(ProcessingInstruction).SetData("?>")
+		root.SetAttribute("a", "\x01")
+
+		recorder := &errorRecorder{proceed: proceed}
+		if err := doc.GetDomConfig().SetParameter("error-handler", recorder); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		doc.NormalizeDocument()
+
+		expected := "SEVERITY_WARNING:cdata-sections-splitted:#cdata-section"
+		if proceed {
+			expected += " SEVERITY_ERROR:wf-invalid-character:a" +
+				" SEVERITY_ERROR:wf-invalid-character:#comment" +
+				" SEVERITY_ERROR:wf-invalid-character:pi"
+		}
+		if recorder.String() != expected {
+			t.Errorf("expected '%v', got '%v'", expected, recorder)
+		}
+	}
+
+	doc := mustParse(t, `<r><![CDATA[a]]></r>`)
+	doc.GetDocumentElement().GetFirstChild().(Text).SetText("1]]>2")
+	recorder := &errorRecorder{proceed: true}
+	doc.GetDomConfig().SetParameter("error-handler", recorder)
+	doc.GetDomConfig().SetParameter("split-cdata-sections", false)
+	doc.NormalizeDocument()
+	if recorder.String() != "SEVERITY_ERROR:invalid-data-in-cdata-section:#cdata-section" {
+		t.Errorf("unexpected errors '%v'", recorder)
+	}
+	if err := doc.GetDomConfig().SetParameter("error-handler", "handler"); err == nil || !strings.HasPrefix(err.Error(), ErrorTypeMismatch.Error()) {
+		t.Errorf("expected %v, got %v", ErrorTypeMismatch, err)
+	}
+	if handler, _ := doc.GetDomConfig().GetParameter("error-handler"); handler != recorder {
+		t.Errorf("expected the handler to be unchanged, got %v", handler)
+	}
+}
+
+func TestDOMErrorParser(t *testing.T) {
+	recorder := &errorRecorder{proceed: true}
+	parser := NewParser(strings.NewReader("<root>\n<a></b></root>"))
+	parser.Configuration.ErrorHandler = recorder
+	if _, err := parser.Parse(); err == nil {
+		t.Error("expected an error")
+	}
+	if len(recorder.errors) != 1 {
+		t.Errorf("expected one error, got %v", recorder)
+		t.FailNow()
+	}
+	fatal := recorder.errors[0]
+	if fatal.GetSeverity() != SeverityFatalError || fatal.GetRelatedException() == nil || fatal.GetLocation().GetLineNumber() != 2 || fatal.GetLocation().GetByteOffset() != 10 {
+		t.Errorf("unexpected fatal error %v at line %v, offset %v", fatal, fatal.GetLocation().GetLineNumber(), fatal.GetLocation().GetByteOffset())
+	}
+
+	// The handler decides whether validation errors are accepted.
+	for _, proceed := range []bool{true, false} {
+		recorder := &errorRecorder{proceed: proceed}
+		parser := NewParser(strings.NewReader(`<!DOCTYPE root [<!ELEMENT root EMPTY>]><root>not empty</root>`))
+		parser.Configuration.Validate = true
+		parser.Configuration.ErrorHandler = recorder
+		doc, err := parser.Parse()
+		if (doc != nil) != proceed || (err == nil) != proceed {
+			t.Errorf("proceed %v: unexpected result %v, %v", proceed, doc, err)
+		}
+		if recorder.String() != "SEVERITY_ERROR:validation-error:root" {
+			t.Errorf("unexpected errors '%v'", recorder)
+		}
+	}
+}
+
+func TestDOMErrorSerializer(t *testing.T) {
+	doc := mustParse(t, `<r><![CDATA[a]]><!--c--><?pi data?><after/></r>`)
+	root := doc.GetDocumentElement()
+	root.GetFirstChild().(Text).SetText("1]]>2")
+	root.GetChildNodes()[1].(Comment).SetComment("a--b")
+	root.GetChildNodes()[2].(ProcessingInstruction).SetData("?>")
+
+	recorder := &errorRecorder{proceed: true}
+	ser := NewSerializer()
+	ser.Configuration.OmitXMLDeclaration = true
+	ser.Configuration.ErrorHandler = recorder
+	var sb strings.Builder
+	ser.Serialize(doc, &sb)
+
+	expected := "SEVERITY_WARNING:cdata-sections-splitted:#cdata-section" +
+		" SEVERITY_ERROR:wf-invalid-character:#comment" +
+		" SEVERITY_FATAL_ERROR:wf-invalid-character:pi"
+	if recorder.String() != expected {
+		t.Errorf("expected '%v', got '%v'", expected, recorder)
+	}
+	if out := sb.String(); strings.Contains(out, "pi") || strings.Contains(out, "after") {
+		t.Errorf("expected the serialization to stop at the fatal error, got %v", out)
+	}
+
+	// Stop at the first problem.
+	recorder = &errorRecorder{proceed: false}
+	ser.Configuration.ErrorHandler = recorder
+	sb.Reset()
+	ser.Serialize(doc, &sb)
+	if len(recorder.errors) != 1 || strings.Contains(sb.String(), "a--b") {
+		t.Errorf("expected the serialization to stop at the first problem, got '%v' and %v", recorder, sb.String())
+	}
+}
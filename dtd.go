@@ -0,0 +1,787 @@
+package dom
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// This file contains a (very) basic scanner for Document Type Definitions, since
+// the encoding/xml package hands the complete <!DOCTYPE ...> declaration over as
+// a single xml.Directive token, and does nothing with it.
+
+// dtdScanner scans declarations in a DTD, or the DOCTYPE declaration itself.
+type dtdScanner struct {
+	input string
+	pos   int
+}
+
+// eof returns true when all input has been consumed.
+func (s *dtdScanner) eof() bool {
+	return s.pos >= len(s.input)
+}
+
+// peek returns the next byte without consuming it, or 0 at the end of the input.
+func (s *dtdScanner) peek() byte {
+	if s.eof() {
+		return 0
+	}
+	return s.input[s.pos]
+}
+
+// skipSpace skips any whitespace, and returns true if there was any.
+func (s *dtdScanner) skipSpace() bool {
+	start := s.pos
+	for !s.eof() && isSpace(s.input[s.pos]) {
+		s.pos++
+	}
+	return s.pos > start
+}
+
+// consume consumes the given literal if the input continues with it.
+func (s *dtdScanner) consume(literal string) bool {
+	if strings.HasPrefix(s.input[s.pos:], literal) {
+		s.pos += len(literal)
+		return true
+	}
+	return false
+}
+
+// name scans an XML Name, which ends at whitespace or any of the delimiters
+// used in declarations.
+func (s *dtdScanner) name() (string, error) {
+	start := s.pos
+	for !s.eof() && !isSpace(s.input[s.pos]) && !strings.ContainsRune("[]()|,?*+>\"'%;", rune(s.input[s.pos])) {
+		s.pos++
+	}
+	name := s.input[start:s.pos]
+	if !XMLName(name).IsValid() {
+		return "", fmt.Errorf("%v: '%v' is not a valid name", ErrorInvalidCharacter, name)
+	}
+	return name, nil
+}
+
+// quoted scans a literal enclosed in either single or double quotes, and returns
+// the literal without the quotes.
+func (s *dtdScanner) quoted() (string, error) {
+	quote := s.peek()
+	if quote != '"' && quote != '\'' {
+		return "", fmt.Errorf("expected a quoted literal at offset %d", s.pos)
+	}
+	end := strings.IndexByte(s.input[s.pos+1:], quote)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated literal at offset %d", s.pos)
+	}
+	literal := s.input[s.pos+1 : s.pos+1+end]
+	s.pos += end + 2
+	return literal, nil
+}
+
+// externalID scans an optional external identifier, being either SYSTEM "systemID"
+// or PUBLIC "publicID" "systemID".
+func (s *dtdScanner) externalID() (publicID, systemID string, err error) {
+	switch {
+	case s.consume("SYSTEM"):
+		s.skipSpace()
+		systemID, err = s.quoted()
+	case s.consume("PUBLIC"):
+		s.skipSpace()
+		if publicID, err = s.quoted(); err != nil {
+			return
+		}
+		s.skipSpace()
+		systemID, err = s.quoted()
+	}
+	return
+}
+
+// skipUntil skips everything up to and including the given terminator. Returns false
+// when the terminator is not found.
+func (s *dtdScanner) skipUntil(terminator string) bool {
+	end := strings.Index(s.input[s.pos:], terminator)
+	if end < 0 {
+		return false
+	}
+	s.pos += end + len(terminator)
+	return true
+}
+
+// skipDecl skips the remainder of a markup declaration, up to and including the
+// closing >. Quoted literals may contain a > themselves.
+func (s *dtdScanner) skipDecl() bool {
+	for !s.eof() {
+		switch s.peek() {
+		case '"', '\'':
+			if _, err := s.quoted(); err != nil {
+				return false
+			}
+		case '>':
+			s.pos++
+			return true
+		default:
+			s.pos++
+		}
+	}
+	return false
+}
+
+// isSpace returns true for the whitespace characters defined by the XML spec.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// parseDoctype parses the directive of a DOCTYPE declaration (i.e. everything between
+// the <! and the >) and returns the DocumentType it describes, owned by the given
+// Document. The internal subset is kept as-is.
+func parseDoctype(owner Document, directive string) (*domDocumentType, error) {
+	s := &dtdScanner{input: directive}
+	if !s.consume("DOCTYPE") || !s.skipSpace() {
+		return nil, fmt.Errorf("malformed DOCTYPE declaration: '%v'", directive)
+	}
+
+	name, err := s.name()
+	if err != nil {
+		return nil, err
+	}
+	s.skipSpace()
+
+	publicID, systemID, err := s.externalID()
+	if err != nil {
+		return nil, fmt.Errorf("malformed DOCTYPE declaration: %v", err)
+	}
+	s.skipSpace()
+
+	doctype := newDocumentType(owner, name, publicID, systemID)
+
+	// The internal subset is everything between the square brackets. Declarations in
+	// the subset may contain brackets themselves, so find the last one.
+	if s.consume("[") {
+		end := strings.LastIndex(directive, "]")
+		if end < s.pos {
+			return nil, fmt.Errorf("malformed DOCTYPE declaration: unterminated internal subset")
+		}
+		doctype.internalSubset = directive[s.pos:end]
+		s.pos = end + 1
+		s.skipSpace()
+
+		if err := parseDeclarations(doctype, doctype.internalSubset); err != nil {
+			return nil, err
+		}
+	}
+
+	if !s.eof() {
+		return nil, fmt.Errorf("malformed DOCTYPE declaration: unexpected '%v'", s.input[s.pos:])
+	}
+
+	return doctype, nil
+}
+
+// parseDeclarations parses the markup declarations in the given subset of a DTD, and
+// adds them to the DocumentType. Comments, processing instructions and parameter
+// entity references are skipped, as well as any declarations which are not used,
+// like notation declarations.
+func parseDeclarations(doctype *domDocumentType, subset string) error {
+	s := &dtdScanner{input: subset}
+	for {
+		s.skipSpace()
+		if s.eof() {
+			return nil
+		}
+
+		var err error
+		switch {
+		case s.consume("<!--"):
+			if !s.skipUntil("-->") {
+				err = fmt.Errorf("unterminated comment")
+			}
+		case s.consume("<?"):
+			if !s.skipUntil("?>") {
+				err = fmt.Errorf("unterminated processing instruction")
+			}
+		case s.consume("<!ENTITY"):
+			err = s.entityDecl(doctype)
+		case s.consume("<!ELEMENT"):
+			err = s.elementDecl(doctype)
+		case s.consume("<!ATTLIST"):
+			err = s.attlistDecl(doctype)
+		case s.consume("<!["):
+			err = fmt.Errorf("conditional sections are not supported")
+		case s.consume("<!"):
+			if !s.skipDecl() {
+				err = fmt.Errorf("unterminated declaration")
+			}
+		case s.consume("%"):
+			// Parameter entity reference: external parameter entities are not read,
+			// so there's nothing to do here.
+			if _, err = s.name(); err == nil && !s.consume(";") {
+				err = fmt.Errorf("expected ';' after parameter entity reference")
+			}
+		default:
+			err = fmt.Errorf("unexpected '%c' at offset %d", s.peek(), s.pos)
+		}
+
+		if err != nil {
+			return fmt.Errorf("malformed DTD: %v", err)
+		}
+	}
+}
+
+// entityDecl parses an entity declaration, just after the <!ENTITY keyword:
+//
+//	<!ENTITY name "replacement text">
+//	<!ENTITY name SYSTEM "uri" [NDATA notation]>
+//	<!ENTITY % name "replacement text">
+//
+// General entities are added to the entities of the DocumentType. Parameter entities
+// are parsed, but discarded. When an entity is declared more than once, the first
+// declaration is binding.
+func (s *dtdScanner) entityDecl(doctype *domDocumentType) error {
+	if !s.skipSpace() {
+		return fmt.Errorf("expected whitespace after <!ENTITY")
+	}
+	parameter := s.consume("%")
+	if parameter && !s.skipSpace() {
+		return fmt.Errorf("expected whitespace after %%")
+	}
+
+	name, err := s.name()
+	if err != nil {
+		return err
+	}
+	if !s.skipSpace() {
+		return fmt.Errorf("expected whitespace after entity name '%v'", name)
+	}
+
+	entity := newEntity(doctype.GetOwnerDocument(), name)
+	if c := s.peek(); c == '"' || c == '\'' {
+		value, err := s.quoted()
+		if err != nil {
+			return err
+		}
+		if entity.value, err = expandCharRefs(value); err != nil {
+			return err
+		}
+		entity.internal = true
+	} else {
+		if entity.publicID, entity.systemID, err = s.externalID(); err != nil {
+			return err
+		}
+		if entity.systemID == "" {
+			return fmt.Errorf("expected a literal or external ID for entity '%v'", name)
+		}
+		if s.skipSpace() && s.consume("NDATA") {
+			s.skipSpace()
+			if entity.notationName, err = s.name(); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.skipSpace()
+	if !s.consume(">") {
+		return fmt.Errorf("expected '>' to end the declaration of entity '%v'", name)
+	}
+
+	if !parameter && doctype.entities.GetNamedItem(name) == nil {
+		doctype.entities.SetNamedItem(entity)
+	}
+	return nil
+}
+
+// expandCharRefs replaces character references (&#...; and &#x...;) in an entity value
+// with the characters they represent. The XML spec requires this to be done when the
+// entity is declared. Entity references are left alone, they are expanded when used.
+func expandCharRefs(value string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(value, "&#")
+		if start < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(value[start:], ';')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated character reference in '%v'", value)
+		}
+
+		ref := value[start+2 : start+end]
+		base := 10
+		if strings.HasPrefix(ref, "x") {
+			ref = ref[1:]
+			base = 16
+		}
+		n, err := strconv.ParseUint(ref, base, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid character reference '%v'", value[start:start+end+1])
+		}
+
+		b.WriteString(value[:start])
+		b.WriteRune(rune(n))
+		value = value[start+end+1:]
+	}
+}
+
+// contentType is the type of content an element may have, as declared in the DTD.
+type contentType int
+
+const (
+	contentEmpty    contentType = iota // EMPTY: no content at all.
+	contentAny                         // ANY: any declared elements and character data.
+	contentMixed                       // (#PCDATA|a|b)*: character data and the given elements.
+	contentChildren                    // (a,b)*: child elements only, matching the content model.
+)
+
+// elementDecl is an element type declaration: <!ELEMENT name contentspec>
+type elementDecl struct {
+	name        string
+	contentType contentType
+	model       *contentParticle // The content model, if the content type is contentChildren.
+	mixed       []string         // The allowed elements, if the content type is contentMixed.
+}
+
+// contentParticle is a part of the content model of an element, being either the name
+// of an element, or a choice or sequence of other particles. Each of them can have an
+// occurrence indicator, being '?', '*' or '+', or 0 if the particle occurs exactly once.
+type contentParticle struct {
+	name       string             // The element name, or empty for choices and sequences.
+	choice     bool               // True for a choice (a|b), false for a sequence (a,b).
+	particles  []*contentParticle // The particles of the choice or sequence.
+	occurrence byte
+}
+
+// matches returns true if the given sequence of element names matches the content model.
+func (cp *contentParticle) matches(names []string) bool {
+	return cp.match(names, map[int]bool{0: true})[len(names)]
+}
+
+// match matches the particle against the element names, starting at any of the given
+// positions. Returns every position where a match could end.
+func (cp *contentParticle) match(names []string, starts map[int]bool) map[int]bool {
+	switch cp.occurrence {
+	case '?':
+		ends := cp.matchOnce(names, starts)
+		for pos := range starts {
+			ends[pos] = true
+		}
+		return ends
+	case '*', '+':
+		ends := make(map[int]bool)
+		if cp.occurrence == '*' {
+			for pos := range starts {
+				ends[pos] = true
+			}
+		}
+		// Keep on matching from the new positions, until no new positions are found.
+		for next := cp.matchOnce(names, starts); len(next) > 0; next = cp.matchOnce(names, next) {
+			for pos := range next {
+				if ends[pos] {
+					delete(next, pos)
+				}
+				ends[pos] = true
+			}
+		}
+		return ends
+	default:
+		return cp.matchOnce(names, starts)
+	}
+}
+
+// matchOnce matches the particle exactly once, regardless of the occurrence indicator.
+func (cp *contentParticle) matchOnce(names []string, starts map[int]bool) map[int]bool {
+	ends := make(map[int]bool)
+	switch {
+	case cp.name != "":
+		for pos := range starts {
+			if pos < len(names) && names[pos] == cp.name {
+				ends[pos+1] = true
+			}
+		}
+	case cp.choice:
+		for _, p := range cp.particles {
+			for pos := range p.match(names, starts) {
+				ends[pos] = true
+			}
+		}
+	default:
+		ends = starts
+		for _, p := range cp.particles {
+			ends = p.match(names, ends)
+		}
+	}
+	return ends
+}
+
+func (cp *contentParticle) String() string {
+	s := cp.name
+	if cp.name == "" {
+		sep := ","
+		if cp.choice {
+			sep = "|"
+		}
+		var parts []string
+		for _, p := range cp.particles {
+			parts = append(parts, p.String())
+		}
+		s = "(" + strings.Join(parts, sep) + ")"
+	}
+	if cp.occurrence != 0 {
+		s += string(cp.occurrence)
+	}
+	return s
+}
+
+// elementDecl parses an element type declaration, just after the <!ELEMENT keyword:
+//
+//	<!ELEMENT name EMPTY>
+//	<!ELEMENT name ANY>
+//	<!ELEMENT name (#PCDATA|a|b)*>
+//	<!ELEMENT name (a,(b|c)*,d?)+>
+//
+// When an element type is declared more than once, the first declaration is binding.
+func (s *dtdScanner) elementDecl(doctype *domDocumentType) error {
+	if !s.skipSpace() {
+		return fmt.Errorf("expected whitespace after <!ELEMENT")
+	}
+	name, err := s.name()
+	if err != nil {
+		return err
+	}
+	if !s.skipSpace() {
+		return fmt.Errorf("expected whitespace after element name '%v'", name)
+	}
+
+	decl := &elementDecl{name: name}
+	switch {
+	case s.consume("EMPTY"):
+		decl.contentType = contentEmpty
+	case s.consume("ANY"):
+		decl.contentType = contentAny
+	case s.peek() == '(':
+		start := s.pos
+		s.pos++
+		s.skipSpace()
+		if s.consume("#PCDATA") {
+			decl.contentType = contentMixed
+			if decl.mixed, err = s.mixed(); err != nil {
+				return err
+			}
+		} else {
+			s.pos = start
+			decl.contentType = contentChildren
+			if decl.model, err = s.particle(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("expected a content specification for element '%v'", name)
+	}
+
+	s.skipSpace()
+	if !s.consume(">") {
+		return fmt.Errorf("expected '>' to end the declaration of element '%v'", name)
+	}
+	if _, ok := doctype.elements[name]; !ok {
+		doctype.elements[name] = decl
+	}
+	return nil
+}
+
+// mixed parses the remainder of a mixed content declaration, just after the #PCDATA,
+// and returns the names of the elements which are allowed.
+func (s *dtdScanner) mixed() ([]string, error) {
+	var names []string
+	for {
+		s.skipSpace()
+		if s.consume(")") {
+			// The asterisk is required when any element names are given.
+			if !s.consume("*") && len(names) > 0 {
+				return nil, fmt.Errorf("expected ')*' to end mixed content")
+			}
+			return names, nil
+		}
+		if !s.consume("|") {
+			return nil, fmt.Errorf("expected '|' or ')' in mixed content at offset %d", s.pos)
+		}
+		s.skipSpace()
+		name, err := s.name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+}
+
+// particle parses a content particle: a name, or a choice or sequence enclosed in
+// parentheses, followed by an optional occurrence indicator.
+func (s *dtdScanner) particle() (*contentParticle, error) {
+	cp := &contentParticle{}
+	if s.consume("(") {
+		var sep byte
+		for {
+			s.skipSpace()
+			p, err := s.particle()
+			if err != nil {
+				return nil, err
+			}
+			cp.particles = append(cp.particles, p)
+
+			s.skipSpace()
+			c := s.peek()
+			if c == ')' {
+				s.pos++
+				break
+			}
+			if c != '|' && c != ',' || sep != 0 && c != sep {
+				return nil, fmt.Errorf("unexpected '%c' in content model at offset %d", c, s.pos)
+			}
+			sep = c
+			s.pos++
+		}
+		cp.choice = sep == '|'
+	} else {
+		name, err := s.name()
+		if err != nil {
+			return nil, err
+		}
+		cp.name = name
+	}
+
+	if c := s.peek(); c == '?' || c == '*' || c == '+' {
+		cp.occurrence = c
+		s.pos++
+	}
+	return cp, nil
+}
+
+// attributeDecl is the declaration of a single attribute in an attribute-list
+// declaration: <!ATTLIST element name type default>
+type attributeDecl struct {
+	name     string
+	attrType string   // CDATA, ID, IDREF, IDREFS, ENTITY, ENTITIES, NMTOKEN, NMTOKENS, NOTATION or ENUMERATION.
+	values   []string // The allowed values, for the NOTATION and ENUMERATION types.
+	mode     string   // Either #REQUIRED, #IMPLIED, #FIXED, or empty if there is only a default value.
+	value    string   // The default value, if any.
+}
+
+// attlistDecl parses an attribute-list declaration, just after the <!ATTLIST keyword:
+//
+//	<!ATTLIST element
+//	    id      ID             #REQUIRED
+//	    ref     IDREF          #IMPLIED
+//	    kind    (big|small)    "big"
+//	    version CDATA          #FIXED "1.0">
+//
+// When an attribute is declared more than once for an element, the first declaration
+// is binding.
+func (s *dtdScanner) attlistDecl(doctype *domDocumentType) error {
+	if !s.skipSpace() {
+		return fmt.Errorf("expected whitespace after <!ATTLIST")
+	}
+	element, err := s.name()
+	if err != nil {
+		return err
+	}
+
+	for {
+		s.skipSpace()
+		if s.consume(">") {
+			return nil
+		}
+		decl, err := s.attributeDecl()
+		if err != nil {
+			return fmt.Errorf("in attribute-list of element '%v': %v", element, err)
+		}
+		if doctype.getAttributeDecl(element, decl.name) == nil {
+			doctype.attributes[element] = append(doctype.attributes[element], decl)
+		}
+	}
+}
+
+// attributeDecl parses the declaration of a single attribute in an attribute-list.
+func (s *dtdScanner) attributeDecl() (*attributeDecl, error) {
+	name, err := s.name()
+	if err != nil {
+		return nil, err
+	}
+	decl := &attributeDecl{name: name}
+	if !s.skipSpace() {
+		return nil, fmt.Errorf("expected whitespace after attribute name '%v'", name)
+	}
+
+	if s.peek() == '(' {
+		decl.attrType = "ENUMERATION"
+	} else {
+		for _, attrType := range []string{"CDATA", "IDREFS", "IDREF", "ID", "ENTITY", "ENTITIES", "NMTOKENS", "NMTOKEN", "NOTATION"} {
+			if s.consume(attrType) {
+				decl.attrType = attrType
+				break
+			}
+		}
+		if decl.attrType == "" {
+			return nil, fmt.Errorf("unknown type of attribute '%v'", name)
+		}
+	}
+	if decl.attrType == "NOTATION" && !s.skipSpace() {
+		return nil, fmt.Errorf("expected whitespace after NOTATION")
+	}
+	if decl.attrType == "ENUMERATION" || decl.attrType == "NOTATION" {
+		if decl.values, err = s.enumeration(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !s.skipSpace() {
+		return nil, fmt.Errorf("expected whitespace after the type of attribute '%v'", name)
+	}
+	for _, mode := range []string{"#REQUIRED", "#IMPLIED", "#FIXED"} {
+		if s.consume(mode) {
+			decl.mode = mode
+			break
+		}
+	}
+	if decl.mode == "" || decl.mode == "#FIXED" {
+		s.skipSpace()
+		value, err := s.quoted()
+		if err != nil {
+			return nil, err
+		}
+		if value, err = expandCharRefs(value); err != nil {
+			return nil, err
+		}
+		decl.value = decl.normalize(value)
+	}
+	return decl, nil
+}
+
+// enumeration parses a list of names enclosed in parentheses, like (a|b|c).
+func (s *dtdScanner) enumeration() ([]string, error) {
+	if !s.consume("(") {
+		return nil, fmt.Errorf("expected '(' at offset %d", s.pos)
+	}
+	var values []string
+	for {
+		s.skipSpace()
+		start := s.pos
+		for !s.eof() && !isSpace(s.peek()) && s.peek() != '|' && s.peek() != ')' {
+			s.pos++
+		}
+		if s.pos == start {
+			return nil, fmt.Errorf("expected a name token at offset %d", s.pos)
+		}
+		values = append(values, s.input[start:s.pos])
+
+		s.skipSpace()
+		if s.consume(")") {
+			return values, nil
+		}
+		if !s.consume("|") {
+			return nil, fmt.Errorf("expected '|' or ')' at offset %d", s.pos)
+		}
+	}
+}
+
+// normalize normalizes an attribute value according to the type of the attribute. All
+// types except CDATA are tokenized, which means leading and trailing whitespace is
+// removed, and any other sequence of whitespace is replaced by a single space.
+func (decl *attributeDecl) normalize(value string) string {
+	if decl.attrType == "CDATA" {
+		return value
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// getAttributeDecl returns the declaration of the attribute of the given element, or nil
+// if it is not declared.
+func (dt *domDocumentType) getAttributeDecl(element, name string) *attributeDecl {
+	for _, decl := range dt.attributes[element] {
+		if decl.name == name {
+			return decl
+		}
+	}
+	return nil
+}
+
+// applyAttributeDecls applies the attribute-list declarations of the DTD to the attributes
+// of the given element. Missing attributes with a default value are added, but they are
+// not specified. The values of tokenized attributes are normalized, and attributes of
+// type ID are marked as such.
+func (dt *domDocumentType) applyAttributeDecls(elem Element) {
+	for _, decl := range dt.attributes[elem.GetNodeName()] {
+		node := elem.GetAttributes().GetNamedItem(decl.name)
+		if node == nil {
+			if decl.mode != "" && decl.mode != "#FIXED" {
+				continue
+			}
+			attr, err := dt.ownerDocument.CreateAttribute(decl.name)
+			if err != nil {
+				continue
+			}
+			attr.SetValue(decl.value)
+			attr.setSpecified(false)
+			elem.SetAttributeNode(attr)
+			node = attr
+		}
+
+		attr := node.(Attr)
+		if value := decl.normalize(attr.GetValue()); value != attr.GetValue() {
+			// Normalizing does not make an attribute specified.
+			specified := attr.IsSpecified()
+			attr.SetValue(value)
+			attr.setSpecified(specified)
+		}
+		attr.setID(decl.attrType == "ID")
+	}
+}
+
+// applyIDType marks the attribute as being of type ID, if it is declared as such for its
+// owner element. Unlike applyAttributeDecls, it never unmarks an attribute, so the marks
+// of Element.SetIdAttribute are kept.
+func (dt *domDocumentType) applyIDType(attr Attr) {
+	if elem := attr.GetOwnerElement(); elem != nil {
+		if decl := dt.getAttributeDecl(elem.GetNodeName(), attr.GetNodeName()); decl != nil && decl.attrType == "ID" {
+			attr.setID(true)
+		}
+	}
+}
+
+// restoreAttributeDefault adds the attribute with the given name to the element, with its
+// default value from the DTD, after the attribute was removed. It does nothing if the DTD
+// does not declare a default value.
+func (dt *domDocumentType) restoreAttributeDefault(elem Element, name string) {
+	decl := dt.getAttributeDecl(elem.GetNodeName(), name)
+	if decl == nil || (decl.mode != "" && decl.mode != "#FIXED") {
+		return
+	}
+	attr, err := dt.ownerDocument.CreateAttribute(name)
+	if err != nil {
+		return
+	}
+	attr.SetValue(decl.normalize(decl.value))
+	attr.setSpecified(false)
+	attr.setID(decl.attrType == "ID")
+	elem.SetAttributeNode(attr)
+}
+
+// loadExternalSubset reads the external subset of the DTD using the resolver, and adds
+// its declarations to the DocumentType. The declarations of the internal subset take
+// precedence, since these are read first. The external subset is read at most once.
+func (dt *domDocumentType) loadExternalSubset(resolver DTDResolver) error {
+	if dt.external || resolver == nil || dt.systemID == "" {
+		return nil
+	}
+	r, err := resolver.Resolve(dt.publicID, dt.systemID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the external subset '%v': %v", dt.systemID, err)
+	}
+	if r == nil {
+		// The resolver does not know about this DTD.
+		return nil
+	}
+	subset, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read the external subset '%v': %v", dt.systemID, err)
+	}
+	dt.external = true
+	return parseDeclarations(dt, string(subset))
+}
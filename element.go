@@ -11,6 +11,7 @@ type domElement struct {
 	parentNode    Node         // Parent node
 	attributes    NamedNodeMap // Attributes on this element.
 	ownerDocument Document     // Owner document.
+	state         nodeState    // The user data of this node.
 	namespaceURI  string       // Namespace uri.
 
 	// Element specific things:
@@ -22,7 +23,7 @@ func newElement(owner Document, tagname string, namespaceURI string) Element {
 	e.ownerDocument = owner
 	e.tagName = XMLName(tagname)
 	e.namespaceURI = namespaceURI
-	e.attributes = newNamedNodeMap()
+	e.attributes = newAttributeMap(e)
 	return e
 }
 
@@ -72,7 +73,7 @@ func (de *domElement) GetAttributes() NamedNodeMap {
 }
 
 func (de *domElement) HasAttributes() bool {
-	return len(de.attributes.GetItems()) > 0
+	return de.attributes.Length() > 0
 }
 
 func (de *domElement) GetOwnerDocument() Document {
@@ -85,10 +86,15 @@ func (de *domElement) AppendChild(child Node) error {
 	}
 
 	// Uh, we can do type assertion, or this.
-	if child.GetNodeType() == AttributeNode || child.GetNodeType() == DocumentNode {
+	if child.GetNodeType() == AttributeNode || child.GetNodeType() == DocumentNode || child.GetNodeType() == DocumentTypeNode {
 		return fmt.Errorf("%v: an attempt was made to insert a node where it is not permitted", ErrorHierarchyRequest)
 	}
 
+	// Appending a DocumentFragment moves all its children to this element instead.
+	if child.GetNodeType() == DocumentFragmentNode {
+		return insertFragment(de, child, nil)
+	}
+
 	// Remove child from it's exisiting parent, if any.
 	parent := child.GetParentNode()
 	if parent != nil {
@@ -97,6 +103,7 @@ func (de *domElement) AppendChild(child Node) error {
 
 	child.setParentNode(de)
 	de.nodes = append(de.nodes, child)
+	notifyInserted(de, child)
 	return nil
 }
 
@@ -109,8 +116,10 @@ func (de *domElement) RemoveChild(oldChild Node) (Node, error) {
 
 	for i, child := range de.GetChildNodes() {
 		if child == oldChild {
+			notifyRemoving(de, child)
 			// Slice trickery to remove the node at the found index:
 			de.nodes = append(de.nodes[:i], de.nodes[i+1:]...)
+			child.setParentNode(nil)
 			return child, nil
 		}
 	}
@@ -135,21 +144,34 @@ func (de *domElement) ReplaceChild(newChild, oldChild Node) (Node, error) {
 		return nil, ErrorWrongDocument
 	}
 
+	if newChild.GetNodeType() == DocumentFragmentNode {
+		return replaceWithFragment(de, newChild, oldChild)
+	}
+
 	// Find the old child, and replace it with the new child.
 	for i, child := range de.GetChildNodes() {
 		if child == oldChild {
+			if newChild == oldChild {
+				// Replacing a child with itself changes nothing.
+				return oldChild, nil
+			}
 			// Check if newChild has a parent (i.e., it's in the tree).
 			ncParent := newChild.GetParentNode()
 			if ncParent != nil {
 				// Remove the newChild from its parent.
 				ncParent.RemoveChild(newChild)
 			}
+			notifyRemoving(de, oldChild)
 
+			// The index may have shifted when newChild was a preceding sibling.
+			i = indexOf(de.nodes, oldChild)
 			// Slice trickery, again. It will make a new underlying slice with one element,
 			// the 'newChild', and then append the rest of the de.nodes to that.
 			de.nodes = append(de.nodes[:i], append([]Node{newChild}, de.nodes[i+1:]...)...)
 			// Change the parent node:
 			newChild.setParentNode(de)
+			oldChild.setParentNode(nil)
+			notifyInserted(de, newChild)
 
 			return oldChild, nil
 		}
@@ -167,7 +189,7 @@ func (de *domElement) InsertBefore(newChild, refChild Node) (Node, error) {
 		return nil, ErrorHierarchyRequest
 	}
 
-	if newChild.GetNodeType() == AttributeNode {
+	if newChild.GetNodeType() == AttributeNode || newChild.GetNodeType() == DocumentTypeNode {
 		return nil, ErrorHierarchyRequest
 	}
 
@@ -185,6 +207,17 @@ func (de *domElement) InsertBefore(newChild, refChild Node) (Node, error) {
 		return newChild, nil
 	}
 
+	// Inserting a DocumentFragment inserts all its children before refChild, in order.
+	if newChild.GetNodeType() == DocumentFragmentNode {
+		if refChild.GetParentNode() != de {
+			return nil, ErrorNotFound
+		}
+		if err := insertFragment(de, newChild, refChild); err != nil {
+			return nil, err
+		}
+		return newChild, nil
+	}
+
 	// Find the reference child, insert newChild before that one.
 	for i, child := range de.GetChildNodes() {
 		if child == refChild {
@@ -193,8 +226,11 @@ func (de *domElement) InsertBefore(newChild, refChild Node) (Node, error) {
 			if ncParent != nil {
 				ncParent.RemoveChild(newChild)
 			}
+			// The index may have shifted when newChild was a preceding sibling.
+			i = indexOf(de.nodes, refChild)
 			newChild.setParentNode(de)
 			de.nodes = append(de.nodes[:i], append([]Node{newChild}, de.nodes[i:]...)...)
+			notifyInserted(de, newChild)
 			return newChild, nil
 		}
 	}
@@ -228,7 +264,17 @@ func (de *domElement) GetTagName() string {
 	return string(de.tagName)
 }
 
+// SetAttribute adds a new attribute, or changes the value of the attribute with the given
+// name if it already exists. A prefix in the name must be declared.
 func (de *domElement) SetAttribute(name, value string) error {
+	if isReadOnly(de) {
+		return ErrorNoModificationAllowed
+	}
+	if existing := de.GetAttributeNode(name); existing != nil {
+		existing.SetValue(value)
+		return nil
+	}
+
 	attr, err := de.GetOwnerDocument().CreateAttribute(name)
 	if err != nil {
 		return err
@@ -249,38 +295,69 @@ func (de *domElement) SetAttribute(name, value string) error {
 	}
 
 	if !namespaceFound {
-		return fmt.Errorf("the namespace for prefix '%v' has not been declared", attr.GetNamespacePrefix())
+		return fmt.Errorf("%v: the namespace for prefix '%v' has not been declared", ErrorNamespace, attr.GetNamespacePrefix())
 	}
 
 	attr.SetValue(value)
-	attr.setOwnerElement(de)
-	de.attributes.SetNamedItem(attr)
-
-	return nil
+	return de.attributes.SetNamedItem(attr)
 }
 
 // SetAttributeNode adds a new attribute node. If an attribute with that name (nodeName) is
-// already present in the element, it is replaced by the new one. Replacing an attribute node
-// by itself has no effect. To add a new attribute node with a qualified name and namespace
-// URI, use the SetAttributeNodeNS method.
+// already present in the element, it is replaced by the new one, and no longer has an owner
+// element. Replacing an attribute node by itself has no effect. To add a new attribute node
+// with a qualified name and namespace URI, use the SetAttributeNodeNS method.
 func (de *domElement) SetAttributeNode(a Attr) error {
-	// Attribute and Element must share the same owner document.
-	if a.GetOwnerDocument() != de.GetOwnerDocument() {
-		return ErrorWrongDocument
+	if a == nil {
+		return fmt.Errorf("%v: the attribute can not be nil", ErrorNotFound)
 	}
-
-	// Is the Attribute is already owned by another Element?
-	if a.GetOwnerElement() != nil {
-		return ErrorAttrInUse
+	if isReadOnly(de) {
+		return ErrorNoModificationAllowed
 	}
+	return de.attributes.SetNamedItem(a)
+}
 
-	a.setOwnerElement(de)
-	de.attributes.SetNamedItem(a)
-	return nil
+// SetAttributeNodeNS adds a new attribute node. If an attribute with the same namespace URI
+// and local name is already present in the element, it is replaced by the new one.
+func (de *domElement) SetAttributeNodeNS(a Attr) error {
+	if a == nil {
+		return fmt.Errorf("%v: the attribute can not be nil", ErrorNotFound)
+	}
+	if isReadOnly(de) {
+		return ErrorNoModificationAllowed
+	}
+	return de.attributes.SetNamedItemNS(a)
 }
 
-// TODO: SetAttributeNodeNS
+// SetAttributeNS adds a new attribute with the namespace URI and qualified name, or changes
+// the value and prefix of the attribute with the namespace URI and local name, if it exists.
+// It returns an ErrorNamespace when the qualified name is malformed, or if its prefix does
+// not fit the namespace URI.
+func (de *domElement) SetAttributeNS(namespaceURI, qualifiedName, value string) error {
+	if isReadOnly(de) {
+		return ErrorNoModificationAllowed
+	}
+	if err := checkQualifiedName(namespaceURI, qualifiedName); err != nil {
+		return err
+	}
+
+	name := XMLName(qualifiedName)
+	if existing := de.GetAttributeNodeNS(namespaceURI, name.GetLocalPart()); existing != nil {
+		// Only the prefix may change. The map looks up attributes by their current name.
+		existing.setName(qualifiedName)
+		existing.SetValue(value)
+		return nil
+	}
+
+	attr, err := de.GetOwnerDocument().CreateAttributeNS(namespaceURI, qualifiedName)
+	if err != nil {
+		return err
+	}
+	attr.SetValue(value)
+	return de.SetAttributeNodeNS(attr)
+}
 
+// GetAttribute returns the value of the attribute with the given name, or an empty string
+// if there is no such attribute.
 func (de *domElement) GetAttribute(name string) string {
 	if theAttr := de.attributes.GetNamedItem(name); theAttr != nil {
 		return theAttr.GetNodeValue()
@@ -290,6 +367,112 @@ func (de *domElement) GetAttribute(name string) string {
 	return ""
 }
 
+// GetAttributeNS returns the value of the attribute with the namespace URI and local name,
+// or an empty string if there is no such attribute.
+func (de *domElement) GetAttributeNS(namespaceURI, localName string) string {
+	if attr := de.GetAttributeNodeNS(namespaceURI, localName); attr != nil {
+		return attr.GetValue()
+	}
+	return ""
+}
+
+// GetAttributeNode returns the attribute with the given name, or nil if there is none.
+func (de *domElement) GetAttributeNode(name string) Attr {
+	if attr, ok := de.attributes.GetNamedItem(name).(Attr); ok {
+		return attr
+	}
+	return nil
+}
+
+// GetAttributeNodeNS returns the attribute with the namespace URI and local name, or nil
+// if there is none.
+func (de *domElement) GetAttributeNodeNS(namespaceURI, localName string) Attr {
+	if attr, ok := de.attributes.GetNamedItemNS(namespaceURI, localName).(Attr); ok {
+		return attr
+	}
+	return nil
+}
+
+// HasAttribute returns true if the element has an attribute with the given name, either
+// specified or with a default value.
+func (de *domElement) HasAttribute(name string) bool {
+	return de.GetAttributeNode(name) != nil
+}
+
+// HasAttributeNS returns true if the element has an attribute with the namespace URI and
+// local name, either specified or with a default value.
+func (de *domElement) HasAttributeNS(namespaceURI, localName string) bool {
+	return de.GetAttributeNodeNS(namespaceURI, localName) != nil
+}
+
+// RemoveAttribute removes the attribute with the given name. When the DTD declares a default
+// value for the attribute, it is replaced by an attribute with the default value. Removing
+// an attribute which does not exist has no effect.
+func (de *domElement) RemoveAttribute(name string) error {
+	if isReadOnly(de) {
+		return ErrorNoModificationAllowed
+	}
+	if attr := de.GetAttributeNode(name); attr != nil {
+		de.removeAttributeNode(attr)
+	}
+	return nil
+}
+
+// RemoveAttributeNS removes the attribute with the namespace URI and local name, like
+// RemoveAttribute does.
+func (de *domElement) RemoveAttributeNS(namespaceURI, localName string) error {
+	if isReadOnly(de) {
+		return ErrorNoModificationAllowed
+	}
+	if attr := de.GetAttributeNodeNS(namespaceURI, localName); attr != nil {
+		de.removeAttributeNode(attr)
+	}
+	return nil
+}
+
+// RemoveAttributeNode removes the attribute node, and returns it. It returns an ErrorNotFound
+// if the attribute is not an attribute of this element.
+func (de *domElement) RemoveAttributeNode(a Attr) (Attr, error) {
+	if isReadOnly(de) {
+		return nil, ErrorNoModificationAllowed
+	}
+	if a == nil || a.GetOwnerElement() != de {
+		return nil, ErrorNotFound
+	}
+	de.removeAttributeNode(a)
+	return a, nil
+}
+
+// SetIdAttribute marks the attribute with the given name as being of type ID, or not, so it
+// is found by Document.GetElementById. It returns an ErrorNotFound if the element does not
+// have the attribute. The mark is lost when the attribute is removed.
+func (de *domElement) SetIdAttribute(name string, isID bool) error {
+	return setIDAttribute(de, de.GetAttributeNode(name), isID)
+}
+
+// SetIdAttributeNS marks the attribute with the namespace URI and local name as being of
+// type ID, or not, like SetIdAttribute does.
+func (de *domElement) SetIdAttributeNS(namespaceURI, localName string, isID bool) error {
+	return setIDAttribute(de, de.GetAttributeNodeNS(namespaceURI, localName), isID)
+}
+
+// SetIdAttributeNode marks the attribute node of this element as being of type ID, or not,
+// like SetIdAttribute does.
+func (de *domElement) SetIdAttributeNode(idAttr Attr, isID bool) error {
+	return setIDAttribute(de, idAttr, isID)
+}
+
+// removeAttributeNode removes the attribute of this element, and restores the default value
+// of the attribute from the DTD, if any.
+func (de *domElement) removeAttributeNode(a Attr) {
+	de.attributes.RemoveNamedItem(a.GetNodeName())
+	if doc := de.GetOwnerDocument(); doc != nil {
+		if doctype, ok := doc.GetDoctype().(*domDocumentType); ok {
+			doctype.restoreAttributeDefault(de, a.GetNodeName())
+		}
+	}
+}
+
 // GetElementsByTagName finds all descendant element with the given tagname.
 // This implementation does a recursive search.
 func (de *domElement) GetElementsByTagName(tagname string) []Element {
@@ -300,6 +483,18 @@ func (de *domElement) GetElementsByTagNameNS(namespaceURI, tagname string) []Ele
 	return getElementsBy(de, namespaceURI, tagname, true)
 }
 
+// QuerySelector finds the first descendant element matching the CSS selectors. Prefixes
+// in the selectors are resolved using this element.
+func (de *domElement) QuerySelector(selectors string) (Element, error) {
+	return querySelector(de, selectors)
+}
+
+// QuerySelectorAll finds all descendant elements matching the CSS selectors, in document
+// order. Prefixes in the selectors are resolved using this element.
+func (de *domElement) QuerySelectorAll(selectors string) ([]Element, error) {
+	return querySelectorAll(de, selectors)
+}
+
 // setTagName is only used internally, when the tagname needs to change. One example is during parsing:
 // The Go encoding/xml package does not directly take prefixes into account, so we do some hackery to
 // make that work. After we found a prefx<->namespace match, we need to change the tagname.
@@ -371,9 +566,9 @@ func (de *domElement) LookupPrefix(requestedNamespace string) (string, bool) {
 
 	// Iterate over attributes with xmlns declarations.
 	if de.GetAttributes() != nil {
-		attrs := de.GetAttributes().GetItems()
-		for _, node := range attrs {
-			a := node.(Attr)
+		attrs := de.GetAttributes()
+		for i := 0; i < attrs.Length(); i++ {
+			a := attrs.Item(i).(Attr)
 			attrpfx := a.GetNamespacePrefix() // xmlns : ... = .........
 			attrloc := a.GetLocalName()       // ..... : pfx = .........
 			attrval := a.GetNodeValue()       // ..... : ... = namespace
@@ -402,9 +597,9 @@ func (de *domElement) LookupNamespaceURI(pfx string) (string, bool) {
 
 	// Check the element's xmlns declarations.
 	if de.GetAttributes() != nil {
-		attrs := de.GetAttributes().GetItems()
-		for _, node := range attrs {
-			a := node.(Attr)
+		attrs := de.GetAttributes()
+		for i := 0; i < attrs.Length(); i++ {
+			a := attrs.Item(i).(Attr)
 			// <elem xmlns="..." />, and prefix is empty:
 			if a.GetNodeName() == "xmlns" && pfx == "" {
 				return a.GetNodeValue(), true
@@ -447,10 +642,8 @@ func (de *domElement) IsDefaultNamespace(namespace string) bool {
 	}
 
 	// TODO verify this loop
-	for k, v := range de.GetAttributes().GetItems() {
-		if k == "xmlns" {
-			return v.GetNodeValue() == namespace
-		}
+	if v := de.GetAttributes().GetNamedItem("xmlns"); v != nil {
+		return v.GetNodeValue() == namespace
 	}
 
 	if parentElement, ok := de.GetParentNode().(Element); ok {
@@ -484,8 +677,10 @@ func (de *domElement) SetTextContent(content string) {
 	if content == "" {
 		return
 	}
-	// Remove existing nodes from this element by initializing an empty Node slice.
-	de.nodes = make([]Node, 0)
+	// Remove existing nodes from this element, one by one so the live objects keep up.
+	for len(de.nodes) > 0 {
+		de.RemoveChild(de.nodes[0])
+	}
 
 	text := de.GetOwnerDocument().CreateText(content)
 	de.AppendChild(text)
@@ -494,34 +689,75 @@ func (de *domElement) SetTextContent(content string) {
 // CloneNode for Elements clones this element. If deep is set to false, it will create a clone of the
 // Element, plus its attributes. If deep is set to true, it will create a clone of all its children (and so on).
 func (de *domElement) CloneNode(deep bool) Node {
+	return de.cloneNode(de.ownerDocument, deep, NodeCloned)
+}
+
+func (de *domElement) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
 	// Clone element. The clone does not have a parent.
-	cloneElement, err := de.ownerDocument.CreateElementNS(de.namespaceURI, string(de.tagName))
+	cloneElement, err := owner.CreateElementNS(de.namespaceURI, string(de.tagName))
 	if err != nil {
 		panic("CreateElement returned an error, but should be impossible at this point")
 	}
 	// Then its attributes.
-	for _, attrNode := range de.GetAttributes().GetItems() {
-		cloneAttr := attrNode.CloneNode(deep).(Attr)
+	for _, attrNode := range attributeList(de) {
+		cloneAttr := attrNode.cloneNode(owner, deep, operation).(Attr)
+		// Unlike a single cloned Attr, the attributes of a cloned Element keep their state.
+		cloneAttr.setSpecified(attrNode.(Attr).IsSpecified())
+		cloneAttr.setID(attrNode.(Attr).IsId())
 		cloneElement.SetAttributeNode(cloneAttr)
 	}
 
-	if !deep {
-		// No deep clone, so return it.
-		return cloneElement
-	}
-	// Do a deep clone.
-	for _, child := range de.GetChildNodes() {
-		childClone := child.CloneNode(true)
-		cloneElement.AppendChild(childClone)
+	if deep {
+		for _, child := range de.GetChildNodes() {
+			childClone := child.cloneNode(owner, true, operation)
+			cloneElement.AppendChild(childClone)
+		}
 	}
 
+	notifyUserDataHandlers(operation, de, cloneElement)
 	return cloneElement
 }
 
+func (de *domElement) Normalize() {
+	normalize(de)
+}
+
+func (de *domElement) IsSameNode(other Node) bool {
+	return isSameNode(de, other)
+}
+
+func (de *domElement) IsEqualNode(other Node) bool {
+	return isEqualNode(de, other)
+}
+
+func (de *domElement) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(de, other)
+}
+
+func (de *domElement) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(de, key, data, handler)
+}
+
+func (de *domElement) GetUserData(key string) interface{} {
+	return getUserData(de, key)
+}
+
 func (de *domElement) ImportNode(n Node, deep bool) Node {
 	return importNode(de.ownerDocument, n, deep)
 }
 
+func (de *domElement) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(de, eventType, listener, useCapture)
+}
+
+func (de *domElement) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(de, eventType, listener, useCapture)
+}
+
+func (de *domElement) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(de, evt)
+}
+
 // Private functions:
 func (de *domElement) setParentNode(parent Node) {
 	de.parentNode = parent
@@ -531,11 +767,15 @@ func (de *domElement) setOwnerDocument(doc Document) {
 	de.ownerDocument = doc
 }
 
+func (de *domElement) getState() *nodeState {
+	return &de.state
+}
+
 // removeNSDeclAndSet finds xmlns:prefix declarations, and removes them. New namespace declarations will be
 // created once we see we need them.
 func (de *domElement) removeNSDecl() {
-	for nsdecl := range de.GetAttributes().GetItems() {
-		if strings.HasPrefix(nsdecl, "xmlns") {
+	for _, attr := range attributeList(de) {
+		if nsdecl := attr.GetNodeName(); strings.HasPrefix(nsdecl, "xmlns") {
 			// remove it.
 			//fmt.Printf("Removing xmlns attribute '%s'\n", nsdecl)
 			de.GetAttributes().RemoveNamedItem(nsdecl)
@@ -2,6 +2,7 @@ package dom
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -851,3 +852,146 @@ func TestElementImportNode(t *testing.T) {
 		}
 	}
 }
+
+func TestElementAttributeNodes(t *testing.T) {
+	doc := NewDocument()
+	elem, _ := doc.CreateElement("elem")
+	doc.AppendChild(elem)
+
+	elem.SetAttribute("a", "1")
+	first := elem.GetAttributeNode("a")
+	if first == nil || first.GetOwnerElement() != elem || !elem.HasAttribute("a") {
+		t.Fatal("expected the attribute to be set")
+	}
+	// Setting the value again keeps the same node.
+	elem.SetAttribute("a", "2")
+	if elem.GetAttributeNode("a") != first || first.GetValue() != "2" {
+		t.Errorf("expected the same attribute with value 2, got %v", elem.GetAttributeNode("a"))
+	}
+
+	// Replacing the attribute node releases the old one.
+	second, _ := doc.CreateAttribute("a")
+	second.SetValue("3")
+	if err := elem.SetAttributeNode(second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if first.GetOwnerElement() != nil || second.GetOwnerElement() != elem || elem.GetAttribute("a") != "3" {
+		t.Error("expected the old attribute to be replaced")
+	}
+	if err := elem.SetAttributeNode(second); err != nil {
+		t.Errorf("expected no error when setting the attribute again, got %v", err)
+	}
+
+	other, _ := doc.CreateElement("other")
+	if err := other.SetAttributeNode(second); err != ErrorAttrInUse {
+		t.Errorf("expected %v, got %v", ErrorAttrInUse, err)
+	}
+	if _, err := other.RemoveAttributeNode(second); err != ErrorNotFound {
+		t.Errorf("expected %v, got %v", ErrorNotFound, err)
+	}
+	foreign, _ := NewDocument().CreateAttribute("b")
+	if err := elem.SetAttributeNode(foreign); err != ErrorWrongDocument {
+		t.Errorf("expected %v, got %v", ErrorWrongDocument, err)
+	}
+
+	if removed, err := elem.RemoveAttributeNode(second); removed != second || err != nil {
+		t.Errorf("unexpected result %v, %v", removed, err)
+	}
+	if second.GetOwnerElement() != nil || elem.HasAttribute("a") || elem.GetAttributeNode("a") != nil {
+		t.Error("expected the attribute to be removed")
+	}
+	// Now it can be used by another element.
+	if err := other.SetAttributeNode(second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	elem.SetAttribute("c", "4")
+	if err := elem.RemoveAttribute("c"); err != nil || elem.HasAttribute("c") {
+		t.Errorf("expected the attribute to be removed, got %v", err)
+	}
+	if err := elem.RemoveAttribute("does-not-exist"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestElementAttributesNS(t *testing.T) {
+	doc := NewDocument()
+	elem, _ := doc.CreateElement("elem")
+	doc.AppendChild(elem)
+
+	if err := elem.SetAttributeNS("urn:one", "one:attr", "1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	attr := elem.GetAttributeNodeNS("urn:one", "attr")
+	if attr == nil || attr.GetOwnerElement() != elem || attr.GetNodeName() != "one:attr" {
+		t.Fatalf("unexpected attribute %v", attr)
+	}
+	if !elem.HasAttributeNS("urn:one", "attr") || elem.HasAttributeNS("urn:two", "attr") || elem.HasAttributeNS("", "attr") {
+		t.Error("expected the attribute to be found by namespace URI and local name only")
+	}
+
+	// Setting it again changes the value and the prefix, but keeps the node.
+	if err := elem.SetAttributeNS("urn:one", "uno:attr", "2"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if elem.GetAttributeNodeNS("urn:one", "attr") != attr || attr.GetNodeName() != "uno:attr" || elem.GetAttributeNS("urn:one", "attr") != "2" {
+		t.Errorf("unexpected attribute %v", attr)
+	}
+	if elem.GetAttribute("uno:attr") != "2" || elem.HasAttribute("one:attr") {
+		t.Error("expected the attribute to be found by its new name")
+	}
+
+	// An attribute with the same local name in another namespace is a different attribute.
+	elem.SetAttributeNS("urn:two", "two:attr", "3")
+	if elem.GetAttributeNS("urn:one", "attr") != "2" || elem.GetAttributeNS("urn:two", "attr") != "3" {
+		t.Error("expected two different attributes")
+	}
+
+	replacement, _ := doc.CreateAttributeNS("urn:two", "dos:attr")
+	if err := elem.SetAttributeNodeNS(replacement); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if elem.GetAttributeNodeNS("urn:two", "attr") != replacement || elem.HasAttribute("two:attr") {
+		t.Error("expected the attribute to be replaced")
+	}
+
+	if err := elem.RemoveAttributeNS("urn:one", "attr"); err != nil || elem.HasAttributeNS("urn:one", "attr") {
+		t.Errorf("expected the attribute to be removed, got %v", err)
+	}
+	if attr.GetOwnerElement() != nil {
+		t.Error("expected the removed attribute to have no owner element")
+	}
+
+	var tests = []struct {
+		namespaceURI, qualifiedName string
+		expected                    error
+	}{
+		{"urn:x", "x:", ErrorNamespace},
+		{"urn:x", "x:y:z", ErrorNamespace},
+		{"", "x:y", ErrorNamespace},
+		{"urn:x", "xml:y", ErrorNamespace},
+		{XMLNamespaceURI, "xml:lang", nil},
+		{"urn:x", "xmlns", ErrorNamespace},
+		{XMLNSNamespaceURI, "xmlns:x", nil},
+		{XMLNSNamespaceURI, "x:y", ErrorNamespace},
+		{"urn:x", "1x", ErrorInvalidCharacter},
+	}
+	for _, test := range tests {
+		err := elem.SetAttributeNS(test.namespaceURI, test.qualifiedName, "value")
+		if (test.expected == nil && err != nil) || (test.expected != nil && (err == nil || !strings.HasPrefix(err.Error(), test.expected.Error()))) {
+			t.Errorf("%v, %v: expected %v, got %v", test.namespaceURI, test.qualifiedName, test.expected, err)
+		}
+	}
+}
+
+func TestElementRemoveAttributeDefault(t *testing.T) {
+	doc := mustParse(t, `<!DOCTYPE e [<!ATTLIST e a CDATA "default">]><e a="specified"/>`)
+	elem := doc.GetDocumentElement()
+	if err := elem.RemoveAttribute("a"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	attr := elem.GetAttributeNode("a")
+	if attr == nil || attr.GetValue() != "default" || attr.IsSpecified() {
+		t.Errorf("expected the default value to reappear, got %v", attr)
+	}
+}
@@ -0,0 +1,241 @@
+package dom
+
+import (
+	"fmt"
+)
+
+// domEntity is an entity declared in the DTD. It is read-only: the children are the
+// parsed replacement text, which are set by the Parser. Entities never have a parent.
+type domEntity struct {
+	nodes         []Node
+	ownerDocument Document
+	state         nodeState // The state the DOM keeps for this node.
+
+	// Entity specific things:
+	name         string
+	value        string // The literal replacement text of an internal entity.
+	internal     bool   // True if the entity is an internal entity (it has a value).
+	publicID     string
+	systemID     string
+	notationName string
+}
+
+func newEntity(owner Document, name string) *domEntity {
+	e := &domEntity{}
+	e.ownerDocument = owner
+	e.name = name
+	return e
+}
+
+func (de *domEntity) GetNodeName() string {
+	return de.name
+}
+
+func (de *domEntity) GetNodeType() NodeType {
+	return EntityNode
+}
+
+// GetNodeValue returns an empty string, since Entity nodes have no value.
+func (de *domEntity) GetNodeValue() string {
+	return ""
+}
+
+func (de *domEntity) GetLocalName() string {
+	return ""
+}
+
+func (de *domEntity) GetChildNodes() []Node {
+	return de.nodes
+}
+
+// GetParentNode returns nil, since entities are not part of the tree.
+func (de *domEntity) GetParentNode() Node {
+	return nil
+}
+
+func (de *domEntity) GetFirstChild() Node {
+	if de.HasChildNodes() {
+		return de.nodes[0]
+	}
+	return nil
+}
+
+func (de *domEntity) GetLastChild() Node {
+	if de.HasChildNodes() {
+		return de.nodes[len(de.nodes)-1]
+	}
+	return nil
+}
+
+func (de *domEntity) GetAttributes() NamedNodeMap {
+	return nil
+}
+
+func (de *domEntity) HasAttributes() bool {
+	return false
+}
+
+func (de *domEntity) GetOwnerDocument() Document {
+	return de.ownerDocument
+}
+
+// AppendChild returns an error, since entities are read-only.
+func (de *domEntity) AppendChild(child Node) error {
+	return ErrorNoModificationAllowed
+}
+
+func (de *domEntity) RemoveChild(oldChild Node) (Node, error) {
+	return nil, ErrorNoModificationAllowed
+}
+
+func (de *domEntity) ReplaceChild(newChild, oldChild Node) (Node, error) {
+	return nil, ErrorNoModificationAllowed
+}
+
+func (de *domEntity) InsertBefore(newChild, refChild Node) (Node, error) {
+	return nil, ErrorNoModificationAllowed
+}
+
+func (de *domEntity) HasChildNodes() bool {
+	return len(de.nodes) > 0
+}
+
+// GetPreviousSibling always returns nil for Entity nodes.
+func (de *domEntity) GetPreviousSibling() Node {
+	return nil
+}
+
+// GetNextSibling always returns nil for Entity nodes.
+func (de *domEntity) GetNextSibling() Node {
+	return nil
+}
+
+func (de *domEntity) GetNamespaceURI() string {
+	return ""
+}
+
+func (de *domEntity) GetNamespacePrefix() string {
+	return ""
+}
+
+func (de *domEntity) LookupPrefix(namespace string) (string, bool) {
+	return "", false
+}
+
+func (de *domEntity) LookupNamespaceURI(pfx string) (string, bool) {
+	return "", false
+}
+
+func (de *domEntity) IsDefaultNamespace(namespace string) bool {
+	return false
+}
+
+// GetTextContent returns the text content of the replacement text of the entity.
+func (de *domEntity) GetTextContent() string {
+	return getChildTextContent(de)
+}
+
+// SetTextContent does nothing, since entities are read-only.
+func (de *domEntity) SetTextContent(content string) {
+	// no-op.
+}
+
+// CloneNode creates a copy of the entity declaration. Since the children of an entity
+// are its replacement text, they are always copied, regardless of deep.
+func (de *domEntity) CloneNode(deep bool) Node {
+	return de.cloneNode(de.ownerDocument, deep, NodeCloned)
+}
+
+func (de *domEntity) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	clone := newEntity(owner, de.name)
+	clone.value = de.value
+	clone.internal = de.internal
+	clone.publicID = de.publicID
+	clone.systemID = de.systemID
+	clone.notationName = de.notationName
+	clone.setChildren(cloneChildrenInto(owner, de, operation))
+	notifyUserDataHandlers(operation, de, clone)
+	return clone
+}
+
+func (de *domEntity) Normalize() {
+	normalize(de)
+}
+
+func (de *domEntity) IsSameNode(other Node) bool {
+	return isSameNode(de, other)
+}
+
+func (de *domEntity) IsEqualNode(other Node) bool {
+	return isEqualNode(de, other)
+}
+
+func (de *domEntity) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(de, other)
+}
+
+func (de *domEntity) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(de, key, data, handler)
+}
+
+func (de *domEntity) GetUserData(key string) interface{} {
+	return getUserData(de, key)
+}
+
+func (de *domEntity) ImportNode(n Node, deep bool) Node {
+	return importNode(de.ownerDocument, n, deep)
+}
+
+func (de *domEntity) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(de, eventType, listener, useCapture)
+}
+
+func (de *domEntity) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(de, eventType, listener, useCapture)
+}
+
+func (de *domEntity) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(de, evt)
+}
+
+// Private functions:
+func (de *domEntity) setParentNode(parent Node) {
+	// no-op
+}
+
+func (de *domEntity) setOwnerDocument(doc Document) {
+	de.ownerDocument = doc
+}
+
+func (de *domEntity) getState() *nodeState {
+	return &de.state
+}
+
+// setChildren sets the (parsed) replacement text of the entity.
+func (de *domEntity) setChildren(nodes []Node) {
+	de.nodes = nodes
+	for _, child := range nodes {
+		child.setParentNode(de)
+	}
+}
+
+// Entity specifics:
+
+func (de *domEntity) GetPublicID() string {
+	return de.publicID
+}
+
+func (de *domEntity) GetSystemID() string {
+	return de.systemID
+}
+
+func (de *domEntity) GetNotationName() string {
+	return de.notationName
+}
+
+func (de *domEntity) String() string {
+	if de.internal {
+		return fmt.Sprintf("%s, %s='%s'", de.GetNodeType(), de.name, de.value)
+	}
+	return fmt.Sprintf("%s, %s, public=%s, system=%s", de.GetNodeType(), de.name, de.publicID, de.systemID)
+}
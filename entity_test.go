@@ -0,0 +1,173 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+var exampleDocEntities = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE root [
+  <!ENTITY name "World">
+  <!ENTITY greeting "Hello, &name;!">
+  <!ENTITY markup "<b>bold</b> text">
+  <!ENTITY chars "&#x41;&#66;">
+  <!ENTITY name "Ignored">
+  <!ENTITY % param "ignored">
+  <!ENTITY ext SYSTEM "external.xml">
+  <!ENTITY pic SYSTEM "pic.png" NDATA png>
+]>
+<root attr="&greeting;">&greeting; &markup; &chars;</root>`
+
+func TestEntityDeclarations(t *testing.T) {
+	doc := mustParse(t, exampleDocEntities)
+
+	entities := doc.GetDoctype().GetEntities()
+	if entities.Length() != 6 {
+		t.Errorf("expected 6 entities, got %d", entities.Length())
+	}
+	if entities.GetNamedItem("param") != nil {
+		t.Error("parameter entities should not be part of the entities")
+	}
+
+	name := entities.GetNamedItem("name").(Entity)
+	if name.GetTextContent() != "World" {
+		t.Errorf("expected the first declaration to be binding, got '%v'", name.GetTextContent())
+	}
+	if entities.GetNamedItem("chars").GetTextContent() != "AB" {
+		t.Errorf("expected 'AB', got '%v'", entities.GetNamedItem("chars").GetTextContent())
+	}
+
+	ext := entities.GetNamedItem("ext").(Entity)
+	if ext.GetSystemID() != "external.xml" || ext.HasChildNodes() {
+		t.Errorf("unexpected external entity '%v'", ext)
+	}
+	pic := entities.GetNamedItem("pic").(Entity)
+	if pic.GetNotationName() != "png" {
+		t.Errorf("expected notation 'png', got '%v'", pic.GetNotationName())
+	}
+
+	// Entities are read-only.
+	if err := name.AppendChild(doc.CreateText("nope")); err != ErrorNoModificationAllowed {
+		t.Errorf("expected %v, got %v", ErrorNoModificationAllowed, err)
+	}
+	if name.GetParentNode() != nil {
+		t.Error("entities should not have a parent")
+	}
+}
+
+func TestEntityReferences(t *testing.T) {
+	doc := mustParse(t, exampleDocEntities)
+
+	root := doc.GetDocumentElement()
+	if root.GetAttribute("attr") != "Hello, World!" {
+		t.Errorf("expected expanded attribute value, got '%v'", root.GetAttribute("attr"))
+	}
+	if root.GetTextContent() != "Hello, World! bold text AB" {
+		t.Errorf("unexpected text content '%v'", root.GetTextContent())
+	}
+
+	// &greeting; " " &markup; " " &chars;
+	children := root.GetChildNodes()
+	if len(children) != 5 {
+		t.Errorf("expected 5 children, got %d", len(children))
+		t.FailNow()
+	}
+	ref := children[0]
+	if ref.GetNodeType() != EntityReferenceNode || ref.GetNodeName() != "greeting" {
+		t.Errorf("expected an entity reference to 'greeting', got '%v'", ref)
+	}
+	// The nested reference is kept inside the expansion.
+	nested := ref.GetChildNodes()[1]
+	if nested.GetNodeType() != EntityReferenceNode || nested.GetParentNode() != ref {
+		t.Errorf("expected a nested entity reference, got '%v'", nested)
+	}
+	if _, ok := children[2].GetFirstChild().(Element); !ok {
+		t.Errorf("expected an element in the expansion of 'markup'")
+	}
+	if err := ref.AppendChild(doc.CreateText("nope")); err != ErrorNoModificationAllowed {
+		t.Errorf("expected %v, got %v", ErrorNoModificationAllowed, err)
+	}
+
+	// The expansion is a copy, not the children of the entity itself.
+	entity := doc.GetDoctype().GetEntities().GetNamedItem("greeting")
+	if ref.GetFirstChild() == entity.GetFirstChild() {
+		t.Error("expected a copy of the children of the entity")
+	}
+}
+
+func TestEntityReferencesExpanded(t *testing.T) {
+	parser := NewParser(strings.NewReader(exampleDocEntities))
+	parser.Configuration.Entities = false
+	doc, err := parser.Parse()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+
+	// "Hello, World! " <b> " text AB"
+	children := doc.GetDocumentElement().GetChildNodes()
+	if len(children) != 3 {
+		t.Errorf("expected 3 children, got %d: %v", len(children), children)
+		t.FailNow()
+	}
+	if children[0].GetNodeValue() != "Hello, World! " {
+		t.Errorf("expected merged text, got '%v'", children[0].GetNodeValue())
+	}
+	if children[1].GetNodeName() != "b" {
+		t.Errorf("expected element 'b', got '%v'", children[1])
+	}
+	if children[2].GetNodeValue() != " text AB" {
+		t.Errorf("expected merged text, got '%v'", children[2].GetNodeValue())
+	}
+}
+
+func TestEntityReferencesErrors(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+	}{
+		{"recursion", `<!DOCTYPE r [<!ENTITY a "&b;"><!ENTITY b "&a;">]><r>&a;</r>`},
+		{"undeclared", `<!DOCTYPE r [<!ENTITY a "a">]><r>&b;</r>`},
+		{"unparsed", `<!DOCTYPE r [<!ENTITY a SYSTEM "a.png" NDATA png>]><r>&a;</r>`},
+		{"external in attribute", `<!DOCTYPE r [<!ENTITY a SYSTEM "a.xml">]><r a="&a;"/>`},
+		{"malformed", `<!DOCTYPE r [<!ENTITY a "unterminated>]><r/>`},
+	}
+
+	for _, test := range tests {
+		if _, err := NewParser(strings.NewReader(test.input)).Parse(); err == nil {
+			t.Errorf("%v: expected error, got none", test.name)
+		}
+	}
+}
+
+func TestEntityReferenceCreate(t *testing.T) {
+	doc := mustParse(t, exampleDocEntities)
+
+	ref, err := doc.CreateEntityReference("greeting")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if ref.GetTextContent() != "Hello, World!" {
+		t.Errorf("expected the expansion of the entity, got '%v'", ref.GetTextContent())
+	}
+	if _, err := doc.CreateEntityReference("in valid"); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	// Unknown entities are allowed, but have no expansion.
+	ref, _ = doc.CreateEntityReference("unknown")
+	if ref.HasChildNodes() {
+		t.Error("expected no children")
+	}
+
+	clone := ref.CloneNode(false)
+	if clone.GetNodeType() != EntityReferenceNode || clone.GetNodeName() != "unknown" {
+		t.Errorf("incorrect clone '%v'", clone)
+	}
+
+	other := NewDocument()
+	imported := other.ImportNode(doc.GetDocumentElement(), true)
+	if imported.GetFirstChild().GetOwnerDocument() != other {
+		t.Error("expected the entity reference to be owned by the other document")
+	}
+}
@@ -0,0 +1,221 @@
+package dom
+
+import (
+	"fmt"
+)
+
+// domEntityReference is a reference to an entity, like &name;. Its children are a
+// read-only copy of the children of the referenced Entity.
+type domEntityReference struct {
+	nodes         []Node
+	parentNode    Node
+	ownerDocument Document
+	state         nodeState // The state the DOM keeps for this node.
+
+	// EntityReference specific things:
+	name string
+}
+
+func newEntityReference(owner Document, name string) *domEntityReference {
+	er := &domEntityReference{}
+	er.ownerDocument = owner
+	er.name = name
+	return er
+}
+
+func (er *domEntityReference) GetNodeName() string {
+	return er.name
+}
+
+func (er *domEntityReference) GetNodeType() NodeType {
+	return EntityReferenceNode
+}
+
+// GetNodeValue returns an empty string, since EntityReference nodes have no value.
+func (er *domEntityReference) GetNodeValue() string {
+	return ""
+}
+
+func (er *domEntityReference) GetLocalName() string {
+	return ""
+}
+
+func (er *domEntityReference) GetChildNodes() []Node {
+	return er.nodes
+}
+
+func (er *domEntityReference) GetParentNode() Node {
+	return er.parentNode
+}
+
+func (er *domEntityReference) GetFirstChild() Node {
+	if er.HasChildNodes() {
+		return er.nodes[0]
+	}
+	return nil
+}
+
+func (er *domEntityReference) GetLastChild() Node {
+	if er.HasChildNodes() {
+		return er.nodes[len(er.nodes)-1]
+	}
+	return nil
+}
+
+func (er *domEntityReference) GetAttributes() NamedNodeMap {
+	return nil
+}
+
+func (er *domEntityReference) HasAttributes() bool {
+	return false
+}
+
+func (er *domEntityReference) GetOwnerDocument() Document {
+	return er.ownerDocument
+}
+
+// AppendChild returns an error, since the children of an entity reference are read-only.
+func (er *domEntityReference) AppendChild(child Node) error {
+	return ErrorNoModificationAllowed
+}
+
+func (er *domEntityReference) RemoveChild(oldChild Node) (Node, error) {
+	return nil, ErrorNoModificationAllowed
+}
+
+func (er *domEntityReference) ReplaceChild(newChild, oldChild Node) (Node, error) {
+	return nil, ErrorNoModificationAllowed
+}
+
+func (er *domEntityReference) InsertBefore(newChild, refChild Node) (Node, error) {
+	return nil, ErrorNoModificationAllowed
+}
+
+func (er *domEntityReference) HasChildNodes() bool {
+	return len(er.nodes) > 0
+}
+
+func (er *domEntityReference) GetPreviousSibling() Node {
+	return getPreviousSibling(er)
+}
+
+func (er *domEntityReference) GetNextSibling() Node {
+	return getNextSibling(er)
+}
+
+func (er *domEntityReference) GetNamespaceURI() string {
+	return ""
+}
+
+func (er *domEntityReference) GetNamespacePrefix() string {
+	return ""
+}
+
+func (er *domEntityReference) LookupPrefix(namespace string) (string, bool) {
+	if er.GetParentNode() != nil {
+		return er.GetParentNode().LookupPrefix(namespace)
+	}
+	return "", false
+}
+
+func (er *domEntityReference) LookupNamespaceURI(pfx string) (string, bool) {
+	if er.GetParentNode() != nil {
+		return er.GetParentNode().LookupNamespaceURI(pfx)
+	}
+	return "", false
+}
+
+func (er *domEntityReference) IsDefaultNamespace(namespace string) bool {
+	if er.GetParentNode() != nil {
+		return er.GetParentNode().IsDefaultNamespace(namespace)
+	}
+	return false
+}
+
+// GetTextContent returns the text content of the expansion of the entity.
+func (er *domEntityReference) GetTextContent() string {
+	return getChildTextContent(er)
+}
+
+// SetTextContent does nothing, since the children of an entity reference are read-only.
+func (er *domEntityReference) SetTextContent(content string) {
+	// no-op.
+}
+
+// CloneNode creates a copy of the entity reference. Since the children are the
+// expansion of the entity, they are always copied, regardless of deep.
+func (er *domEntityReference) CloneNode(deep bool) Node {
+	return er.cloneNode(er.ownerDocument, deep, NodeCloned)
+}
+
+func (er *domEntityReference) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	clone := newEntityReference(owner, er.name)
+	clone.setChildren(cloneChildrenInto(owner, er, operation))
+	notifyUserDataHandlers(operation, er, clone)
+	return clone
+}
+
+func (er *domEntityReference) Normalize() {
+	normalize(er)
+}
+
+func (er *domEntityReference) IsSameNode(other Node) bool {
+	return isSameNode(er, other)
+}
+
+func (er *domEntityReference) IsEqualNode(other Node) bool {
+	return isEqualNode(er, other)
+}
+
+func (er *domEntityReference) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(er, other)
+}
+
+func (er *domEntityReference) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(er, key, data, handler)
+}
+
+func (er *domEntityReference) GetUserData(key string) interface{} {
+	return getUserData(er, key)
+}
+
+func (er *domEntityReference) ImportNode(n Node, deep bool) Node {
+	return importNode(er.ownerDocument, n, deep)
+}
+
+func (er *domEntityReference) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(er, eventType, listener, useCapture)
+}
+
+func (er *domEntityReference) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(er, eventType, listener, useCapture)
+}
+
+func (er *domEntityReference) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(er, evt)
+}
+
+// Private functions:
+func (er *domEntityReference) setParentNode(parent Node) {
+	er.parentNode = parent
+}
+
+func (er *domEntityReference) setOwnerDocument(doc Document) {
+	er.ownerDocument = doc
+}
+
+func (er *domEntityReference) getState() *nodeState {
+	return &er.state
+}
+
+// setChildren sets the expansion of the entity as the children of this reference.
+func (er *domEntityReference) setChildren(nodes []Node) {
+	er.nodes = nodes
+	for _, child := range nodes {
+		child.setParentNode(er)
+	}
+}
+
+func (er *domEntityReference) String() string {
+	return fmt.Sprintf("%s: &%s;", er.GetNodeType(), er.name)
+}
@@ -0,0 +1,324 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// This file contains the DOM Level 3 Events dispatch model. Every Node is an EventTarget,
+// and events are dispatched along the ancestors of the target, as returned by GetParentNode.
+// See https://www.w3.org/TR/DOM-Level-3-Events/#event-flow
+
+// ErrorUnspecifiedEventType is returned when an Event is dispatched without a type, which
+// happens when the Event is not initialized.
+var ErrorUnspecifiedEventType = errors.New("UNSPECIFIED_EVENT_TYPE_ERR: the type of the Event was not specified by initializing the event")
+
+// ErrorDispatchRequest is returned when an Event is dispatched while it is being dispatched.
+var ErrorDispatchRequest = errors.New("DISPATCH_REQUEST_ERR: the Event is already being dispatched")
+
+// EventPhase is the phase of the event flow which is being processed.
+type EventPhase uint8
+
+// Enumeration of the phases of the event flow.
+const (
+	// EventPhaseNone is the phase of an Event which is not being dispatched.
+	EventPhaseNone EventPhase = iota
+	// CapturingPhase propagates the Event from the root down to the parent of the target.
+	CapturingPhase
+	// AtTarget is the phase in which the Event is at the target.
+	AtTarget
+	// BubblingPhase propagates the Event from the parent of the target up to the root.
+	BubblingPhase
+)
+
+// String returns the string representation of the EventPhase, using the default
+// representation by the W3 specification.
+func (p EventPhase) String() string {
+	switch p {
+	case EventPhaseNone:
+		return "NONE"
+	case CapturingPhase:
+		return "CAPTURING_PHASE"
+	case AtTarget:
+		return "AT_TARGET"
+	case BubblingPhase:
+		return "BUBBLING_PHASE"
+	default:
+		return "???"
+	}
+}
+
+// EventTarget is implemented by every Node. Listeners registered at a Node are called when
+// an Event is dispatched to the Node, or to one of its descendants.
+type EventTarget interface {
+	// AddEventListener registers the listener for events of the given type. With useCapture,
+	// the listener is called in the capturing phase, instead of the bubbling phase. Both kinds
+	// of listeners are called when the Node is the target. Registering the same listener twice
+	// has no effect. The listener must be comparable, see EventListenerFunc.
+	AddEventListener(eventType string, listener EventListener, useCapture bool)
+	// RemoveEventListener removes a listener registered by AddEventListener.
+	RemoveEventListener(eventType string, listener EventListener, useCapture bool)
+	// DispatchEvent dispatches the Event with this Node as the target. It returns false if a
+	// listener called PreventDefault on a cancelable Event.
+	DispatchEvent(evt Event) (bool, error)
+}
+
+// EventListener handles the events it is registered for.
+type EventListener interface {
+	HandleEvent(evt Event)
+}
+
+// EventListenerFunc is an adapter to use ordinary functions as an EventListener. Functions
+// can not be compared, so a pointer to the EventListenerFunc is the EventListener:
+//
+//	listener := EventListenerFunc(func(evt Event) { ... })
+//	node.AddEventListener("change", &listener, false)
+//	node.RemoveEventListener("change", &listener, false)
+type EventListenerFunc func(evt Event)
+
+// HandleEvent calls (*f)(evt).
+func (f *EventListenerFunc) HandleEvent(evt Event) {
+	(*f)(evt)
+}
+
+// Event holds the information of an event, and controls its flow. Events are created by
+// Document.CreateEvent, and must be initialized before they are dispatched. Own event
+// types with a payload can embed an Event, or use a CustomEvent.
+type Event interface {
+	GetType() string           // Returns the type of the event, like "change".
+	GetTarget() Node           // Returns the Node the event was dispatched to.
+	GetCurrentTarget() Node    // Returns the Node whose listeners are being called.
+	GetEventPhase() EventPhase // Returns the current phase of the event flow.
+	GetBubbles() bool          // Returns true if the event goes through the bubbling phase.
+	GetCancelable() bool       // Returns true if the default action can be prevented.
+	GetTimeStamp() time.Time   // Returns the time the event was created.
+	GetDefaultPrevented() bool // Returns true if PreventDefault was called on a cancelable event.
+	StopPropagation()          // Stops the event flow after the listeners of the current target.
+	StopImmediatePropagation() // Stops the event flow right away, skipping the remaining listeners.
+	PreventDefault()           // Signals that the default action should not be taken, if cancelable.
+
+	// InitEvent initializes the type and flags of the event. It has no effect while the
+	// event is being dispatched.
+	InitEvent(eventType string, bubbles, cancelable bool)
+
+	event() *domEvent // Returns the underlying event, which holds the state of the dispatch.
+}
+
+// CustomEvent is an Event with an application-specific payload.
+type CustomEvent interface {
+	Event
+	GetDetail() interface{} // Returns the payload.
+
+	// InitCustomEvent initializes the event like InitEvent, and sets the payload.
+	InitCustomEvent(eventType string, bubbles, cancelable bool, detail interface{})
+}
+
+// domEvent implements the Event.
+type domEvent struct {
+	eventType     string
+	target        Node
+	currentTarget Node
+	phase         EventPhase
+	bubbles       bool
+	cancelable    bool
+	timeStamp     time.Time
+
+	dispatching      bool
+	defaultPrevented bool
+	stopped          bool // StopPropagation was called.
+	stoppedNow       bool // StopImmediatePropagation was called.
+}
+
+func newEvent() *domEvent {
+	return &domEvent{timeStamp: time.Now()}
+}
+
+func (e *domEvent) GetType() string {
+	return e.eventType
+}
+
+func (e *domEvent) GetTarget() Node {
+	return e.target
+}
+
+func (e *domEvent) GetCurrentTarget() Node {
+	return e.currentTarget
+}
+
+func (e *domEvent) GetEventPhase() EventPhase {
+	return e.phase
+}
+
+func (e *domEvent) GetBubbles() bool {
+	return e.bubbles
+}
+
+func (e *domEvent) GetCancelable() bool {
+	return e.cancelable
+}
+
+func (e *domEvent) GetTimeStamp() time.Time {
+	return e.timeStamp
+}
+
+func (e *domEvent) GetDefaultPrevented() bool {
+	return e.defaultPrevented
+}
+
+func (e *domEvent) StopPropagation() {
+	e.stopped = true
+}
+
+func (e *domEvent) StopImmediatePropagation() {
+	e.stopped = true
+	e.stoppedNow = true
+}
+
+func (e *domEvent) PreventDefault() {
+	if e.cancelable {
+		e.defaultPrevented = true
+	}
+}
+
+func (e *domEvent) InitEvent(eventType string, bubbles, cancelable bool) {
+	if e.dispatching {
+		return
+	}
+	e.eventType = eventType
+	e.bubbles = bubbles
+	e.cancelable = cancelable
+	e.target = nil
+	e.stopped = false
+	e.stoppedNow = false
+	e.defaultPrevented = false
+}
+
+func (e *domEvent) event() *domEvent {
+	return e
+}
+
+func (e *domEvent) String() string {
+	return fmt.Sprintf("Event: %v (%v)", e.eventType, e.phase)
+}
+
+// domCustomEvent implements the CustomEvent.
+type domCustomEvent struct {
+	*domEvent
+	detail interface{}
+}
+
+func (e *domCustomEvent) GetDetail() interface{} {
+	return e.detail
+}
+
+func (e *domCustomEvent) InitCustomEvent(eventType string, bubbles, cancelable bool, detail interface{}) {
+	if e.dispatching {
+		return
+	}
+	e.InitEvent(eventType, bubbles, cancelable)
+	e.detail = detail
+}
+
+// eventListenerEntry is a listener registered at a Node.
+type eventListenerEntry struct {
+	eventType  string
+	listener   EventListener
+	useCapture bool
+	removed    bool // Set when the listener is removed during a dispatch.
+}
+
+// addEventListener implements EventTarget.AddEventListener for the Node n. The listeners
+// are kept by the Node itself.
+func addEventListener(n Node, eventType string, listener EventListener, useCapture bool) {
+	if listener == nil {
+		return
+	}
+	state := n.getState()
+	for _, entry := range state.listeners {
+		if entry.eventType == eventType && entry.listener == listener && entry.useCapture == useCapture {
+			return
+		}
+	}
+	entry := &eventListenerEntry{eventType: eventType, listener: listener, useCapture: useCapture}
+	state.listeners = append(state.listeners, entry)
+}
+
+// removeEventListener implements EventTarget.RemoveEventListener for the Node n.
+func removeEventListener(n Node, eventType string, listener EventListener, useCapture bool) {
+	state := n.getState()
+	for i, entry := range state.listeners {
+		if entry.eventType == eventType && entry.listener == listener && entry.useCapture == useCapture {
+			entry.removed = true
+			// Make a new slice, since a dispatch may be iterating over the old one.
+			state.listeners = append(append([]*eventListenerEntry(nil), state.listeners[:i]...), state.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatchEvent implements EventTarget.DispatchEvent for the target Node. The propagation
+// path is determined before the event is dispatched, so changes to the tree made by the
+// listeners do not affect it.
+func dispatchEvent(target Node, evt Event) (bool, error) {
+	if evt == nil {
+		return false, fmt.Errorf("%v: the event can not be nil", ErrorNotSupported)
+	}
+	e := evt.event()
+	if e.dispatching {
+		return false, ErrorDispatchRequest
+	}
+	if e.eventType == "" {
+		return false, ErrorUnspecifiedEventType
+	}
+
+	e.dispatching = true
+	e.target = target
+	e.stopped = false
+	e.stoppedNow = false
+	defer func() {
+		e.dispatching = false
+		e.phase = EventPhaseNone
+		e.currentTarget = nil
+	}()
+
+	var ancestors []Node
+	for n := target.GetParentNode(); n != nil; n = n.GetParentNode() {
+		ancestors = append(ancestors, n)
+	}
+
+	e.phase = CapturingPhase
+	for i := len(ancestors) - 1; i >= 0 && !e.stopped; i-- {
+		invokeListeners(ancestors[i], evt)
+	}
+	if !e.stopped {
+		e.phase = AtTarget
+		invokeListeners(target, evt)
+	}
+	if e.bubbles {
+		e.phase = BubblingPhase
+		for i := 0; i < len(ancestors) && !e.stopped; i++ {
+			invokeListeners(ancestors[i], evt)
+		}
+	}
+	return !e.defaultPrevented, nil
+}
+
+// invokeListeners calls the listeners of the Node n which match the type and phase of
+// the event. Listeners added while doing so are not called for this event.
+func invokeListeners(n Node, evt Event) {
+	e := evt.event()
+	e.currentTarget = n
+	for _, entry := range n.getState().listeners {
+		if e.stoppedNow {
+			return
+		}
+		if entry.removed || entry.eventType != e.eventType {
+			continue
+		}
+		if (e.phase == CapturingPhase && !entry.useCapture) || (e.phase == BubblingPhase && entry.useCapture) {
+			continue
+		}
+		entry.listener.HandleEvent(evt)
+	}
+}
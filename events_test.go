@@ -0,0 +1,204 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var exampleDocEvents = `<root><parent><child/></parent></root>`
+
+// newTestEvent creates an initialized event.
+func newTestEvent(t *testing.T, doc Document, eventType string, bubbles, cancelable bool) Event {
+	evt, err := doc.CreateEvent("Event")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	evt.InitEvent(eventType, bubbles, cancelable)
+	return evt
+}
+
+func TestEventFlow(t *testing.T) {
+	doc := mustParse(t, exampleDocEvents)
+	parent := doc.GetElementsByTagName("parent")[0]
+	child := doc.GetElementsByTagName("child")[0]
+
+	var log []string
+	// record registers a listener which logs the current target and phase.
+	record := func(n Node, useCapture bool) {
+		listener := EventListenerFunc(func(evt Event) {
+			log = append(log, fmt.Sprintf("%v:%v", evt.GetCurrentTarget().GetNodeName(), evt.GetEventPhase()))
+			if evt.GetTarget() != child {
+				t.Errorf("unexpected target %v", evt.GetTarget())
+			}
+		})
+		n.AddEventListener("change", &listener, useCapture)
+	}
+	for _, n := range []Node{doc, doc.GetDocumentElement(), parent, child} {
+		record(n, true)
+		record(n, false)
+	}
+
+	var tests = []struct {
+		bubbles  bool
+		expected string
+	}{
+		{true, "#document:CAPTURING_PHASE root:CAPTURING_PHASE parent:CAPTURING_PHASE child:AT_TARGET child:AT_TARGET parent:BUBBLING_PHASE root:BUBBLING_PHASE #document:BUBBLING_PHASE"},
+		{false, "#document:CAPTURING_PHASE root:CAPTURING_PHASE parent:CAPTURING_PHASE child:AT_TARGET child:AT_TARGET"},
+	}
+	for _, test := range tests {
+		log = nil
+		evt := newTestEvent(t, doc, "change", test.bubbles, false)
+		if ok, err := child.DispatchEvent(evt); !ok || err != nil {
+			t.Errorf("unexpected result %v, %v", ok, err)
+		}
+		if actual := strings.Join(log, " "); actual != test.expected {
+			t.Errorf("expected '%v', got '%v'", test.expected, actual)
+		}
+		if evt.GetEventPhase() != EventPhaseNone || evt.GetCurrentTarget() != nil {
+			t.Error("expected no phase and current target after the dispatch")
+		}
+	}
+
+	// Listeners for other types are not called.
+	log = nil
+	child.DispatchEvent(newTestEvent(t, doc, "other", true, false))
+	if len(log) != 0 {
+		t.Errorf("expected no listeners to be called, got %v", log)
+	}
+}
+
+func TestEventStopPropagation(t *testing.T) {
+	doc := mustParse(t, exampleDocEvents)
+	parent := doc.GetElementsByTagName("parent")[0]
+	child := doc.GetElementsByTagName("child")[0]
+
+	var log []string
+	stop := EventListenerFunc(func(evt Event) {
+		log = append(log, "stop")
+		evt.StopPropagation()
+	})
+	stopNow := EventListenerFunc(func(evt Event) {
+		log = append(log, "stopNow")
+		evt.StopImmediatePropagation()
+	})
+	logger := EventListenerFunc(func(evt Event) {
+		log = append(log, evt.GetCurrentTarget().GetNodeName())
+	})
+
+	// StopPropagation still calls the other listeners of the current target.
+	parent.AddEventListener("change", &stop, false)
+	parent.AddEventListener("change", &logger, false)
+	doc.GetDocumentElement().AddEventListener("change", &logger, false)
+	child.DispatchEvent(newTestEvent(t, doc, "change", true, false))
+	if actual := strings.Join(log, " "); actual != "stop parent" {
+		t.Errorf("unexpected listeners called: '%v'", actual)
+	}
+
+	// StopImmediatePropagation does not.
+	log = nil
+	parent.RemoveEventListener("change", &stop, false)
+	parent.RemoveEventListener("change", &logger, false)
+	parent.AddEventListener("change", &stopNow, true)
+	parent.AddEventListener("change", &logger, true)
+	child.AddEventListener("change", &logger, false)
+	child.DispatchEvent(newTestEvent(t, doc, "change", true, false))
+	if actual := strings.Join(log, " "); actual != "stopNow" {
+		t.Errorf("unexpected listeners called: '%v'", actual)
+	}
+
+	// Adding a listener twice has no effect, removing it works for the right phase only.
+	log = nil
+	parent.RemoveEventListener("change", &stopNow, true)
+	parent.RemoveEventListener("change", &logger, true)
+	child.AddEventListener("change", &logger, false)
+	child.RemoveEventListener("change", &logger, true)
+	child.DispatchEvent(newTestEvent(t, doc, "change", false, false))
+	if actual := strings.Join(log, " "); actual != "child" {
+		t.Errorf("unexpected listeners called: '%v'", actual)
+	}
+}
+
+func TestEventPreventDefault(t *testing.T) {
+	doc := mustParse(t, exampleDocEvents)
+	parent := doc.GetElementsByTagName("parent")[0]
+	child := doc.GetElementsByTagName("child")[0]
+	prevent := EventListenerFunc(func(evt Event) {
+		evt.PreventDefault()
+	})
+	parent.AddEventListener("submit", &prevent, false)
+
+	if ok, _ := child.DispatchEvent(newTestEvent(t, doc, "submit", true, true)); ok {
+		t.Error("expected the cancelable event to be prevented")
+	}
+	evt := newTestEvent(t, doc, "submit", true, false)
+	if ok, _ := child.DispatchEvent(evt); !ok || evt.GetDefaultPrevented() {
+		t.Error("expected an event which is not cancelable not to be prevented")
+	}
+}
+
+func TestCustomEvent(t *testing.T) {
+	doc := mustParse(t, exampleDocEvents)
+	parent := doc.GetElementsByTagName("parent")[0]
+	child := doc.GetElementsByTagName("child")[0]
+
+	var detail interface{}
+	listener := EventListenerFunc(func(evt Event) {
+		if custom, ok := evt.(CustomEvent); ok {
+			detail = custom.GetDetail()
+		}
+	})
+	parent.AddEventListener("edit", &listener, false)
+
+	evt, err := doc.CreateEvent("CustomEvent")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	evt.(CustomEvent).InitCustomEvent("edit", true, false, map[string]int{"line": 3})
+	child.DispatchEvent(evt)
+	if m, ok := detail.(map[string]int); !ok || m["line"] != 3 {
+		t.Errorf("unexpected detail %v", detail)
+	}
+
+	// Own event types can embed an Event.
+	type editEvent struct {
+		Event
+		line int
+	}
+	listener = EventListenerFunc(func(evt Event) {
+		detail = evt.(*editEvent).line
+	})
+	base, _ := doc.CreateEvent("Event")
+	base.InitEvent("edit", true, false)
+	child.DispatchEvent(&editEvent{base, 42})
+	if detail != 42 {
+		t.Errorf("unexpected detail %v", detail)
+	}
+
+	if _, err := doc.CreateEvent("MouseEvent"); err == nil {
+		t.Error("expected an error for an unknown event interface")
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	doc := mustParse(t, exampleDocEvents)
+	child := doc.GetElementsByTagName("child")[0]
+
+	evt, _ := doc.CreateEvent("Event")
+	if _, err := child.DispatchEvent(evt); err != ErrorUnspecifiedEventType {
+		t.Errorf("expected %v, got %v", ErrorUnspecifiedEventType, err)
+	}
+
+	evt.InitEvent("change", true, false)
+	var nested error
+	listener := EventListenerFunc(func(e Event) {
+		_, nested = doc.DispatchEvent(e)
+	})
+	child.AddEventListener("change", &listener, false)
+	child.DispatchEvent(evt)
+	if nested != ErrorDispatchRequest {
+		t.Errorf("expected %v, got %v", ErrorDispatchRequest, nested)
+	}
+}
@@ -0,0 +1,134 @@
+package dom
+
+// This file contains the index of the elements by the value of their ID attributes, used by
+// Document.GetElementById. Attributes are of type ID when they are declared as such in the
+// DTD, when they are named xml:id, or when they are marked using Element.SetIdAttribute.
+// See https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#ID-getElBId
+
+// isXMLID returns true if the attribute is an xml:id attribute, which is always of type ID.
+// See https://www.w3.org/TR/xml-id/
+func isXMLID(attr Attr) bool {
+	if attr.GetNamespaceURI() == XMLNamespaceURI {
+		return attr.GetLocalName() == "id"
+	}
+	return attr.GetNodeName() == "xml:id"
+}
+
+// elementByID returns the first Element in document order with an ID attribute of the given
+// value, or nil. The index is built on the first lookup, and kept up to date from then on.
+func (dd *domDocument) elementByID(id string) Element {
+	if dd.ids == nil {
+		dd.ids = make(map[string][]Element)
+		dd.indexIDs(dd)
+	}
+	// More than one Element only has the same ID in invalid documents.
+	var first Element
+	for _, elem := range dd.ids[id] {
+		if first == nil || compareTreeOrder(elem, first) < 0 {
+			first = elem
+		}
+	}
+	return first
+}
+
+// indexIDs adds the ID attributes of the Node n and its descendants to the index, if n is
+// part of this Document. It is called right after n is inserted.
+func (dd *domDocument) indexIDs(n Node) {
+	if dd.ids == nil || treeRoot(n) != dd {
+		return
+	}
+	walkIDs(n, func(attr Node) {
+		dd.ids[attr.GetNodeValue()] = append(dd.ids[attr.GetNodeValue()], attr.(Attr).GetOwnerElement())
+	})
+}
+
+// unindexIDs removes the ID attributes of the Node n and its descendants from the index, if
+// n is part of this Document. It is called right before n is removed.
+func (dd *domDocument) unindexIDs(n Node) {
+	if dd.ids == nil || treeRoot(n) != dd {
+		return
+	}
+	walkIDs(n, func(attr Node) {
+		elems := dd.ids[attr.GetNodeValue()]
+		for i, elem := range elems {
+			if elem == attr.(Attr).GetOwnerElement() {
+				elems = append(elems[:i:i], elems[i+1:]...)
+				break
+			}
+		}
+		if len(elems) == 0 {
+			delete(dd.ids, attr.GetNodeValue())
+		} else {
+			dd.ids[attr.GetNodeValue()] = elems
+		}
+	})
+}
+
+// walkIDs calls fn for every attribute of type ID of the Node n and its descendants. If n
+// is an attribute itself, fn is only called for n.
+func walkIDs(n Node, fn func(attr Node)) {
+	if a, ok := n.(Attr); ok && n.GetNodeType() == AttributeNode {
+		if a.IsId() && a.GetOwnerElement() != nil {
+			fn(n)
+		}
+		return
+	}
+	if n.GetNodeType() == ElementNode {
+		for _, attr := range attributeList(n) {
+			walkIDs(attr, fn)
+		}
+	}
+	for _, child := range n.GetChildNodes() {
+		walkIDs(child, fn)
+	}
+}
+
+// typeIDs marks the attributes of the Node n and its descendants as being of type ID, when
+// the DTD of this Document declares them as such. If n is an attribute, only n is marked.
+// It is called when attributes are added to an element, and when nodes are inserted into or
+// adopted by this Document, so the DTD applies to nodes which were not parsed as well.
+func (dd *domDocument) typeIDs(n Node) {
+	doctype, ok := dd.GetDoctype().(*domDocumentType)
+	if !ok || len(doctype.attributes) == 0 {
+		return
+	}
+	if a, ok := n.(Attr); ok && n.GetNodeType() == AttributeNode {
+		doctype.applyIDType(a)
+		return
+	}
+	if n.GetNodeType() == ElementNode {
+		for _, attr := range attributeList(n) {
+			doctype.applyIDType(attr.(Attr))
+		}
+	}
+	for _, child := range n.GetChildNodes() {
+		dd.typeIDs(child)
+	}
+}
+
+// attributeChanging removes the attribute from the index of IDs, and attributeChanged adds
+// it again. Every change of an attribute of an Element which may affect the index, like its
+// value or its type, is surrounded by calls to both.
+func attributeChanging(attr Node) {
+	if doc := liveDocument(attr); doc != nil {
+		doc.unindexIDs(attr)
+	}
+}
+
+func attributeChanged(attr Node) {
+	if doc := liveDocument(attr); doc != nil {
+		doc.indexIDs(attr)
+	}
+}
+
+// setIDAttribute implements Element.SetIdAttributeNode for the Element elem.
+func setIDAttribute(elem Element, attr Attr, isID bool) error {
+	if isReadOnly(elem) {
+		return ErrorNoModificationAllowed
+	}
+	if attr == nil || attr.GetOwnerElement() != elem {
+		return ErrorNotFound
+	}
+	attr.setID(isID)
+	return nil
+}
@@ -0,0 +1,221 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DOMImplementation creates Documents and DocumentTypes independent of any particular
+// Document, and reports the features it supports. The implementation of this package is
+// returned by Document.GetImplementation, and can be looked up using GetDOMImplementation.
+// See https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#ID-102161490
+type DOMImplementation interface {
+	// HasFeature returns true if the feature is supported in the given version. An empty
+	// version matches any version of the feature. Feature names are case-insensitive.
+	HasFeature(feature, version string) bool
+	// GetFeature returns an object which implements the feature in the given version, or
+	// nil if the feature is not supported.
+	GetFeature(feature, version string) interface{}
+	// CreateDocument creates a Document with the given DocumentType, which may be nil, and
+	// a document element with the namespace URI and qualified name. When the qualified name
+	// is empty, the Document has no document element. Nothing is changed when an error is
+	// returned.
+	CreateDocument(namespaceURI, qualifiedName string, doctype DocumentType) (Document, error)
+	// CreateDocumentType creates an empty DocumentType, which is not owned by any Document
+	// until it is passed to CreateDocument.
+	CreateDocumentType(qualifiedName, publicID, systemID string) (DocumentType, error)
+}
+
+// DOMImplementationSource provides the DOMImplementations which support the features in the
+// features string. The string is a space separated list of feature names, each optionally
+// followed by a version, like "XML 3.0 Traversal +Events 2.0".
+// See https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#DOMImplementationSource
+type DOMImplementationSource interface {
+	// GetDOMImplementation returns the first DOMImplementation which supports the features,
+	// or nil if there is none.
+	GetDOMImplementation(features string) DOMImplementation
+	// GetDOMImplementationList returns all the DOMImplementations which support the features.
+	GetDOMImplementationList(features string) []DOMImplementation
+}
+
+var (
+	registryMutex sync.Mutex
+	// sources are the registered DOMImplementationSources, in the order they were registered.
+	sources = []DOMImplementationSource{domImplementationSource{}}
+)
+
+// RegisterDOMImplementationSource adds the source to the registry, so the implementations
+// it provides are found by GetDOMImplementation and GetDOMImplementationList. The sources
+// are asked in the order they were registered, starting with the one of this package.
+func RegisterDOMImplementationSource(source DOMImplementationSource) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	sources = append(sources, source)
+}
+
+// GetDOMImplementation returns the first DOMImplementation of the registered sources which
+// supports the features, or nil if there is none. An empty features string returns the
+// implementation of this package.
+func GetDOMImplementation(features string) DOMImplementation {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	for _, source := range sources {
+		if impl := source.GetDOMImplementation(features); impl != nil {
+			return impl
+		}
+	}
+	return nil
+}
+
+// GetDOMImplementationList returns the DOMImplementations of all the registered sources
+// which support the features.
+func GetDOMImplementationList(features string) []DOMImplementation {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	var list []DOMImplementation
+	for _, source := range sources {
+		list = append(list, source.GetDOMImplementationList(features)...)
+	}
+	return list
+}
+
+// featureVersion is a single feature of a features string, with its version, if any.
+type featureVersion struct {
+	feature string
+	version string
+}
+
+// parseFeatures splits the features string in its features. A token starting with a digit
+// is the version of the feature before it.
+func parseFeatures(features string) []featureVersion {
+	var parsed []featureVersion
+	for _, token := range strings.Fields(features) {
+		if token[0] >= '0' && token[0] <= '9' && len(parsed) > 0 && parsed[len(parsed)-1].version == "" {
+			parsed[len(parsed)-1].version = token
+			continue
+		}
+		parsed = append(parsed, featureVersion{feature: token})
+	}
+	return parsed
+}
+
+// hasFeatures returns true if the DOMImplementation supports all features of the string.
+func hasFeatures(impl DOMImplementation, features string) bool {
+	for _, fv := range parseFeatures(features) {
+		if !impl.HasFeature(fv.feature, fv.version) {
+			return false
+		}
+	}
+	return true
+}
+
+// domImplementationSource is the DOMImplementationSource of this package, which is always
+// registered first.
+type domImplementationSource struct{}
+
+func (domImplementationSource) GetDOMImplementation(features string) DOMImplementation {
+	if hasFeatures(implementation, features) {
+		return implementation
+	}
+	return nil
+}
+
+func (domImplementationSource) GetDOMImplementationList(features string) []DOMImplementation {
+	if hasFeatures(implementation, features) {
+		return []DOMImplementation{implementation}
+	}
+	return nil
+}
+
+// supportedFeatures are the features of this package, by lower case name, with the
+// supported versions.
+var supportedFeatures = map[string][]string{
+	"core":      {"1.0", "2.0", "3.0"},
+	"xml":       {"1.0", "2.0", "3.0"},
+	"traversal": {"2.0"},
+	"range":     {"2.0"},
+	"events":    {"2.0"},
+	"xpath":     {"3.0"},
+}
+
+// domImplementation is the DOMImplementation of this package.
+type domImplementation struct{}
+
+// implementation is the DOMImplementation shared by all Documents of this package.
+var implementation = &domImplementation{}
+
+// HasFeature returns true for the features Core and XML in the versions 1.0, 2.0 and 3.0,
+// Traversal, Range and Events in version 2.0, and XPath in version 3.0. A leading '+' of
+// the feature name is ignored.
+func (di *domImplementation) HasFeature(feature, version string) bool {
+	versions, ok := supportedFeatures[strings.ToLower(strings.TrimPrefix(feature, "+"))]
+	if !ok {
+		return false
+	}
+	return version == "" || containsString(versions, version)
+}
+
+// GetFeature returns the DOMImplementation itself if it has the feature, since it implements
+// all its features directly.
+func (di *domImplementation) GetFeature(feature, version string) interface{} {
+	if di.HasFeature(feature, version) {
+		return di
+	}
+	return nil
+}
+
+// CreateDocument creates a Document, with the DocumentType and a document element.
+//
+// Errors:
+//
+// INVALID_CHARACTER_ERR: Raised if the qualified name is not an XML name.
+//
+// NAMESPACE_ERR: Raised if the qualified name is malformed, does not fit the namespace URI,
+// or if it is empty while the namespace URI is not.
+//
+// WRONG_DOCUMENT_ERR: Raised if the DocumentType is already used by another Document, or was
+// created by a different implementation.
+func (di *domImplementation) CreateDocument(namespaceURI, qualifiedName string, doctype DocumentType) (Document, error) {
+	if qualifiedName == "" && namespaceURI != "" {
+		return nil, fmt.Errorf("%v: a document element with namespace URI '%v' needs a name", ErrorNamespace, namespaceURI)
+	}
+	if qualifiedName != "" {
+		if err := checkQualifiedName(namespaceURI, qualifiedName); err != nil {
+			return nil, err
+		}
+	}
+	dt, ok := doctype.(*domDocumentType)
+	if doctype != nil && (!ok || dt.GetOwnerDocument() != nil) {
+		return nil, ErrorWrongDocument
+	}
+
+	doc := &domDocument{}
+	if dt != nil {
+		dt.setOwnerDocument(doc)
+		if err := doc.AppendChild(dt); err != nil {
+			return nil, err
+		}
+	}
+	if qualifiedName != "" {
+		elem := newElement(doc, qualifiedName, namespaceURI)
+		if err := doc.AppendChild(elem); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// CreateDocumentType creates a DocumentType without an internal subset.
+//
+// Errors:
+//
+// INVALID_CHARACTER_ERR: Raised if the qualified name is not an XML name.
+//
+// NAMESPACE_ERR: Raised if the qualified name is malformed.
+func (di *domImplementation) CreateDocumentType(qualifiedName, publicID, systemID string) (DocumentType, error) {
+	if err := checkQName(qualifiedName); err != nil {
+		return nil, err
+	}
+	return newDocumentType(nil, qualifiedName, publicID, systemID), nil
+}
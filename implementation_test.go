@@ -0,0 +1,134 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+// compactImplementation is a DOMImplementation which only supports its own feature.
+type compactImplementation struct {
+	domImplementation
+}
+
+func (ci *compactImplementation) HasFeature(feature, version string) bool {
+	return strings.EqualFold(feature, "Compact")
+}
+
+type compactSource struct {
+	impl *compactImplementation
+}
+
+func (cs compactSource) GetDOMImplementation(features string) DOMImplementation {
+	if hasFeatures(cs.impl, features) {
+		return cs.impl
+	}
+	return nil
+}
+
+func (cs compactSource) GetDOMImplementationList(features string) []DOMImplementation {
+	if impl := cs.GetDOMImplementation(features); impl != nil {
+		return []DOMImplementation{impl}
+	}
+	return nil
+}
+
+func TestDOMImplementationFeatures(t *testing.T) {
+	impl := NewDocument().GetImplementation()
+	tests := []struct {
+		feature string
+		version string
+		has     bool
+	}{
+		{"Core", "", true},
+		{"core", "3.0", true},
+		{"XML", "1.0", true},
+		{"+Traversal", "2.0", true},
+		{"XPath", "3.0", true},
+		{"Range", "3.0", false},
+		{"LS", "", false},
+	}
+	for _, test := range tests {
+		if has := impl.HasFeature(test.feature, test.version); has != test.has {
+			t.Errorf("feature %v %v: expected %v, got %v", test.feature, test.version, test.has, has)
+		}
+		if feature := impl.GetFeature(test.feature, test.version); (feature != nil) != test.has {
+			t.Errorf("feature %v %v: unexpected object %v", test.feature, test.version, feature)
+		}
+	}
+
+	if GetDOMImplementation("XML 3.0 Traversal +Events 2.0") != impl {
+		t.Error("expected the implementation of the package")
+	}
+	if GetDOMImplementation("XML 3.0 Compact") != nil {
+		t.Error("expected no implementation for an unknown feature")
+	}
+
+	defer func(registered []DOMImplementationSource) { sources = registered }(sources)
+	compact := &compactImplementation{}
+	RegisterDOMImplementationSource(compactSource{compact})
+	if GetDOMImplementation("Compact") != compact {
+		t.Error("expected the registered implementation")
+	}
+	if list := GetDOMImplementationList(""); len(list) != 2 || list[0] != impl || list[1] != compact {
+		t.Errorf("unexpected implementations %v", list)
+	}
+}
+
+func TestDOMImplementationCreateDocument(t *testing.T) {
+	impl := GetDOMImplementation("Core")
+	doctype, err := impl.CreateDocumentType("x:root", "-//Test//EN", "root.dtd")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	if doctype.GetOwnerDocument() != nil {
+		t.Error("expected the DocumentType not to be owned yet")
+	}
+	doc, err := impl.CreateDocument("urn:x", "x:root", doctype)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	root := doc.GetDocumentElement()
+	if doc.GetDoctype() != doctype || doctype.GetOwnerDocument() != doc {
+		t.Error("expected the DocumentType to be owned by the Document")
+	}
+	if root == nil || root.GetNodeName() != "x:root" || root.GetNamespaceURI() != "urn:x" || root.GetOwnerDocument() != doc {
+		t.Errorf("unexpected document element %v", root)
+	}
+	if xml := serializeToString(doc); !strings.Contains(xml, "\n<!DOCTYPE x:root PUBLIC \"-//Test//EN\" \"root.dtd\">\n") {
+		t.Errorf("unexpected serialization %v", xml)
+	}
+
+	empty, err := impl.CreateDocument("", "", nil)
+	if err != nil || empty.GetDocumentElement() != nil || len(empty.GetChildNodes()) != 0 {
+		t.Errorf("expected an empty document, got %v, %v", empty, err)
+	}
+
+	unused, _ := impl.CreateDocumentType("r", "", "")
+	tests := []struct {
+		ns      string
+		qname   string
+		doctype DocumentType
+		err     error
+	}{
+		{"", "in valid", unused, ErrorInvalidCharacter},
+		{"", "x:root", unused, ErrorNamespace},
+		{"urn:x", "", unused, ErrorNamespace},
+		{"", "root", doctype, ErrorWrongDocument},
+	}
+	for _, test := range tests {
+		if _, err := impl.CreateDocument(test.ns, test.qname, test.doctype); err == nil || !strings.HasPrefix(err.Error(), test.err.Error()) {
+			t.Errorf("creating %v: expected %v, got %v", test.qname, test.err, err)
+		}
+	}
+	if unused.GetOwnerDocument() != nil {
+		t.Error("expected a failed creation to leave the DocumentType alone")
+	}
+
+	for qname, expected := range map[string]error{"a b": ErrorInvalidCharacter, "a:b:c": ErrorNamespace, ":a": ErrorNamespace} {
+		if _, err := impl.CreateDocumentType(qname, "", ""); err == nil || !strings.HasPrefix(err.Error(), expected.Error()) {
+			t.Errorf("creating doctype %v: expected %v, got %v", qname, expected, err)
+		}
+	}
+}
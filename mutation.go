@@ -0,0 +1,281 @@
+package dom
+
+import (
+	"fmt"
+)
+
+// This file contains mutation observers, which get notified of changes to the tree. They
+// follow https://dom.spec.whatwg.org/#mutation-observers, except for the delivery of the
+// records: Go has no event loop, so the records are queued until the Document is asked
+// to notify the observers, using NotifyMutationObservers.
+
+// MutationType is the kind of change described by a MutationRecord.
+type MutationType uint8
+
+// Enumeration of the kinds of changes.
+const (
+	MutationChildList     MutationType = iota // Children were added or removed.
+	MutationAttributes                        // An attribute was set, changed or removed.
+	MutationCharacterData                     // The data of a Text, Comment or ProcessingInstruction changed.
+)
+
+// String returns the string representation of the MutationType, using the default
+// representation by the specification.
+func (t MutationType) String() string {
+	switch t {
+	case MutationChildList:
+		return "childList"
+	case MutationAttributes:
+		return "attributes"
+	case MutationCharacterData:
+		return "characterData"
+	default:
+		return "???"
+	}
+}
+
+// MutationRecord describes a single change to the tree.
+type MutationRecord struct {
+	Type               MutationType
+	Target             Node   // The parent of the children, the element of the attribute, or the character data node.
+	AddedNodes         []Node // The children which were added.
+	RemovedNodes       []Node // The children which were removed.
+	PreviousSibling    Node   // The previous sibling of the added or removed children.
+	NextSibling        Node   // The next sibling of the added or removed children.
+	AttributeName      string // The local name of the changed attribute.
+	AttributeNamespace string // The namespace URI of the changed attribute.
+	OldValue           string // The value before the change, if requested. Empty if there was none.
+}
+
+// MutationObserverInit holds the options of MutationObserver.Observe. At least one of
+// ChildList, Attributes or CharacterData must be set. The options which only make sense
+// for attributes or character data imply these.
+type MutationObserverInit struct {
+	ChildList             bool     // Observe the addition and removal of children.
+	Attributes            bool     // Observe changes to attributes.
+	CharacterData         bool     // Observe changes to character data.
+	Subtree               bool     // Observe the descendants of the target as well.
+	AttributeOldValue     bool     // Record the old value of attributes.
+	CharacterDataOldValue bool     // Record the old character data.
+	AttributeFilter       []string // Only observe the attributes with these local names, without a namespace.
+}
+
+// MutationCallback is called with a batch of records, in the order of the changes.
+type MutationCallback func(records []*MutationRecord, observer MutationObserver)
+
+// MutationObserver observes nodes for changes. The changes are recorded, and passed in
+// batches to the callback when Document.NotifyMutationObservers is called.
+type MutationObserver interface {
+	// Observe starts observing the target Node, or changes the options if it is observed
+	// already by this observer.
+	Observe(target Node, options MutationObserverInit) error
+	// Disconnect stops observing all nodes, and discards the records which are not delivered.
+	Disconnect()
+	// TakeRecords returns the records which are not delivered, and removes them from the queue.
+	TakeRecords() []*MutationRecord
+}
+
+// NewMutationObserver creates a MutationObserver, which delivers its records to the callback.
+func NewMutationObserver(callback MutationCallback) MutationObserver {
+	return &domMutationObserver{callback: callback}
+}
+
+// domMutationObserver implements the MutationObserver.
+type domMutationObserver struct {
+	callback MutationCallback
+	nodes    []Node // The nodes this observer is registered at, including transient registrations.
+	records  []*MutationRecord
+}
+
+// mutationRegistration registers a MutationObserver at a Node.
+type mutationRegistration struct {
+	observer *domMutationObserver
+	options  MutationObserverInit
+	// For a transient registration, the registration it was copied from. A transient
+	// registration keeps observing a node which was removed from an observed subtree,
+	// until the records are delivered.
+	source *mutationRegistration
+}
+
+func (o *domMutationObserver) Observe(target Node, options MutationObserverInit) error {
+	if target == nil {
+		return fmt.Errorf("%v: the target can not be nil", ErrorNotSupported)
+	}
+	if options.AttributeOldValue || len(options.AttributeFilter) > 0 {
+		options.Attributes = true
+	}
+	if options.CharacterDataOldValue {
+		options.CharacterData = true
+	}
+	if !options.ChildList && !options.Attributes && !options.CharacterData {
+		return fmt.Errorf("%v: one of ChildList, Attributes or CharacterData must be set", ErrorNotSupported)
+	}
+	if liveDocument(target) == nil {
+		return fmt.Errorf("%v: the target has no owner document", ErrorNotSupported)
+	}
+
+	state := target.getState()
+	for _, reg := range state.registrations {
+		if reg.observer == o && reg.source == nil {
+			for _, n := range o.nodes {
+				o.removeRegistrations(n, func(r *mutationRegistration) bool { return r.source == reg })
+			}
+			reg.options = options
+			return nil
+		}
+	}
+
+	state.registrations = append(state.registrations, &mutationRegistration{observer: o, options: options})
+	o.nodes = append(o.nodes, target)
+	return nil
+}
+
+func (o *domMutationObserver) Disconnect() {
+	for _, n := range o.nodes {
+		o.removeRegistrations(n, func(r *mutationRegistration) bool { return true })
+	}
+	o.nodes = nil
+	o.records = nil
+}
+
+func (o *domMutationObserver) TakeRecords() []*MutationRecord {
+	records := o.records
+	o.records = nil
+	return records
+}
+
+// removeRegistrations removes the registrations of this observer at the Node n which
+// match the given function.
+func (o *domMutationObserver) removeRegistrations(n Node, match func(r *mutationRegistration) bool) {
+	state := n.getState()
+	var kept []*mutationRegistration
+	for _, reg := range state.registrations {
+		if reg.observer != o || !match(reg) {
+			kept = append(kept, reg)
+		}
+	}
+	state.registrations = kept
+}
+
+// NotifyMutationObservers delivers the queued records to the callbacks of the observers.
+// Records which are queued by the callbacks are delivered as well, before returning.
+func (dd *domDocument) NotifyMutationObservers() {
+	for len(dd.pendingObservers) > 0 {
+		observers := dd.pendingObservers
+		dd.pendingObservers = nil
+		for _, o := range observers {
+			records := o.TakeRecords()
+			var nodes []Node
+			for _, n := range o.nodes {
+				o.removeRegistrations(n, func(r *mutationRegistration) bool { return r.source != nil })
+				if len(n.getState().registrations) > 0 {
+					nodes = append(nodes, n)
+				}
+			}
+			o.nodes = nodes
+			if len(records) > 0 && o.callback != nil {
+				o.callback(records, o)
+			}
+		}
+	}
+}
+
+// queueMutationRecord queues the record for every observer which is interested in it,
+// because it is registered at the target, or at an ancestor of the target for a subtree.
+// The old value of the record is only kept for observers which asked for it.
+func queueMutationRecord(record *MutationRecord) {
+	doc := liveDocument(record.Target)
+	if doc == nil {
+		return
+	}
+
+	// The interested observers, in order, with a flag whether they want the old value.
+	var observers []*domMutationObserver
+	oldValue := make(map[*domMutationObserver]bool)
+	for n := record.Target; n != nil; n = n.GetParentNode() {
+		for _, reg := range n.getState().registrations {
+			options := reg.options
+			switch {
+			case n != record.Target && !options.Subtree:
+				continue
+			case record.Type == MutationAttributes && !options.Attributes:
+				continue
+			case record.Type == MutationAttributes && len(options.AttributeFilter) > 0 &&
+				(record.AttributeNamespace != "" || !containsString(options.AttributeFilter, record.AttributeName)):
+				continue
+			case record.Type == MutationCharacterData && !options.CharacterData:
+				continue
+			case record.Type == MutationChildList && !options.ChildList:
+				continue
+			}
+			if _, ok := oldValue[reg.observer]; !ok {
+				observers = append(observers, reg.observer)
+				oldValue[reg.observer] = false
+			}
+			if (record.Type == MutationAttributes && options.AttributeOldValue) ||
+				(record.Type == MutationCharacterData && options.CharacterDataOldValue) {
+				oldValue[reg.observer] = true
+			}
+		}
+	}
+
+	for _, o := range observers {
+		r := *record
+		if !oldValue[o] {
+			r.OldValue = ""
+		}
+		if len(o.records) == 0 {
+			doc.pendingObservers = append(doc.pendingObservers, o)
+		}
+		o.records = append(o.records, &r)
+	}
+}
+
+// queueChildListMutation queues a record for the addition or removal of children of the
+// target. The siblings are the ones of the children, while they are in the target.
+func queueChildListMutation(target Node, added, removed []Node, previousSibling, nextSibling Node) {
+	queueMutationRecord(&MutationRecord{
+		Type:            MutationChildList,
+		Target:          target,
+		AddedNodes:      added,
+		RemovedNodes:    removed,
+		PreviousSibling: previousSibling,
+		NextSibling:     nextSibling,
+	})
+}
+
+// queueAttributeMutation queues a record for a change to the attribute of the Element.
+func queueAttributeMutation(elem Element, attr Node, oldValue string) {
+	queueMutationRecord(&MutationRecord{
+		Type:               MutationAttributes,
+		Target:             elem,
+		AttributeName:      attr.GetLocalName(),
+		AttributeNamespace: attr.GetNamespaceURI(),
+		OldValue:           oldValue,
+	})
+}
+
+// addTransientObservers registers the observers of the subtrees the Node n is about to be
+// removed from at n itself, so changes to n are observed until the records are delivered.
+func (dd *domDocument) addTransientObservers(n Node) {
+	state := n.getState()
+	for ancestor := n.GetParentNode(); ancestor != nil; ancestor = ancestor.GetParentNode() {
+		for _, reg := range ancestor.getState().registrations {
+			if reg.options.Subtree {
+				transient := &mutationRegistration{observer: reg.observer, options: reg.options, source: reg}
+				state.registrations = append(state.registrations, transient)
+				reg.observer.nodes = append(reg.observer.nodes, n)
+			}
+		}
+	}
+}
+
+// containsString returns true if the slice contains the string s.
+func containsString(slice []string, s string) bool {
+	for _, str := range slice {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var exampleDocMutation = `<root a="1"><child>text</child><!--comment--><?pi data?></root>`
+
+// describeRecords returns a short description of each record, separated by spaces.
+func describeRecords(records []*MutationRecord) string {
+	var s []string
+	for _, r := range records {
+		desc := fmt.Sprintf("%v:%v", r.Type, r.Target.GetNodeName())
+		if len(r.AddedNodes) > 0 {
+			desc += "+" + names(r.AddedNodes)
+		}
+		if len(r.RemovedNodes) > 0 {
+			desc += "-" + names(r.RemovedNodes)
+		}
+		if r.AttributeName != "" {
+			desc += "@" + r.AttributeName
+		}
+		if r.OldValue != "" {
+			desc += "=" + r.OldValue
+		}
+		s = append(s, desc)
+	}
+	return strings.Join(s, " ")
+}
+
+func TestMutationObserverBatches(t *testing.T) {
+	doc := mustParse(t, exampleDocMutation)
+	root := doc.GetDocumentElement()
+
+	var batches []string
+	observer := NewMutationObserver(func(records []*MutationRecord, observer MutationObserver) {
+		batches = append(batches, describeRecords(records))
+	})
+	err := observer.Observe(root, MutationObserverInit{ChildList: true, Attributes: true, CharacterData: true, Subtree: true})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	x := mustCreateElement(doc, "x")
+	root.AppendChild(x)
+	root.SetAttribute("a", "2")
+	root.GetFirstChild().GetFirstChild().(Text).SetText("changed")
+	if len(batches) != 0 {
+		t.Errorf("expected no delivery before notifying, got %v", batches)
+	}
+
+	doc.NotifyMutationObservers()
+	expected := "childList:root+x attributes:root@a characterData:#text"
+	if len(batches) != 1 || batches[0] != expected {
+		t.Errorf("expected one batch '%v', got %v", expected, batches)
+	}
+
+	// Nothing is delivered when there are no changes.
+	doc.NotifyMutationObservers()
+	if len(batches) != 1 {
+		t.Errorf("expected one batch, got %v", batches)
+	}
+
+	root.RemoveChild(x)
+	root.GetChildNodes()[1].(Comment).SetComment("other")
+	root.GetChildNodes()[2].(ProcessingInstruction).SetData("other")
+	root.GetAttributes().RemoveNamedItem("a")
+	doc.NotifyMutationObservers()
+	expected = "childList:root-x characterData:#comment characterData:pi attributes:root@a"
+	if len(batches) != 2 || batches[1] != expected {
+		t.Errorf("expected a second batch '%v', got %v", expected, batches)
+	}
+
+	observer.Disconnect()
+	root.AppendChild(x)
+	doc.NotifyMutationObservers()
+	if len(batches) != 2 {
+		t.Errorf("expected no delivery after disconnecting, got %v", batches)
+	}
+}
+
+func TestMutationObserverOptions(t *testing.T) {
+	doc := mustParse(t, exampleDocMutation)
+	root := doc.GetDocumentElement()
+	child := root.GetFirstChild().(Element)
+	text := child.GetFirstChild().(Text)
+
+	var tests = []struct {
+		target   Node
+		options  MutationObserverInit
+		expected string
+	}{
+		{root, MutationObserverInit{ChildList: true}, "childList:root+y"},
+		{root, MutationObserverInit{ChildList: true, Subtree: true}, "childList:root+y childList:child+z"},
+		{root, MutationObserverInit{Attributes: true, Subtree: true}, "attributes:root@a attributes:child@b attributes:child@b"},
+		{root, MutationObserverInit{AttributeOldValue: true, Subtree: true}, "attributes:root@a=1 attributes:child@b attributes:child@b=first"},
+		{root, MutationObserverInit{AttributeFilter: []string{"b"}, Subtree: true}, "attributes:child@b attributes:child@b"},
+		{child, MutationObserverInit{CharacterDataOldValue: true, Subtree: true}, "characterData:#text=text"},
+		{text, MutationObserverInit{CharacterData: true}, "characterData:#text"},
+		{child, MutationObserverInit{CharacterData: true}, ""},
+	}
+
+	for _, test := range tests {
+		observer := NewMutationObserver(nil)
+		if err := observer.Observe(test.target, test.options); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		root.SetAttribute("a", "2")
+		root.AppendChild(mustCreateElement(doc, "y"))
+		child.SetAttribute("b", "first")
+		child.GetAttributes().GetNamedItem("b").(Attr).SetValue("second")
+		child.AppendChild(mustCreateElement(doc, "z"))
+		text.SetText("new text")
+
+		if actual := describeRecords(observer.TakeRecords()); actual != test.expected {
+			t.Errorf("%+v: expected '%v', got '%v'", test.options, test.expected, actual)
+		}
+		observer.Disconnect()
+
+		// Restore the document for the next test.
+		root.SetAttribute("a", "1")
+		root.RemoveChild(root.GetLastChild())
+		child.GetAttributes().RemoveNamedItem("b")
+		child.RemoveChild(child.GetLastChild())
+		text.SetText("text")
+	}
+
+	observer := NewMutationObserver(nil)
+	if err := observer.Observe(root, MutationObserverInit{Subtree: true}); err == nil {
+		t.Error("expected an error without ChildList, Attributes or CharacterData")
+	}
+}
+
+func TestMutationObserverRemovedSubtree(t *testing.T) {
+	doc := mustParse(t, exampleDocMutation)
+	root := doc.GetDocumentElement()
+	child := root.GetFirstChild()
+
+	var records []*MutationRecord
+	observer := NewMutationObserver(func(r []*MutationRecord, observer MutationObserver) {
+		records = append(records, r...)
+	})
+	observer.Observe(root, MutationObserverInit{ChildList: true, CharacterData: true, Subtree: true})
+
+	// Changes to a removed node are observed until the records are delivered.
+	root.RemoveChild(child)
+	child.GetFirstChild().(Text).SetText("detached")
+	doc.NotifyMutationObservers()
+	child.GetFirstChild().(Text).SetText("unobserved")
+	doc.NotifyMutationObservers()
+
+	expected := "childList:root-child characterData:#text"
+	if actual := describeRecords(records); actual != expected {
+		t.Errorf("expected '%v', got '%v'", expected, actual)
+	}
+	if records[0].PreviousSibling != nil || records[0].NextSibling == nil || records[0].NextSibling.GetNodeType() != CommentNode {
+		t.Errorf("unexpected siblings %v and %v", records[0].PreviousSibling, records[0].NextSibling)
+	}
+}
+
+func TestMutationObserverReentrant(t *testing.T) {
+	doc := mustParse(t, exampleDocMutation)
+	root := doc.GetDocumentElement()
+
+	var batches []string
+	observer := NewMutationObserver(func(records []*MutationRecord, observer MutationObserver) {
+		batches = append(batches, describeRecords(records))
+		// Changes made by the callback are delivered in a next batch.
+		if len(batches) == 1 {
+			root.SetAttribute("c", "callback")
+		}
+	})
+	observer.Observe(root, MutationObserverInit{ChildList: true, Attributes: true})
+
+	root.AppendChild(mustCreateElement(doc, "x"))
+	doc.NotifyMutationObservers()
+	expected := "childList:root+x|attributes:root@c"
+	if actual := strings.Join(batches, "|"); actual != expected {
+		t.Errorf("expected '%v', got '%v'", expected, actual)
+	}
+}
+
+func TestMutationObserverReplaceChildWithItself(t *testing.T) {
+	doc := mustParse(t, exampleDocMutation)
+	root := doc.GetDocumentElement()
+	child := root.GetFirstChild()
+
+	observer := NewMutationObserver(nil)
+	observer.Observe(doc, MutationObserverInit{ChildList: true, Subtree: true})
+
+	if old, err := root.ReplaceChild(child, child); err != nil || old != child {
+		t.Errorf("expected '%v' without error, got '%v' (%v)", child, old, err)
+	}
+	if child.GetParentNode() != root || root.GetFirstChild() != child {
+		t.Error("child should not have moved")
+	}
+	if old, err := doc.ReplaceChild(root, root); err != nil || old != root {
+		t.Errorf("expected '%v' without error, got '%v' (%v)", root, old, err)
+	}
+	if doc.GetDocumentElement() != root {
+		t.Error("document element should not have changed")
+	}
+	if actual := describeRecords(observer.TakeRecords()); actual != "" {
+		t.Errorf("expected no records, got '%v'", actual)
+	}
+}
+
+func TestMutationObserverAdoptedNode(t *testing.T) {
+	doc := mustParse(t, exampleDocMutation)
+	child := doc.GetDocumentElement().GetFirstChild().(Element)
+
+	observer := NewMutationObserver(nil)
+	observer.Observe(child, MutationObserverInit{Attributes: true})
+
+	target := NewDocument()
+	if _, err := target.AdoptNode(child); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	child.SetAttribute("b", "2")
+	if actual := describeRecords(observer.TakeRecords()); actual != "attributes:child@b" {
+		t.Errorf("expected the observer to move along, got '%v'", actual)
+	}
+}
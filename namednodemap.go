@@ -2,62 +2,215 @@ package dom
 
 import "fmt"
 
+// domNamedNodeMap keeps its nodes in the order they were added, which is the document order
+// for parsed attributes. Replacing a node keeps the position of the old one. The nodes are
+// looked up by their node name, so renaming a node does not require re-keying the map.
 type domNamedNodeMap struct {
-	nodes map[string]Node
+	nodes        []Node
+	nodeType     NodeType // The type of nodes this map accepts.
+	ownerElement Element  // The Element of the attributes, or nil for other maps.
 }
 
+// newNamedNodeMap creates a NamedNodeMap for attributes.
 func newNamedNodeMap() NamedNodeMap {
+	return newNamedNodeMapOf(AttributeNode)
+}
+
+// newAttributeMap creates the NamedNodeMap for the attributes of the owner Element. Changes
+// to the map are reported to the MutationObservers of the Element, and the map maintains
+// the owner element of the attributes.
+func newAttributeMap(owner Element) NamedNodeMap {
+	nnm := newNamedNodeMapOf(AttributeNode).(*domNamedNodeMap)
+	nnm.ownerElement = owner
+	return nnm
+}
+
+// newNamedNodeMapOf creates a NamedNodeMap which only accepts nodes of the given type,
+// for example EntityNode for the entities of a DocumentType.
+func newNamedNodeMapOf(nodeType NodeType) NamedNodeMap {
 	nnm := &domNamedNodeMap{}
-	nnm.nodes = make(map[string]Node)
+	nnm.nodeType = nodeType
 	return nnm
 }
 
-// TODO: GetItems()... not according to spec though.
+// GetItems returns the nodes as a Go map, keyed by node name. The map is a copy, so changing
+// it does not change the NamedNodeMap. Use Item to visit the nodes in order.
 func (nnm *domNamedNodeMap) GetItems() map[string]Node {
-	return nnm.nodes
+	items := make(map[string]Node, len(nnm.nodes))
+	for _, n := range nnm.nodes {
+		items[n.GetNodeName()] = n
+	}
+	return items
 }
 
 func (nnm *domNamedNodeMap) GetNamedItem(name string) Node {
-	return nnm.nodes[name]
+	if i := nnm.indexOf(name); i >= 0 {
+		return nnm.nodes[i]
+	}
+	return nil
 }
 
-// TODO: Return proper errors. The current implementation is not entirely correct.
-// The spec mentions that a NamedNodeMap can accept Nodes other than Attr, except
-// when the 'parent' is an Element. We'll need specializations of the NamedNodeMap
-// interface, for ex. attrNamedNodeMap, entityNamedNodeMap.
+func (nnm *domNamedNodeMap) GetNamedItemNS(namespaceURI, localName string) Node {
+	if i := nnm.indexOfNS(namespaceURI, localName); i >= 0 {
+		return nnm.nodes[i]
+	}
+	return nil
+}
+
+// SetNamedItem adds the node using its node name. A node with the same name is replaced,
+// and keeps its position in the map.
 //
-// See: https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#ID-1780488922
+// See: https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#ID-1025163788
 //
 // Errors:
 //
-// NUSE_ATTRIBUTE_ERR: Raised if arg is an Attr that is already an attribute of
-// another Element object. The DOM user must explicitly clone Attr nodes to
-// re-use them in other elements.
+// HIERARCHY_REQUEST_ERR: Raised if the node does not belong in this NamedNodeMap, like
+// anything other than an Attr in the attributes of an Element, or anything other than
+// an Entity in the entities of a DocumentType.
+//
+// WRONG_DOCUMENT_ERR: Raised if an Attr was created from a different document than the
+// Element of the map.
 //
-// HIERARCHY_REQUEST_ERR: Raised if an attempt is made to add a node doesn't
-// belong in this NamedNodeMap. Examples would include trying to insert something
-// other than an Attr node into an Element's map of attributes, or a non-Entity
-// node into the DocumentType's map of Entities.
+// INUSE_ATTRIBUTE_ERR: Raised if the node is an Attr that is already an attribute of
+// another Element. The DOM user must explicitly clone Attr nodes to re-use them in other
+// elements.
 func (nnm *domNamedNodeMap) SetNamedItem(n Node) error {
-	if _, ok := n.(Attr); ok {
-		nnm.nodes[n.GetNodeName()] = n
-		return nil
+	if n == nil {
+		return fmt.Errorf("%v: the node can not be nil", ErrorNotFound)
+	}
+	return nnm.setItem(n, nnm.indexOf(n.GetNodeName()))
+}
+
+// SetNamedItemNS adds the node using its namespace URI and local name. A node with the same
+// namespace URI and local name is replaced, and keeps its position in the map. It returns
+// the same errors as SetNamedItem.
+func (nnm *domNamedNodeMap) SetNamedItemNS(n Node) error {
+	if n == nil {
+		return fmt.Errorf("%v: the node can not be nil", ErrorNotFound)
+	}
+	return nnm.setItem(n, nnm.indexOfNS(n.GetNamespaceURI(), n.GetLocalName()))
+}
+
+// setItem puts the node n at index i, replacing the node there, or adds it to the end when
+// i is negative.
+func (nnm *domNamedNodeMap) setItem(n Node, i int) error {
+	if n.GetNodeType() != nnm.nodeType {
+		return fmt.Errorf("%v: can not set a %v as a named item in a map of %v", ErrorHierarchyRequest, n.GetNodeType(), nnm.nodeType)
+	}
+
+	var attr Attr
+	if n.GetNodeType() == AttributeNode && nnm.ownerElement != nil {
+		attr = n.(Attr)
+		if attr.GetOwnerElement() == nnm.ownerElement && i >= 0 && nnm.nodes[i] == n {
+			// The attribute replaces itself.
+			return nil
+		}
+		if attr.GetOwnerElement() != nil && attr.GetOwnerElement() != nnm.ownerElement {
+			return ErrorAttrInUse
+		}
+		if attr.GetOwnerDocument() != nnm.ownerElement.GetOwnerDocument() {
+			return ErrorWrongDocument
+		}
+	}
+
+	var old Node
+	if i >= 0 {
+		old = nnm.nodes[i]
+	}
+	if nnm.ownerElement != nil {
+		var oldValue string
+		if old != nil {
+			oldValue = old.GetNodeValue()
+		}
+		queueAttributeMutation(nnm.ownerElement, n, oldValue)
+		if old != nil {
+			attributeChanging(old)
+		}
+	}
+
+	if i >= 0 {
+		nnm.nodes[i] = n
+	} else {
+		nnm.nodes = append(nnm.nodes, n)
+	}
+	if attr != nil {
+		if oldAttr, ok := old.(Attr); ok {
+			oldAttr.setOwnerElement(nil)
+		}
+		attr.setOwnerElement(nnm.ownerElement)
+		attributeChanged(attr)
+		if doc := liveDocument(attr); doc != nil {
+			doc.typeIDs(attr)
+		}
 	}
-	return fmt.Errorf("%v: can not set a non-Attr node as a named item", ErrorHierarchyRequest)
+	return nil
 }
 
-func (nnm *domNamedNodeMap) RemoveNamedItem(name string) {
-	delete(nnm.nodes, name)
+// RemoveNamedItem removes the node with the given name, and returns it. It returns an
+// ErrorNotFound if there is no such node.
+func (nnm *domNamedNodeMap) RemoveNamedItem(name string) (Node, error) {
+	return nnm.removeItem(nnm.indexOf(name))
+}
+
+// RemoveNamedItemNS removes the node with the namespace URI and local name, and returns it.
+// It returns an ErrorNotFound if there is no such node.
+func (nnm *domNamedNodeMap) RemoveNamedItemNS(namespaceURI, localName string) (Node, error) {
+	return nnm.removeItem(nnm.indexOfNS(namespaceURI, localName))
+}
+
+// removeItem removes the node at index i, if i is not negative.
+func (nnm *domNamedNodeMap) removeItem(i int) (Node, error) {
+	if i < 0 {
+		return nil, ErrorNotFound
+	}
+	old := nnm.nodes[i]
+	if nnm.ownerElement != nil {
+		queueAttributeMutation(nnm.ownerElement, old, old.GetNodeValue())
+		attributeChanging(old)
+		if attr, ok := old.(Attr); ok {
+			attr.setOwnerElement(nil)
+		}
+	}
+	nnm.nodes = append(nnm.nodes[:i:i], nnm.nodes[i+1:]...)
+	return old, nil
+}
+
+// Item returns the node at the given index, or nil if the index is out of range.
+func (nnm *domNamedNodeMap) Item(index int) Node {
+	if index < 0 || index >= len(nnm.nodes) {
+		return nil
+	}
+	return nnm.nodes[index]
 }
 
 func (nnm *domNamedNodeMap) Length() int {
 	return len(nnm.nodes)
 }
 
+// indexOf returns the index of the node with the given name, or -1.
+func (nnm *domNamedNodeMap) indexOf(name string) int {
+	for i, n := range nnm.nodes {
+		if n.GetNodeName() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexOfNS returns the index of the node with the namespace URI and local name, or -1.
+func (nnm *domNamedNodeMap) indexOfNS(namespaceURI, localName string) int {
+	for i, n := range nnm.nodes {
+		if n.GetNamespaceURI() == namespaceURI && n.GetLocalName() == localName {
+			return i
+		}
+	}
+	return -1
+}
+
 func (nnm *domNamedNodeMap) String() string {
 	s := ""
-	for k, v := range nnm.GetItems() {
-		s += fmt.Sprintf("%v=%v,", k, v.GetNodeValue())
+	for _, n := range nnm.nodes {
+		s += fmt.Sprintf("%v=%v,", n.GetNodeName(), n.GetNodeValue())
 	}
 	return s
 }
@@ -1,6 +1,9 @@
 package dom
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestNamedNodeMap(t *testing.T) {
 	doc := NewDocument()
@@ -68,3 +71,117 @@ func TestNamedNodeMap(t *testing.T) {
 		t.Error("expected to find key 'name', but got nothing")
 	}
 }
+
+func TestNamedNodeMapOrder(t *testing.T) {
+	doc := mustParse(t, `<root z="1" a="2" m="3"/>`)
+	root := doc.GetDocumentElement()
+	root.SetAttributeNS(XMLNSNamespaceURI, "xmlns:p", "urn:p")
+	root.SetAttributeNS("urn:p", "p:x", "4")
+	attrs := root.GetAttributes()
+
+	// names returns the node names of the attributes, in order.
+	names := func() string {
+		var s []string
+		for i := 0; i < attrs.Length(); i++ {
+			s = append(s, attrs.Item(i).GetNodeName())
+		}
+		return strings.Join(s, " ")
+	}
+	if actual := names(); actual != "z a m xmlns:p p:x" {
+		t.Errorf("expected the attributes in document order, got '%v'", actual)
+	}
+	if attrs.Item(-1) != nil || attrs.Item(attrs.Length()) != nil {
+		t.Error("expected nil for an index out of range")
+	}
+
+	// Replacing an attribute keeps its position, new ones are added to the end.
+	replacement, _ := doc.CreateAttribute("a")
+	replacement.SetValue("replaced")
+	if err := attrs.SetNamedItem(replacement); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	root.SetAttribute("b", "5")
+	if actual := names(); actual != "z a m xmlns:p p:x b" {
+		t.Errorf("unexpected order '%v'", actual)
+	}
+
+	// The serialized attributes are in the same order, every time.
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<root z="1" a="replaced" m="3" xmlns:p="urn:p" p:x="4" b="5"/>
+`
+	for i := 0; i < 10; i++ {
+		if actual := serializeToString(root); actual != expected {
+			t.Errorf("expected '%v', got '%v'", expected, actual)
+			break
+		}
+	}
+}
+
+func TestNamedNodeMapNS(t *testing.T) {
+	doc := mustParse(t, `<root x="2"/>`)
+	root := doc.GetDocumentElement()
+	root.SetAttributeNS("urn:p", "p:x", "1")
+	attrs := root.GetAttributes()
+
+	if n := attrs.GetNamedItemNS("urn:p", "x"); n == nil || n.GetNodeValue() != "1" {
+		t.Errorf("unexpected item %v", n)
+	}
+	if n := attrs.GetNamedItemNS("urn:other", "x"); n != nil {
+		t.Errorf("expected no item, got %v", n)
+	}
+
+	// A different prefix for the same namespace URI replaces the attribute.
+	attr, _ := doc.CreateAttributeNS("urn:p", "q:x")
+	attr.SetValue("3")
+	if err := attrs.SetNamedItemNS(attr); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if attrs.Length() != 2 || attrs.Item(1) != attr || attrs.GetNamedItem("p:x") != nil {
+		t.Errorf("expected 'p:x' to be replaced by 'q:x', got %v", attrs)
+	}
+
+	removed, err := attrs.RemoveNamedItemNS("urn:p", "x")
+	if err != nil || removed != attr {
+		t.Errorf("unexpected result %v, %v", removed, err)
+	}
+	if attr.GetOwnerElement() != nil {
+		t.Error("expected the removed attribute to have no owner element")
+	}
+	if _, err := attrs.RemoveNamedItemNS("urn:p", "x"); err != ErrorNotFound {
+		t.Errorf("expected %v, got %v", ErrorNotFound, err)
+	}
+	if _, err := attrs.RemoveNamedItem("missing"); err != ErrorNotFound {
+		t.Errorf("expected %v, got %v", ErrorNotFound, err)
+	}
+}
+
+func TestNamedNodeMapErrors(t *testing.T) {
+	doc := mustParse(t, `<root a="1"><child/></root>`)
+	root := doc.GetDocumentElement()
+	child := root.GetFirstChild().(Element)
+
+	inUse := root.GetAttributes().GetNamedItem("a")
+	if err := child.GetAttributes().SetNamedItem(inUse); err != ErrorAttrInUse {
+		t.Errorf("expected %v, got %v", ErrorAttrInUse, err)
+	}
+	if err := root.GetAttributes().SetNamedItem(inUse); err != nil {
+		t.Errorf("expected no error when setting an attribute at its own element, got %v", err)
+	}
+
+	other, _ := NewDocument().CreateAttribute("b")
+	if err := child.GetAttributes().SetNamedItem(other); err != ErrorWrongDocument {
+		t.Errorf("expected %v, got %v", ErrorWrongDocument, err)
+	}
+
+	// The map keeps the owner element of the attributes.
+	attr, _ := doc.CreateAttribute("b")
+	child.GetAttributes().SetNamedItem(attr)
+	if attr.GetOwnerElement() != child {
+		t.Errorf("expected owner element 'child', got %v", attr.GetOwnerElement())
+	}
+	replacement, _ := doc.CreateAttribute("b")
+	child.GetAttributes().SetNamedItem(replacement)
+	if attr.GetOwnerElement() != nil || replacement.GetOwnerElement() != child {
+		t.Error("expected the replaced attribute to have no owner element")
+	}
+}
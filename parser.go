@@ -1,6 +1,8 @@
 package dom
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -13,6 +15,7 @@ type Parser struct {
 	reader io.Reader // Reader containing the XML document.
 
 	Configuration Configuration
+	Resolver      DTDResolver // Reads the external subset of the DTD. Optional.
 }
 
 // NewParser constructs a new Parser using the given reader. The reader is expected
@@ -25,37 +28,131 @@ func NewParser(reader io.Reader) *Parser {
 	return b
 }
 
+// parseState holds the state of a single call to Parse, which is shared between the
+// document itself and the replacement texts of the entities used in the document.
+type parseState struct {
+	config   Configuration
+	resolver DTDResolver
+	doc      Document
+	doctype  *domDocumentType      // The DTD of the Document, if any.
+	entities map[string]*domEntity // Entities declared in the DTD.
+	names    map[string]string     // Entity map for the decoder, see appendCharData.
+	loaded   map[string]bool       // Entities of which the replacement text is parsed.
+	loading  map[string]bool       // Entities currently being parsed, to detect recursion.
+	offset   int64                 // The input offset of the current token of the Document.
+}
+
 // Parse parses an XML Document contained within the reader attribute of the current Parser.
 // A Document will be returned and a nil error if the parsing succeeded.
+//
+// When the Configuration has an ErrorHandler, the error which stops the parsing is reported
+// to it as a fatal error. When validating, every violation is reported to it as an error,
+// and the Document is returned if the handler accepts all of them.
 func (b *Parser) Parse() (Document, error) {
 	doc := NewDocument()
-	decoder := xml.NewDecoder(b.reader)
-	var curNode = Node(doc)
+	ps := &parseState{
+		config:   b.Configuration,
+		resolver: b.Resolver,
+		doc:      doc,
+		entities: make(map[string]*domEntity),
+		names:    make(map[string]string),
+		loaded:   make(map[string]bool),
+		loading:  make(map[string]bool),
+	}
+	if err := ps.parse(doc, b.reader); err != nil {
+		if b.Configuration.ErrorHandler != nil {
+			reportError(b.Configuration.ErrorHandler, ps.fatalError(err))
+		}
+		return nil, err
+	}
+
+	if b.Configuration.Validate {
+		validator := &Validator{Resolver: b.Resolver}
+		violations, err := validator.Validate(doc)
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) > 0 && !b.reportViolations(violations) {
+			return nil, violations
+		}
+	}
+	return doc, nil
+}
+
+// fatalError creates the DOMError for the error which stopped the parsing. The line is only
+// known for syntax errors.
+func (ps *parseState) fatalError(err error) *domError {
+	line := -1
+	if syntaxErr, ok := err.(*xml.SyntaxError); ok {
+		line = syntaxErr.Line
+	}
+	return &domError{
+		severity:  SeverityFatalError,
+		message:   err.Error(),
+		errType:   "parse-error",
+		exception: err,
+		location:  &domLocator{line: line, column: -1, offset: int(ps.offset)},
+	}
+}
+
+// reportViolations reports the validation errors to the ErrorHandler of the Configuration,
+// and returns true if the handler accepts all of them. Without a handler, none are accepted.
+func (b *Parser) reportViolations(violations ValidationErrors) bool {
+	if b.Configuration.ErrorHandler == nil {
+		return false
+	}
+	for _, violation := range violations {
+		domErr := newNodeError(SeverityError, "validation-error", violation.Node, "%v", violation.Message)
+		domErr.exception = violation
+		if !reportError(b.Configuration.ErrorHandler, domErr) {
+			return false
+		}
+	}
+	return true
+}
+
+// parse parses the XML from the reader, and appends the result to the given root. The
+// root is either the Document, or a DocumentFragment when parsing the replacement
+// text of an entity.
+func (ps *parseState) parse(root Node, reader io.Reader) error {
+	doc := ps.doc
+	raw := newRawReader(reader)
+	decoder := xml.NewDecoder(raw)
+	// The map is shared, so entities declared in the DOCTYPE are picked up by the decoder.
+	decoder.Entity = ps.names
+	var curNode = root
 
 	for {
+		// Remember where the token starts, so we can inspect its raw markup. Anything
+		// before that is not necessary anymore.
+		start := decoder.InputOffset()
+		raw.discard(start)
+		if root == ps.doc {
+			ps.offset = start
+		}
+
 		token, err := decoder.Token()
 		if err == io.EOF {
 			// End of file, processed okay
-			return doc, nil
+			return nil
 		}
 		if err != nil {
 			// Other error, return that.
-			return nil, err
+			return err
 		}
 
 		switch typ := token.(type) {
 		case xml.Comment:
 			// Skip comments?
-			if !b.Configuration.Comments {
+			if !ps.config.Comments {
 				continue
 			}
-
 			cmt, err := doc.CreateComment(string(typ))
 			if err != nil {
-				return nil, err
+				return err
 			}
 			if err = curNode.AppendChild(cmt); err != nil {
-				return nil, err
+				return err
 			}
 		case xml.ProcInst:
 			// Note: the Go default decoder regards the XML declaration as a processing
@@ -64,12 +161,34 @@ func (b *Parser) Parse() (Document, error) {
 			if strings.ToLower(typ.Target) != "xml" {
 				pi, err := doc.CreateProcessingInstruction(typ.Target, string(typ.Inst))
 				if err != nil {
-					return nil, err
+					return err
 				}
-				if err = doc.AppendChild(pi); err != nil {
-					return nil, err
+				if err = curNode.AppendChild(pi); err != nil {
+					return err
 				}
 			}
+		case xml.Directive:
+			// Only the DOCTYPE declaration is a valid directive in a document. Use the raw
+			// markup instead of the directive, since the decoder strips comments from it.
+			if !bytes.HasPrefix(typ, []byte("DOCTYPE")) || curNode != doc {
+				continue
+			}
+			markup := raw.slice(start, decoder.InputOffset())
+			doctype, err := parseDoctype(doc, string(markup[2:len(markup)-1]))
+			if err != nil {
+				return err
+			}
+			if err = curNode.AppendChild(doctype); err != nil {
+				return err
+			}
+			if err = doctype.loadExternalSubset(ps.resolver); err != nil {
+				return err
+			}
+			ps.doctype = doctype
+			for name, entity := range doctype.GetEntities().GetItems() {
+				ps.entities[name] = entity.(*domEntity)
+				ps.names[name] = ""
+			}
 		case xml.StartElement:
 			//  FIXME: The default encoding/xml.Decoder does fuck all about prefixes.
 			// That's not all: https://github.com/golang/go/issues/11735
@@ -77,20 +196,25 @@ func (b *Parser) Parse() (Document, error) {
 			// NormalizeDocument() or Normalize() can be used to "fix" the namespaces.
 			namespace := ""
 			// Are we parsing namespaces? e.g. namespace awareness?
-			if b.Configuration.NamespaceDeclarations {
+			if ps.config.NamespaceDeclarations {
 				namespace = typ.Name.Space
 			}
 
 			elem, err := doc.CreateElementNS(namespace, typ.Name.Local)
 			if err != nil {
-				return nil, err
+				return err
+			}
+			// The entity references in the values are found in the raw markup.
+			rawValues := rawAttrValues(string(raw.slice(start, decoder.InputOffset())))
+			if len(rawValues) != len(typ.Attr) {
+				return fmt.Errorf("malformed attributes of element '%v'", typ.Name.Local)
 			}
 
 			// Iterate over the element's attributes.
-			for _, a := range typ.Attr {
+			for i, a := range typ.Attr {
 				namespace = ""
 				// Are we parsing namespaces?
-				if b.Configuration.NamespaceDeclarations {
+				if ps.config.NamespaceDeclarations {
 					namespace = a.Name.Space
 				}
 
@@ -115,53 +239,345 @@ func (b *Parser) Parse() (Document, error) {
 				// Add all other (normal) attributes.
 				attr, err := doc.CreateAttributeNS(namespace, attrName)
 				if err != nil {
-					return nil, err
+					return err
+				}
+				value, err := ps.expandAttrValue(rawValues[i])
+				if err != nil {
+					return err
 				}
-				attr.SetValue(a.Value)
-				elem.GetAttributes().SetNamedItem(attr)
+				attr.SetValue(value)
+				if err := elem.SetAttributeNode(attr); err != nil {
+					return err
+				}
+			}
+			// Add default attributes, and apply the attribute types from the DTD.
+			if ps.doctype != nil {
+				ps.doctype.applyAttributeDecls(elem)
 			}
 
 			if err = curNode.AppendChild(elem); err != nil {
-				return nil, err
+				return err
 			}
 			curNode = elem
 		case xml.EndElement:
 			curNode = curNode.GetParentNode()
 		case xml.CharData:
-			// If there is no document element yet, and the character data is found which is NOT whitespace,
-			// generate an error. No character data allowed before document element, but whitespaces
-			// are okay to parse. Don't add it as a child element though.
-			if doc.GetDocumentElement() == nil {
-				if strings.TrimSpace(string(typ)) != "" {
-					return nil, fmt.Errorf("%v: content is not allowed in prolog", ErrorHierarchyRequest)
-				}
-				// We got whitespace. Don't add it as a child, merely continue the next token
-				// parsing in the stream.
-				continue
-			}
-			// Likewise, character data may not occur after the document element in the trailing
-			// section, so check that as well. The Go decoder doesn't care so we handle this edge
-			// case as well.
+			// The prolog and trailing section only exist in the Document itself, not in
+			// the replacement text of an entity.
 			if curNode == doc {
+				// If there is no document element yet, and the character data is found which is NOT whitespace,
+				// generate an error. No character data allowed before document element, but whitespaces
+				// are okay to parse. Don't add it as a child element though.
+				if doc.GetDocumentElement() == nil {
+					if strings.TrimSpace(string(typ)) != "" {
+						return fmt.Errorf("%v: content is not allowed in prolog", ErrorHierarchyRequest)
+					}
+					// We got whitespace. Don't add it as a child, merely continue the next token
+					// parsing in the stream.
+					continue
+				}
+				// Likewise, character data may not occur after the document element in the trailing
+				// section, so check that as well. The Go decoder doesn't care so we handle this edge
+				// case as well.
 				if strings.TrimSpace(string(typ)) != "" {
 					// We cannot append text/chardata to the document itself.
-					return nil, fmt.Errorf("%v: content is not allowed in trailing section", ErrorHierarchyRequest)
+					return fmt.Errorf("%v: content is not allowed in trailing section", ErrorHierarchyRequest)
 				}
 				// We got whitespace. Don't add it as a child, merely continue the next token
 				// parsing in the stream. Same behaviour as above.
 				continue
 			}
 
-			text := doc.CreateText(string(typ))
-			// Should we ignore ignorable whitespaces, and the text content is whitespace?
-			if !b.Configuration.ElementContentWhitespace && text.IsElementContentWhitespace() {
+			// The decoder does not tell CDATA sections apart from other character data,
+			// so check the raw markup of the token.
+			if raw.hasPrefix(start, "<![CDATA[") {
+				if ps.config.CDataSections {
+					err = curNode.AppendChild(doc.CreateCDATASection(string(typ)))
+				} else {
+					err = ps.appendText(curNode, string(typ))
+				}
+				if err != nil {
+					return err
+				}
 				continue
 			}
 
-			// In all other cases, create a text node and add it to the current node as a child.
-			if err := curNode.AppendChild(text); err != nil {
+			// The entity references are found in the raw markup, since the decoder
+			// replaces them.
+			if err := ps.appendCharData(curNode, string(raw.slice(start, decoder.InputOffset()))); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// appendCharData appends the raw character data to the parent as Text nodes. The decoder
+// replaces entity references by the value in its Entity map, where every declared entity is
+// mapped to an empty string, so the references are taken from the raw markup instead. They
+// are replaced by EntityReference nodes, or by their expansion, depending on the configuration.
+func (ps *parseState) appendCharData(parent Node, raw string) error {
+	parts, err := splitEntityRefs(raw)
+	if err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if part.entity == "" {
+			if err := ps.appendText(parent, part.text); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := part.entity
+		entity, ok := ps.entities[name]
+		if !ok {
+			return fmt.Errorf("reference to undeclared entity '%v'", name)
+		}
+		if entity.notationName != "" {
+			return fmt.Errorf("reference to unparsed entity '%v'", name)
+		}
+		if err := ps.loadEntity(entity); err != nil {
+			return err
+		}
+
+		// The expansion of external entities is unknown, so these are always kept.
+		if ps.config.Entities || !entity.internal {
+			ref := newEntityReference(ps.doc, name)
+			ref.setChildren(cloneChildren(entity))
+			if err := parent.AppendChild(ref); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, child := range cloneChildren(entity) {
+			var err error
+			if text, ok := child.(Text); ok && child.GetNodeType() == TextNode {
+				err = ps.appendText(parent, text.GetText())
+			} else {
+				err = parent.AppendChild(child)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// appendText appends a Text node with the given data to the parent.
+func (ps *parseState) appendText(parent Node, data string) error {
+	if data == "" {
+		return nil
+	}
+	text := ps.doc.CreateText(data)
+	// Should we ignore ignorable whitespaces, and the text content is whitespace?
+	if !ps.config.ElementContentWhitespace && text.IsElementContentWhitespace() {
+		return nil
+	}
+
+	// When CDATA sections or entity references are not kept, they become Text nodes
+	// which are merged with adjacent Text nodes.
+	if last, ok := parent.GetLastChild().(Text); ok && (!ps.config.CDataSections || !ps.config.Entities) && last.GetNodeType() == TextNode {
+		last.SetText(last.GetText() + text.GetText())
+		return nil
+	}
+
+	// In all other cases, add the text node to the parent as a child.
+	return parent.AppendChild(text)
+}
+
+// loadEntity parses the replacement text of an internal entity, and sets the result as
+// the children of the entity. This is done once, when the entity is first referenced.
+func (ps *parseState) loadEntity(entity *domEntity) error {
+	if !entity.internal || ps.loaded[entity.name] {
+		return nil
+	}
+	if ps.loading[entity.name] {
+		return fmt.Errorf("recursive reference to entity '%v'", entity.name)
+	}
+	ps.loading[entity.name] = true
+	defer delete(ps.loading, entity.name)
+
+	frag := ps.doc.CreateDocumentFragment()
+	if err := ps.parse(frag, strings.NewReader(entity.value)); err != nil {
+		return fmt.Errorf("in entity '%v': %v", entity.name, err)
+	}
+	entity.setChildren(frag.GetChildNodes())
+	ps.loaded[entity.name] = true
+	return nil
+}
+
+// expandAttrValue replaces the entity references in a raw attribute value by the text
+// content of the entities, and returns the value.
+func (ps *parseState) expandAttrValue(raw string) (string, error) {
+	parts, err := splitEntityRefs(raw)
+	if err != nil {
+		return "", err
+	}
+	var value strings.Builder
+	for _, part := range parts {
+		if part.entity == "" {
+			value.WriteString(part.text)
+			continue
+		}
+		entity, ok := ps.entities[part.entity]
+		if !ok {
+			return "", fmt.Errorf("reference to undeclared entity '%v' in attribute value", part.entity)
+		}
+		if !entity.internal {
+			return "", fmt.Errorf("reference to external entity '%v' in attribute value", part.entity)
+		}
+		if err := ps.loadEntity(entity); err != nil {
+			return "", err
+		}
+		value.WriteString(entity.GetTextContent())
+	}
+	return value.String(), nil
+}
+
+// charDataPart is a part of raw character data or of a raw attribute value, being either
+// text or a reference to a general entity.
+type charDataPart struct {
+	text   string
+	entity string // The name of the referenced entity, or empty for text.
+}
+
+// predefinedEntities are the entities which are recognized without being declared.
+var predefinedEntities = map[string]string{"lt": "<", "gt": ">", "amp": "&", "apos": "'", "quot": "\""}
+
+// splitEntityRefs splits raw character data or a raw attribute value in text and references
+// to general entities. Character references and the predefined entities are replaced by their
+// characters, and line endings are normalized, like the decoder does.
+func splitEntityRefs(raw string) ([]charDataPart, error) {
+	var parts []charDataPart
+	var text strings.Builder
+	for {
+		amp := strings.IndexByte(raw, '&')
+		if amp < 0 {
+			text.WriteString(normalizeNewlines(raw))
+			break
+		}
+		text.WriteString(normalizeNewlines(raw[:amp]))
+		end := strings.IndexByte(raw[amp:], ';')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated reference in '%v'", raw)
+		}
+		ref := raw[amp : amp+end+1]
+		raw = raw[amp+end+1:]
+
+		name := ref[1 : len(ref)-1]
+		if strings.HasPrefix(name, "#") {
+			char, err := expandCharRefs(ref)
+			if err != nil {
 				return nil, err
 			}
+			text.WriteString(char)
+		} else if value, ok := predefinedEntities[name]; ok {
+			text.WriteString(value)
+		} else {
+			if text.Len() > 0 {
+				parts = append(parts, charDataPart{text: text.String()})
+				text.Reset()
+			}
+			parts = append(parts, charDataPart{entity: name})
 		}
 	}
+	if text.Len() > 0 {
+		parts = append(parts, charDataPart{text: text.String()})
+	}
+	return parts, nil
+}
+
+// normalizeNewlines replaces the \r\n and \r line endings by \n.
+func normalizeNewlines(s string) string {
+	return strings.Replace(strings.Replace(s, "\r\n", "\n", -1), "\r", "\n", -1)
+}
+
+// rawAttrValues returns the raw values of the attributes in the markup of a start tag, in
+// the order they appear, without the quotes.
+func rawAttrValues(markup string) []string {
+	var values []string
+	for {
+		eq := strings.IndexByte(markup, '=')
+		if eq < 0 {
+			return values
+		}
+		rest := strings.TrimLeft(markup[eq+1:], " \t\r\n")
+		if rest == "" {
+			return values
+		}
+		end := strings.IndexByte(rest[1:], rest[0])
+		if end < 0 {
+			return values
+		}
+		values = append(values, rest[1:end+1])
+		markup = rest[end+2:]
+	}
+}
+
+// rawReader records the bytes which are read by the xml.Decoder, so the raw markup of
+// a token can be inspected after decoding it. This is necessary since the decoder does
+// not distinguish CDATA sections from any other character data. The decoder reads byte
+// by byte when given an io.ByteReader, so that's what this type is.
+type rawReader struct {
+	r      io.ByteReader
+	buf    []byte // The bytes read so far, starting at the input offset 'offset'.
+	offset int64  // The input offset of the first byte in buf.
+}
+
+func newRawReader(r io.Reader) *rawReader {
+	rr := &rawReader{}
+	if br, ok := r.(io.ByteReader); ok {
+		rr.r = br
+	} else {
+		rr.r = bufio.NewReader(r)
+	}
+	return rr
+}
+
+// ReadByte reads the next byte and records it.
+func (rr *rawReader) ReadByte() (byte, error) {
+	c, err := rr.r.ReadByte()
+	if err == nil {
+		rr.buf = append(rr.buf, c)
+	}
+	return c, err
+}
+
+// Read is implemented to satisfy the io.Reader interface, but the decoder will only
+// ever use ReadByte.
+func (rr *rawReader) Read(p []byte) (int, error) {
+	for i := range p {
+		c, err := rr.ReadByte()
+		if err != nil {
+			return i, err
+		}
+		p[i] = c
+	}
+	return len(p), nil
+}
+
+// hasPrefix returns true if the raw input starting at the given offset starts with
+// the given prefix.
+func (rr *rawReader) hasPrefix(offset int64, prefix string) bool {
+	index := offset - rr.offset
+	if index < 0 || index > int64(len(rr.buf)) {
+		return false
+	}
+	return bytes.HasPrefix(rr.buf[index:], []byte(prefix))
+}
+
+// slice returns the raw input between the offsets start and end.
+func (rr *rawReader) slice(start, end int64) []byte {
+	return rr.buf[start-rr.offset : end-rr.offset]
+}
+
+// discard forgets all recorded bytes before the given offset.
+func (rr *rawReader) discard(offset int64) {
+	index := offset - rr.offset
+	if index <= 0 || index > int64(len(rr.buf)) {
+		return
+	}
+	rr.buf = rr.buf[index:]
+	rr.offset = offset
 }
@@ -28,6 +28,17 @@ var exampleDoc1 = `<?xml version="1.0" encoding="UTF-8"?>
 	<!-- Comment with one-dash -->
 </directory>`
 
+// mustParse parses the XML of a test fixture, and stops the test if that fails.
+func mustParse(t *testing.T, xml string) Document {
+	t.Helper()
+	doc, err := NewParser(strings.NewReader(xml)).Parse()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	return doc
+}
+
 // Tests a completely valid document and checks whether everything is in place.
 func TestParserParse(t *testing.T) {
 	reader := strings.NewReader(exampleDoc1)
@@ -249,3 +260,116 @@ func TestParserWut(t *testing.T) {
 	}
 	_ = doc
 }
+
+//=============================================================================
+
+var exampleDocCDATA = `<?xml version="1.0" encoding="UTF-8"?>
+<page>before<![CDATA[<script>if (a && b) {}</script>]]>after</page>`
+
+func TestParserParseCDATASections(t *testing.T) {
+	parser := NewParser(strings.NewReader(exampleDocCDATA))
+	doc, err := parser.Parse()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+
+	children := doc.GetDocumentElement().GetChildNodes()
+	if len(children) != 3 {
+		t.Errorf("expected 3 children, got %d", len(children))
+		t.FailNow()
+	}
+	if children[1].GetNodeType() != CDATASectionNode {
+		t.Errorf("expected a CDATA section, got '%v'", children[1])
+	}
+	if children[1].GetNodeValue() != "<script>if (a && b) {}</script>" {
+		t.Errorf("unexpected CDATA content '%v'", children[1].GetNodeValue())
+	}
+
+	// Without CDATA sections, everything should be merged in one Text node.
+	parser = NewParser(strings.NewReader(exampleDocCDATA))
+	parser.Configuration.CDataSections = false
+	doc, err = parser.Parse()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+
+	children = doc.GetDocumentElement().GetChildNodes()
+	if len(children) != 1 {
+		t.Errorf("expected 1 child, got %d", len(children))
+		t.FailNow()
+	}
+	if children[0].GetNodeType() != TextNode {
+		t.Errorf("expected a Text node, got '%v'", children[0])
+	}
+	expected := "before<script>if (a && b) {}</script>after"
+	if children[0].GetNodeValue() != expected {
+		t.Errorf("expected '%v', got '%v'", expected, children[0].GetNodeValue())
+	}
+}
+
+//=============================================================================
+
+var exampleDocDoctype = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- Comment before the doctype -->
+<!DOCTYPE note SYSTEM "note.dtd" [
+  <!-- internal subset comment -->
+  <!ELEMENT note (#PCDATA)>
+]>
+<note>Hi</note>`
+
+func TestParserParseDoctype(t *testing.T) {
+	doc := mustParse(t, exampleDocDoctype)
+
+	doctype := doc.GetDoctype()
+	if doctype == nil {
+		t.Error("expected a doctype")
+		t.FailNow()
+	}
+	if doc.GetChildNodes()[1] != doctype {
+		t.Error("expected the doctype as second child of the document")
+	}
+	if doctype.GetName() != "note" {
+		t.Errorf("expected 'note', got '%v'", doctype.GetName())
+	}
+	if doctype.GetSystemID() != "note.dtd" {
+		t.Errorf("expected 'note.dtd', got '%v'", doctype.GetSystemID())
+	}
+	expected := `
+  <!-- internal subset comment -->
+  <!ELEMENT note (#PCDATA)>
+`
+	if doctype.GetInternalSubset() != expected {
+		t.Errorf("expected internal subset '%v', got '%v'", expected, doctype.GetInternalSubset())
+	}
+}
+
+// The noncharacters U+FDD0 and U+FDD1 are legal in XML, and must not be mistaken for
+// entity references.
+func TestParserParseNoncharacters(t *testing.T) {
+	tests := []string{
+		"<a b=\"x\uFDD0y\">x\uFDD0y</a>",
+		"<a b=\"x\uFDD0y\uFDD1\">x\uFDD0y\uFDD1</a>",
+		"<!DOCTYPE a [<!ENTITY e \"v\">]><a b=\"\uFDD0e\uFDD1&e;\">\uFDD0e\uFDD1&e;</a>",
+	}
+	expected := []string{"x\uFDD0y", "x\uFDD0y\uFDD1", "\uFDD0e\uFDD1v"}
+	for i, input := range tests {
+		for _, entities := range []bool{true, false} {
+			parser := NewParser(strings.NewReader(input))
+			parser.Configuration.Entities = entities
+			doc, err := parser.Parse()
+			if err != nil {
+				t.Errorf("unexpected error for %q: %v", input, err)
+				continue
+			}
+			root := doc.GetDocumentElement()
+			if value := root.GetAttribute("b"); value != expected[i] {
+				t.Errorf("expected attribute %q, got %q", expected[i], value)
+			}
+			if text := root.GetTextContent(); text != expected[i] {
+				t.Errorf("expected text %q, got %q", expected[i], text)
+			}
+		}
+	}
+}
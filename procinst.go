@@ -4,6 +4,7 @@ import "fmt"
 
 type domProcInst struct {
 	ownerDocument Document
+	state         nodeState // The state the DOM keeps for this node.
 	parentNode    Node
 	data          string
 	target        string
@@ -126,7 +127,7 @@ func (pi *domProcInst) GetTarget() string {
 }
 
 func (pi *domProcInst) SetData(data string) {
-	pi.data = data
+	replaceData(pi, 0, dataLength(pi.data), data)
 }
 
 func (pi *domProcInst) setTarget(target string) {
@@ -142,17 +143,58 @@ func (pi *domProcInst) SetTextContent(content string) {
 }
 
 func (pi *domProcInst) CloneNode(deep bool) Node {
-	clonePi, err := pi.ownerDocument.CreateProcessingInstruction(pi.target, pi.data)
+	return pi.cloneNode(pi.ownerDocument, deep, NodeCloned)
+}
+
+func (pi *domProcInst) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	clonePi, err := owner.CreateProcessingInstruction(pi.target, pi.data)
 	if err != nil {
 		panic("CreateProcessingInstruction returned an unexpected error")
 	}
+	notifyUserDataHandlers(operation, pi, clonePi)
 	return clonePi
 }
 
+func (pi *domProcInst) Normalize() {
+	normalize(pi)
+}
+
+func (pi *domProcInst) IsSameNode(other Node) bool {
+	return isSameNode(pi, other)
+}
+
+func (pi *domProcInst) IsEqualNode(other Node) bool {
+	return isEqualNode(pi, other)
+}
+
+func (pi *domProcInst) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(pi, other)
+}
+
+func (pi *domProcInst) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(pi, key, data, handler)
+}
+
+func (pi *domProcInst) GetUserData(key string) interface{} {
+	return getUserData(pi, key)
+}
+
 func (pi *domProcInst) ImportNode(n Node, deep bool) Node {
 	return nil
 }
 
+func (pi *domProcInst) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(pi, eventType, listener, useCapture)
+}
+
+func (pi *domProcInst) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(pi, eventType, listener, useCapture)
+}
+
+func (pi *domProcInst) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(pi, evt)
+}
+
 func (pi *domProcInst) setParentNode(parent Node) {
 	pi.parentNode = parent
 }
@@ -161,6 +203,10 @@ func (pi *domProcInst) setOwnerDocument(doc Document) {
 	pi.ownerDocument = doc
 }
 
+func (pi *domProcInst) getState() *nodeState {
+	return &pi.state
+}
+
 func (pi *domProcInst) String() string {
 	return fmt.Sprintf("%s: '%s'='%s'", pi.GetNodeType(), pi.target, pi.data)
 }
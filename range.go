@@ -0,0 +1,725 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// This file contains the DOM Level 2 Range module. A Range selects the content between
+// two boundary points of a tree, and is kept up-to-date when the tree is changed.
+// See https://www.w3.org/TR/DOM-Level-2-Traversal-Range/ranges.html. The algorithms
+// follow https://dom.spec.whatwg.org/#ranges, which specifies the corner cases.
+
+// ErrorBadBoundaryPoints is returned when the boundary points of a Range do not meet
+// specific requirements, like SurroundContents on a Range which partially selects an Element.
+var ErrorBadBoundaryPoints = errors.New("BAD_BOUNDARYPOINTS_ERR: the boundary-points of the Range do not meet specific requirements")
+
+// ErrorInvalidNodeType is returned when the container of a boundary point of a Range is
+// set to a Node of an invalid type, or a Node with an ancestor of an invalid type.
+var ErrorInvalidNodeType = errors.New("INVALID_NODE_TYPE_ERR: the container of a boundary-point of the Range is of an invalid type")
+
+// CompareHow defines which boundary points are compared by Range.CompareBoundaryPoints.
+type CompareHow uint8
+
+// Enumeration of the ways to compare two Ranges. The first part of the name is the
+// boundary point of the source Range, the second part the one of the Range itself.
+const (
+	StartToStart CompareHow = iota // Compares the start of the source to the start.
+	StartToEnd                     // Compares the start of the source to the end.
+	EndToEnd                       // Compares the end of the source to the end.
+	EndToStart                     // Compares the end of the source to the start.
+)
+
+// Range selects the content between a start and an end boundary point. A boundary point
+// is a container Node and an offset into it. The offset is a child index, or a position
+// in the character data if the container is a Text, CDATASection, Comment or
+// ProcessingInstruction. The start never comes after the end: setting one boundary point
+// past the other collapses the Range.
+//
+// The boundary points are updated when the tree is modified, so the Range keeps selecting
+// the same content, as long as the Range is not detached. Because of that, the Document
+// keeps a reference to every Range until Detach is called, and updates it on every
+// mutation. Always detach a Range when done with it, or it is never garbage collected.
+type Range interface {
+	GetStartContainer() Node           // Returns the container of the start.
+	GetStartOffset() int               // Returns the offset of the start in its container.
+	GetEndContainer() Node             // Returns the container of the end.
+	GetEndOffset() int                 // Returns the offset of the end in its container.
+	GetCollapsed() bool                // Returns true if the start and the end are the same.
+	GetCommonAncestorContainer() Node  // Returns the deepest Node containing both containers.
+	SetStart(n Node, offset int) error // Sets the start. The end is moved too if it would come before the start.
+	SetEnd(n Node, offset int) error   // Sets the end. The start is moved too if it would come after the end.
+	SetStartBefore(n Node) error       // Sets the start right before the Node.
+	SetStartAfter(n Node) error        // Sets the start right after the Node.
+	SetEndBefore(n Node) error         // Sets the end right before the Node.
+	SetEndAfter(n Node) error          // Sets the end right after the Node.
+	Collapse(toStart bool) error       // Collapses the Range to its start, or its end.
+	SelectNode(n Node) error           // Selects the Node and its contents.
+	SelectNodeContents(n Node) error   // Selects the contents of the Node.
+
+	// CompareBoundaryPoints compares a boundary point of this Range with one of the source
+	// Range, and returns -1, 0 or 1 when the boundary point of this Range is respectively
+	// before, equal to, or after the one of the source.
+	CompareBoundaryPoints(how CompareHow, source Range) (int, error)
+	// DeleteContents removes the contents of the Range from the tree. Elements which are
+	// partially selected are kept. Afterwards the Range is collapsed.
+	DeleteContents() error
+	// ExtractContents moves the contents of the Range from the tree to a new DocumentFragment.
+	// Elements which are partially selected are kept, but cloned into the fragment.
+	ExtractContents() (DocumentFragment, error)
+	// CloneContents copies the contents of the Range to a new DocumentFragment.
+	CloneContents() (DocumentFragment, error)
+	// InsertNode inserts the Node at the start of the Range. A Text container is split.
+	InsertNode(n Node) error
+	// SurroundContents moves the contents of the Range into the new parent, and inserts
+	// that at the start of the Range. The Range then selects the new parent.
+	SurroundContents(newParent Node) error
+	// CloneRange returns a new Range with the same boundary points. The clone must be
+	// detached as well.
+	CloneRange() (Range, error)
+	// ToString returns the character data of the Text nodes in the Range.
+	ToString() (string, error)
+	// Detach releases the Range. Using it afterwards results in an ErrorInvalidState.
+	Detach()
+}
+
+// rangeMode is the operation performed by domRange.contents.
+type rangeMode uint8
+
+const (
+	rangeExtract rangeMode = iota
+	rangeClone
+	rangeDelete
+)
+
+// domRange implements the Range.
+type domRange struct {
+	document       *domDocument // The Document the Range belongs to.
+	startContainer Node
+	startOffset    int
+	endContainer   Node
+	endOffset      int
+	detached       bool
+}
+
+func (r *domRange) GetStartContainer() Node {
+	return r.startContainer
+}
+
+func (r *domRange) GetStartOffset() int {
+	return r.startOffset
+}
+
+func (r *domRange) GetEndContainer() Node {
+	return r.endContainer
+}
+
+func (r *domRange) GetEndOffset() int {
+	return r.endOffset
+}
+
+func (r *domRange) GetCollapsed() bool {
+	return r.startContainer == r.endContainer && r.startOffset == r.endOffset
+}
+
+func (r *domRange) GetCommonAncestorContainer() Node {
+	container := r.startContainer
+	for !isInclusiveAncestor(container, r.endContainer) {
+		container = container.GetParentNode()
+	}
+	return container
+}
+
+func (r *domRange) SetStart(n Node, offset int) error {
+	return r.setBoundary(n, offset, true)
+}
+
+func (r *domRange) SetEnd(n Node, offset int) error {
+	return r.setBoundary(n, offset, false)
+}
+
+func (r *domRange) SetStartBefore(n Node) error {
+	return r.setBoundaryAt(n, 0, true)
+}
+
+func (r *domRange) SetStartAfter(n Node) error {
+	return r.setBoundaryAt(n, 1, true)
+}
+
+func (r *domRange) SetEndBefore(n Node) error {
+	return r.setBoundaryAt(n, 0, false)
+}
+
+func (r *domRange) SetEndAfter(n Node) error {
+	return r.setBoundaryAt(n, 1, false)
+}
+
+func (r *domRange) Collapse(toStart bool) error {
+	if r.detached {
+		return ErrorInvalidState
+	}
+	if toStart {
+		r.endContainer, r.endOffset = r.startContainer, r.startOffset
+	} else {
+		r.startContainer, r.startOffset = r.endContainer, r.endOffset
+	}
+	return nil
+}
+
+func (r *domRange) SelectNode(n Node) error {
+	parent, err := r.checkParent(n)
+	if err != nil {
+		return err
+	}
+	index := indexOf(parent.GetChildNodes(), n)
+	r.startContainer, r.startOffset = parent, index
+	r.endContainer, r.endOffset = parent, index+1
+	return nil
+}
+
+func (r *domRange) SelectNodeContents(n Node) error {
+	if err := r.checkNode(n); err != nil {
+		return err
+	}
+	r.startContainer, r.startOffset = n, 0
+	r.endContainer, r.endOffset = n, nodeLength(n)
+	return nil
+}
+
+func (r *domRange) CompareBoundaryPoints(how CompareHow, source Range) (int, error) {
+	if r.detached {
+		return 0, ErrorInvalidState
+	}
+	src, ok := source.(*domRange)
+	if !ok || src.detached {
+		return 0, ErrorInvalidState
+	}
+	if treeRoot(r.startContainer) != treeRoot(src.startContainer) {
+		return 0, ErrorWrongDocument
+	}
+
+	switch how {
+	case StartToStart:
+		return boundaryPosition(r.startContainer, r.startOffset, src.startContainer, src.startOffset), nil
+	case StartToEnd:
+		return boundaryPosition(r.endContainer, r.endOffset, src.startContainer, src.startOffset), nil
+	case EndToEnd:
+		return boundaryPosition(r.endContainer, r.endOffset, src.endContainer, src.endOffset), nil
+	case EndToStart:
+		return boundaryPosition(r.startContainer, r.startOffset, src.endContainer, src.endOffset), nil
+	}
+	return 0, ErrorNotSupported
+}
+
+func (r *domRange) DeleteContents() error {
+	_, err := r.contents(rangeDelete)
+	return err
+}
+
+func (r *domRange) ExtractContents() (DocumentFragment, error) {
+	return r.contents(rangeExtract)
+}
+
+func (r *domRange) CloneContents() (DocumentFragment, error) {
+	return r.contents(rangeClone)
+}
+
+func (r *domRange) InsertNode(n Node) error {
+	if r.detached {
+		return ErrorInvalidState
+	}
+	if n == nil {
+		return fmt.Errorf("%v: the node can not be nil", ErrorHierarchyRequest)
+	}
+	start := r.startContainer
+	switch n.GetNodeType() {
+	case AttributeNode, EntityNode, DocumentNode:
+		return ErrorInvalidNodeType
+	}
+	if start.GetNodeType() == CommentNode || start.GetNodeType() == ProcessingInstructionNode {
+		return ErrorHierarchyRequest
+	}
+	if isTextNode(start) && start.GetParentNode() == nil {
+		return ErrorHierarchyRequest
+	}
+	if isInclusiveAncestor(n, start) {
+		return ErrorHierarchyRequest
+	}
+	if isReadOnly(start) {
+		return ErrorNoModificationAllowed
+	}
+
+	// Find the parent and the reference child to insert the node before.
+	var parent, refChild Node
+	if isTextNode(start) {
+		parent = start.GetParentNode()
+		var err error
+		if refChild, err = splitText(start, r.startOffset); err != nil {
+			return err
+		}
+	} else {
+		parent = start
+		if children := start.GetChildNodes(); r.startOffset < len(children) {
+			refChild = children[r.startOffset]
+		}
+	}
+	if n == refChild {
+		refChild = refChild.GetNextSibling()
+	}
+	if oldParent := n.GetParentNode(); oldParent != nil {
+		if _, err := oldParent.RemoveChild(n); err != nil {
+			return err
+		}
+	}
+
+	// The offset right after the inserted node(s), to extend a collapsed Range with.
+	newOffset := len(parent.GetChildNodes())
+	if refChild != nil {
+		newOffset = indexOf(parent.GetChildNodes(), refChild)
+	}
+	if n.GetNodeType() == DocumentFragmentNode {
+		newOffset += len(n.GetChildNodes())
+	} else {
+		newOffset++
+	}
+
+	collapsed := r.GetCollapsed()
+	if _, err := parent.InsertBefore(n, refChild); err != nil {
+		return err
+	}
+	if collapsed {
+		r.endContainer, r.endOffset = parent, newOffset
+	}
+	return nil
+}
+
+func (r *domRange) SurroundContents(newParent Node) error {
+	if r.detached {
+		return ErrorInvalidState
+	}
+	if newParent == nil {
+		return fmt.Errorf("%v: the new parent can not be nil", ErrorHierarchyRequest)
+	}
+	// A partially selected node would have to be split, which is only possible for text.
+	for _, n := range r.partiallyContained() {
+		if !isTextNode(n) {
+			return ErrorBadBoundaryPoints
+		}
+	}
+	switch newParent.GetNodeType() {
+	case AttributeNode, EntityNode, DocumentNode, DocumentTypeNode, DocumentFragmentNode:
+		return ErrorInvalidNodeType
+	}
+
+	fragment, err := r.ExtractContents()
+	if err != nil {
+		return err
+	}
+	for newParent.GetFirstChild() != nil {
+		if _, err := newParent.RemoveChild(newParent.GetFirstChild()); err != nil {
+			return err
+		}
+	}
+	if err := r.InsertNode(newParent); err != nil {
+		return err
+	}
+	if err := newParent.AppendChild(fragment); err != nil {
+		return err
+	}
+	return r.SelectNode(newParent)
+}
+
+func (r *domRange) CloneRange() (Range, error) {
+	if r.detached {
+		return nil, ErrorInvalidState
+	}
+	clone := &domRange{}
+	*clone = *r
+	r.document.ranges = append(r.document.ranges, clone)
+	return clone, nil
+}
+
+func (r *domRange) ToString() (string, error) {
+	if r.detached {
+		return "", ErrorInvalidState
+	}
+	if r.startContainer == r.endContainer && isTextNode(r.startContainer) {
+		return substringData(r.startContainer.GetNodeValue(), r.startOffset, r.endOffset-r.startOffset), nil
+	}
+
+	var sb strings.Builder
+	if isTextNode(r.startContainer) {
+		data := r.startContainer.GetNodeValue()
+		sb.WriteString(substringData(data, r.startOffset, dataLength(data)-r.startOffset))
+	}
+	var traverse func(n Node)
+	traverse = func(n Node) {
+		for _, child := range n.GetChildNodes() {
+			if isTextNode(child) && r.contains(child) {
+				sb.WriteString(child.GetNodeValue())
+			}
+			traverse(child)
+		}
+	}
+	traverse(r.GetCommonAncestorContainer())
+	if isTextNode(r.endContainer) {
+		sb.WriteString(substringData(r.endContainer.GetNodeValue(), 0, r.endOffset))
+	}
+	return sb.String(), nil
+}
+
+func (r *domRange) Detach() {
+	if !r.detached {
+		r.detached = true
+		r.document.removeRange(r)
+	}
+}
+
+func (r *domRange) String() string {
+	return fmt.Sprintf("Range: (%v, %d) - (%v, %d)", r.startContainer, r.startOffset, r.endContainer, r.endOffset)
+}
+
+// checkNode checks whether the Node may be (in) a container of a boundary point.
+func (r *domRange) checkNode(n Node) error {
+	if r.detached {
+		return ErrorInvalidState
+	}
+	if n == nil {
+		return ErrorInvalidNodeType
+	}
+	if n != r.document && n.GetOwnerDocument() != r.document {
+		return ErrorWrongDocument
+	}
+	for c := n; c != nil; c = c.GetParentNode() {
+		switch c.GetNodeType() {
+		case AttributeNode, EntityNode, DocumentTypeNode:
+			return ErrorInvalidNodeType
+		}
+	}
+	return nil
+}
+
+// setBoundary sets the start or end boundary point, collapsing the Range when the other
+// boundary point would be on the wrong side of it, or in another tree.
+func (r *domRange) setBoundary(n Node, offset int, start bool) error {
+	if err := r.checkNode(n); err != nil {
+		return err
+	}
+	if offset < 0 || offset > nodeLength(n) {
+		return ErrorIndexSize
+	}
+	if isCharacterData(n) && splitsSurrogatePair(n.GetNodeValue(), offset) {
+		return ErrorIndexSize
+	}
+
+	if start {
+		if treeRoot(n) != treeRoot(r.endContainer) || boundaryPosition(n, offset, r.endContainer, r.endOffset) > 0 {
+			r.endContainer, r.endOffset = n, offset
+		}
+		r.startContainer, r.startOffset = n, offset
+	} else {
+		if treeRoot(n) != treeRoot(r.startContainer) || boundaryPosition(n, offset, r.startContainer, r.startOffset) < 0 {
+			r.startContainer, r.startOffset = n, offset
+		}
+		r.endContainer, r.endOffset = n, offset
+	}
+	return nil
+}
+
+// checkParent checks whether a boundary point may be set right before or after the Node
+// n, and returns the parent of n, which is the container of such a boundary point.
+func (r *domRange) checkParent(n Node) (Node, error) {
+	if r.detached {
+		return nil, ErrorInvalidState
+	}
+	if n == nil {
+		return nil, ErrorInvalidNodeType
+	}
+	switch n.GetNodeType() {
+	case AttributeNode, EntityNode, DocumentNode, DocumentFragmentNode:
+		return nil, ErrorInvalidNodeType
+	}
+	parent := n.GetParentNode()
+	if parent == nil {
+		return nil, ErrorInvalidNodeType
+	}
+	return parent, r.checkNode(parent)
+}
+
+// setBoundaryAt sets the start or end boundary point right before (delta 0) or after
+// (delta 1) the Node n, in its parent.
+func (r *domRange) setBoundaryAt(n Node, delta int, start bool) error {
+	parent, err := r.checkParent(n)
+	if err != nil {
+		return err
+	}
+	return r.setBoundary(parent, indexOf(parent.GetChildNodes(), n)+delta, start)
+}
+
+// contains returns true if the Node is completely selected by the Range.
+func (r *domRange) contains(n Node) bool {
+	return treeRoot(n) == treeRoot(r.startContainer) &&
+		boundaryPosition(n, 0, r.startContainer, r.startOffset) > 0 &&
+		boundaryPosition(n, nodeLength(n), r.endContainer, r.endOffset) < 0
+}
+
+// partiallyContained returns the nodes which are partially selected by the Range: the
+// inclusive ancestors of one container which are not an ancestor of the other one.
+func (r *domRange) partiallyContained() []Node {
+	var nodes []Node
+	for n := r.startContainer; n != nil; n = n.GetParentNode() {
+		if !isInclusiveAncestor(n, r.endContainer) {
+			nodes = append(nodes, n)
+		}
+	}
+	for n := r.endContainer; n != nil; n = n.GetParentNode() {
+		if !isInclusiveAncestor(n, r.startContainer) {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// contents extracts, clones or deletes the contents of the Range, depending on the mode.
+// The three operations share the same algorithm, which walks from the start to the end,
+// splitting the partially selected nodes. For rangeDelete, no fragment is returned.
+func (r *domRange) contents(mode rangeMode) (DocumentFragment, error) {
+	if r.detached {
+		return nil, ErrorInvalidState
+	}
+	fragment := r.document.CreateDocumentFragment()
+	if r.GetCollapsed() {
+		return fragment, nil
+	}
+
+	// The Range itself is updated while the tree is modified, so keep the original values.
+	startNode, startOffset := r.startContainer, r.startOffset
+	endNode, endOffset := r.endContainer, r.endOffset
+	if mode != rangeClone && (isReadOnly(startNode) || isReadOnly(endNode)) {
+		return nil, ErrorNoModificationAllowed
+	}
+
+	// cloneData clones the character data node n, with only the given part of its data.
+	cloneData := func(n Node, offset, count int) {
+		if mode != rangeDelete {
+			clone := n.CloneNode(false)
+			setCharacterData(clone, substringData(n.GetNodeValue(), offset, count))
+			fragment.AppendChild(clone)
+		}
+		if mode != rangeClone {
+			replaceData(n, offset, count, "")
+		}
+	}
+
+	if startNode == endNode && isCharacterData(startNode) {
+		cloneData(startNode, startOffset, endOffset-startOffset)
+		return fragment, nil
+	}
+
+	common := r.GetCommonAncestorContainer()
+	var firstPartial, lastPartial Node
+	var contained []Node
+	for _, child := range common.GetChildNodes() {
+		switch {
+		case r.contains(child):
+			if child.GetNodeType() == DocumentTypeNode && mode != rangeDelete {
+				return nil, ErrorHierarchyRequest
+			}
+			contained = append(contained, child)
+		case !isInclusiveAncestor(startNode, endNode) && isInclusiveAncestor(child, startNode):
+			firstPartial = child
+		case !isInclusiveAncestor(endNode, startNode) && isInclusiveAncestor(child, endNode):
+			lastPartial = child
+		}
+	}
+
+	// Where the Range is collapsed to afterwards: right after the partially selected
+	// ancestor of the start, in the common ancestor.
+	newNode, newOffset := startNode, startOffset
+	if !isInclusiveAncestor(startNode, endNode) {
+		ref := startNode
+		for !isInclusiveAncestor(ref.GetParentNode(), endNode) {
+			ref = ref.GetParentNode()
+		}
+		newNode = ref.GetParentNode()
+		newOffset = indexOf(newNode.GetChildNodes(), ref) + 1
+	}
+
+	// subContents handles a partially selected node, using a Range over the selected part.
+	subContents := func(partial, startNode Node, startOffset int, endNode Node, endOffset int) error {
+		sub := &domRange{document: r.document, startContainer: startNode, startOffset: startOffset, endContainer: endNode, endOffset: endOffset}
+		subFragment, err := sub.contents(mode)
+		if err != nil {
+			return err
+		}
+		if mode != rangeDelete {
+			clone := partial.CloneNode(false)
+			if err := clone.AppendChild(subFragment); err != nil {
+				return err
+			}
+			return fragment.AppendChild(clone)
+		}
+		return nil
+	}
+
+	if firstPartial != nil {
+		if isCharacterData(firstPartial) {
+			cloneData(firstPartial, startOffset, nodeLength(firstPartial)-startOffset)
+		} else if err := subContents(firstPartial, startNode, startOffset, firstPartial, nodeLength(firstPartial)); err != nil {
+			return nil, err
+		}
+	}
+	for _, child := range contained {
+		var err error
+		switch mode {
+		case rangeExtract:
+			err = fragment.AppendChild(child)
+		case rangeClone:
+			err = fragment.AppendChild(child.CloneNode(true))
+		case rangeDelete:
+			if _, err = common.RemoveChild(child); err == nil {
+				notifyUserDataDeleted(child)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if lastPartial != nil {
+		if isCharacterData(lastPartial) {
+			cloneData(lastPartial, 0, endOffset)
+		} else if err := subContents(lastPartial, lastPartial, 0, endNode, endOffset); err != nil {
+			return nil, err
+		}
+	}
+
+	if mode != rangeClone {
+		r.startContainer, r.startOffset = newNode, newOffset
+		r.endContainer, r.endOffset = newNode, newOffset
+	}
+	return fragment, nil
+}
+
+// nodeInserted updates the boundary points after the Node n is inserted into its parent.
+func (r *domRange) nodeInserted(n Node) {
+	parent := n.GetParentNode()
+	index := indexOf(parent.GetChildNodes(), n)
+	if r.startContainer == parent && r.startOffset > index {
+		r.startOffset++
+	}
+	if r.endContainer == parent && r.endOffset > index {
+		r.endOffset++
+	}
+}
+
+// nodeRemoving updates the boundary points before the Node n is removed from its parent.
+// Boundary points inside the Node are moved to the position of the Node in its parent.
+func (r *domRange) nodeRemoving(n Node) {
+	parent := n.GetParentNode()
+	if parent == nil {
+		return
+	}
+	index := indexOf(parent.GetChildNodes(), n)
+	if isInclusiveAncestor(n, r.startContainer) {
+		r.startContainer, r.startOffset = parent, index
+	}
+	if isInclusiveAncestor(n, r.endContainer) {
+		r.endContainer, r.endOffset = parent, index
+	}
+	if r.startContainer == parent && r.startOffset > index {
+		r.startOffset--
+	}
+	if r.endContainer == parent && r.endOffset > index {
+		r.endOffset--
+	}
+}
+
+// dataReplaced updates the boundary points before count code units of the character data
+// of the Node n, starting at offset, are replaced by data of the given length.
+func (r *domRange) dataReplaced(n Node, offset, count, length int) {
+	if r.startContainer == n {
+		if r.startOffset > offset+count {
+			r.startOffset += length - count
+		} else if r.startOffset > offset {
+			r.startOffset = offset
+		}
+	}
+	if r.endContainer == n {
+		if r.endOffset > offset+count {
+			r.endOffset += length - count
+		} else if r.endOffset > offset {
+			r.endOffset = offset
+		}
+	}
+}
+
+// textSplit updates the boundary points when the Text node n is split at the offset, so
+// boundary points after the offset move to the newNode.
+func (r *domRange) textSplit(n, newNode Node, offset int) {
+	if r.startContainer == n && r.startOffset > offset {
+		r.startContainer, r.startOffset = newNode, r.startOffset-offset
+	}
+	if r.endContainer == n && r.endOffset > offset {
+		r.endContainer, r.endOffset = newNode, r.endOffset-offset
+	}
+	parent := n.GetParentNode()
+	index := indexOf(parent.GetChildNodes(), n) + 1
+	if r.startContainer == parent && r.startOffset == index {
+		r.startOffset++
+	}
+	if r.endContainer == parent && r.endOffset == index {
+		r.endOffset++
+	}
+}
+
+// nodeLength returns the number of possible offsets in the Node, minus one: the length of
+// the character data, or the number of children.
+func nodeLength(n Node) int {
+	if isCharacterData(n) {
+		return dataLength(n.GetNodeValue())
+	}
+	if n.GetNodeType() == DocumentTypeNode {
+		return 0
+	}
+	return len(n.GetChildNodes())
+}
+
+// isReadOnly returns true if the Node is (in) an entity or entity reference, whose
+// contents can not be modified.
+func isReadOnly(n Node) bool {
+	for ; n != nil; n = n.GetParentNode() {
+		if n.GetNodeType() == EntityReferenceNode || n.GetNodeType() == EntityNode {
+			return true
+		}
+	}
+	return false
+}
+
+// boundaryPosition returns -1, 0 or 1 when the boundary point (nodeA, offsetA) is
+// respectively before, equal to, or after the boundary point (nodeB, offsetB). Both
+// boundary points must be in the same tree.
+func boundaryPosition(nodeA Node, offsetA int, nodeB Node, offsetB int) int {
+	if nodeA == nodeB {
+		switch {
+		case offsetA < offsetB:
+			return -1
+		case offsetA > offsetB:
+			return 1
+		}
+		return 0
+	}
+	if compareTreeOrder(nodeA, nodeB) > 0 {
+		return -boundaryPosition(nodeB, offsetB, nodeA, offsetA)
+	}
+	if isInclusiveAncestor(nodeA, nodeB) {
+		child := nodeB
+		for child.GetParentNode() != nodeA {
+			child = child.GetParentNode()
+		}
+		if indexOf(nodeA.GetChildNodes(), child) < offsetA {
+			return 1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,407 @@
+package dom
+
+import (
+	"testing"
+)
+
+var exampleDocRange = `<root><p id="1">Hello <b>bold</b> world</p><p id="2">Second <i>italic</i></p></root>`
+
+// mustCreateElement creates an element, for names which are known to be valid.
+func mustCreateElement(doc Document, name string) Element {
+	elem, err := doc.CreateElement(name)
+	if err != nil {
+		panic(err)
+	}
+	return elem
+}
+
+func TestRangeBoundaries(t *testing.T) {
+	doc := mustParse(t, exampleDocRange)
+	p := doc.GetElementsByTagName("p")
+	p1, p2 := p[0], p[1]
+	r := doc.CreateRange()
+	if r.GetStartContainer() != doc || r.GetEndContainer() != doc || !r.GetCollapsed() {
+		t.Error("expected a new range to be collapsed at the start of the document")
+	}
+
+	hello := p1.GetFirstChild()
+	if err := r.SetStart(hello, 2); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// The end was before the start, so the range is collapsed to the start.
+	if r.GetEndContainer() != hello || r.GetEndOffset() != 2 || !r.GetCollapsed() {
+		t.Errorf("expected a collapsed range, got %v", r)
+	}
+	if err := r.SetEnd(p2, 1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if r.GetCollapsed() || r.GetCommonAncestorContainer() != doc.GetDocumentElement() {
+		t.Errorf("unexpected common ancestor %v", r.GetCommonAncestorContainer())
+	}
+	if s, _ := r.ToString(); s != "llo bold worldSecond " {
+		t.Errorf("unexpected string '%v'", s)
+	}
+	// Setting the end before the start collapses the range to the end.
+	if err := r.SetEnd(p1, 0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if r.GetStartContainer() != p1 || !r.GetCollapsed() {
+		t.Errorf("expected a collapsed range, got %v", r)
+	}
+
+	if err := r.SetStart(hello, 7); err != ErrorIndexSize {
+		t.Errorf("expected %v, got %v", ErrorIndexSize, err)
+	}
+	if err := r.SetStart(p1, 4); err != ErrorIndexSize {
+		t.Errorf("expected %v, got %v", ErrorIndexSize, err)
+	}
+	if err := r.SetStart(p1.GetAttributes().GetNamedItem("id"), 0); err != ErrorInvalidNodeType {
+		t.Errorf("expected %v, got %v", ErrorInvalidNodeType, err)
+	}
+	if err := r.SetStart(mustCreateElement(NewDocument(), "x"), 0); err != ErrorWrongDocument {
+		t.Errorf("expected %v, got %v", ErrorWrongDocument, err)
+	}
+	if err := r.SetStartBefore(doc); err != ErrorInvalidNodeType {
+		t.Errorf("expected %v, got %v", ErrorInvalidNodeType, err)
+	}
+
+	if err := r.SelectNode(p2); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if r.GetStartOffset() != 1 || r.GetEndOffset() != 2 || r.GetStartContainer() != doc.GetDocumentElement() {
+		t.Errorf("unexpected range %v", r)
+	}
+	if err := r.SelectNodeContents(p1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if r.GetStartOffset() != 0 || r.GetEndOffset() != 3 || r.GetStartContainer() != p1 {
+		t.Errorf("unexpected range %v", r)
+	}
+	if err := r.SetEndBefore(p1.GetLastChild()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if s, _ := r.ToString(); s != "Hello bold" {
+		t.Errorf("unexpected string '%v'", s)
+	}
+
+	r.Detach()
+	if err := r.SetStart(p1, 0); err != ErrorInvalidState {
+		t.Errorf("expected %v, got %v", ErrorInvalidState, err)
+	}
+}
+
+func TestRangeCompareBoundaryPoints(t *testing.T) {
+	doc := mustParse(t, exampleDocRange)
+	p := doc.GetElementsByTagName("p")
+	p1, p2 := p[0], p[1]
+	r1 := doc.CreateRange()
+	r1.SelectNode(p1)
+	r2 := doc.CreateRange()
+	r2.SelectNodeContents(p1.GetFirstChild())
+
+	var tests = []struct {
+		how      CompareHow
+		expected int
+	}{
+		{StartToStart, -1},
+		{StartToEnd, 1},
+		{EndToEnd, 1},
+		{EndToStart, -1},
+	}
+	for _, test := range tests {
+		actual, err := r1.CompareBoundaryPoints(test.how, r2)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if actual != test.expected {
+			t.Errorf("%v: expected %d, got %d", test.how, test.expected, actual)
+		}
+	}
+
+	r2.SetStartBefore(p1)
+	if c, _ := r1.CompareBoundaryPoints(StartToStart, r2); c != 0 {
+		t.Errorf("expected 0, got %d", c)
+	}
+	r2.SelectNode(p2)
+	if c, _ := r1.CompareBoundaryPoints(EndToStart, r2); c != -1 {
+		t.Errorf("expected -1, got %d", c)
+	}
+
+	other := NewDocument().CreateRange()
+	if _, err := r1.CompareBoundaryPoints(StartToStart, other); err != ErrorWrongDocument {
+		t.Errorf("expected %v, got %v", ErrorWrongDocument, err)
+	}
+}
+
+func TestRangeContents(t *testing.T) {
+	var tests = []struct {
+		// The boundary points, as a path of child indices from the root element, and an offset.
+		start, end   []int
+		fragment     string // The fragment, which is the same for cloning and extracting.
+		afterExtract string
+	}{
+		// Within a single text node.
+		{[]int{0, 0, 1}, []int{0, 0, 4}, "ell", `<root><p id="1">Ho <b>bold</b> world</p><p id="2">Second <i>italic</i></p></root>`},
+		// Partially selected text nodes and elements.
+		{[]int{0, 0, 2}, []int{0, 1, 0, 2}, "llo <b>bo</b>", `<root><p id="1">He<b>ld</b> world</p><p id="2">Second <i>italic</i></p></root>`},
+		{[]int{0, 2, 3}, []int{1, 1, 0, 3}, `<p id="1">rld</p><p id="2">Second <i>ita</i></p>`, `<root><p id="1">Hello <b>bold</b> wo</p><p id="2"><i>lic</i></p></root>`},
+		// Completely selected children.
+		{[]int{0}, []int{2}, `<p id="1">Hello <b>bold</b> world</p><p id="2">Second <i>italic</i></p>`, `<root/>`},
+		{[]int{0, 1}, []int{1, 1}, `<p id="1"><b>bold</b> world</p><p id="2">Second </p>`, `<root><p id="1">Hello </p><p id="2"><i>italic</i></p></root>`},
+	}
+
+	// fragmentXML serializes the fragment as the content of an element, so it can be compared
+	// with the expected fragment, which is parsed that way.
+	fragmentXML := func(doc Document, fragment Node) string {
+		wrapper := mustCreateElement(doc, "fragment")
+		wrapper.AppendChild(fragment)
+		return serializeToString(wrapper)
+	}
+
+	// boundary resolves the path to a boundary point.
+	boundary := func(doc Document, path []int) (Node, int) {
+		var n Node = doc.GetDocumentElement()
+		for _, i := range path[:len(path)-1] {
+			n = n.GetChildNodes()[i]
+		}
+		return n, path[len(path)-1]
+	}
+
+	for _, test := range tests {
+		fragment := serializeToString(mustParse(t, "<fragment>"+test.fragment+"</fragment>"))
+		afterExtract := serializeToString(mustParse(t, test.afterExtract))
+		doc := mustParse(t, exampleDocRange)
+		unmodified := serializeToString(doc)
+		r := doc.CreateRange()
+		r.SetEnd(boundary(doc, test.end))
+		r.SetStart(boundary(doc, test.start))
+
+		clone, err := r.CloneContents()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if actual := fragmentXML(doc, clone); actual != fragment {
+			t.Errorf("clone: expected '%v', got '%v'", test.fragment, actual)
+		}
+		if actual := serializeToString(doc); actual != unmodified {
+			t.Errorf("clone: expected an unmodified document, got '%v'", actual)
+		}
+
+		extracted, err := r.ExtractContents()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if actual := fragmentXML(doc, extracted); actual != fragment {
+			t.Errorf("extract: expected '%v', got '%v'", test.fragment, actual)
+		}
+		if actual := serializeToString(doc); actual != afterExtract {
+			t.Errorf("extract: expected '%v', got '%v'", test.afterExtract, actual)
+		}
+		if !r.GetCollapsed() {
+			t.Errorf("expected a collapsed range after extracting, got %v", r)
+		}
+
+		// Deleting has the same effect on the document.
+		doc = mustParse(t, exampleDocRange)
+		r = doc.CreateRange()
+		r.SetEnd(boundary(doc, test.end))
+		r.SetStart(boundary(doc, test.start))
+		if err := r.DeleteContents(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if actual := serializeToString(doc); actual != afterExtract {
+			t.Errorf("delete: expected '%v', got '%v'", test.afterExtract, actual)
+		}
+	}
+}
+
+func TestRangeInsertNode(t *testing.T) {
+	doc := mustParse(t, exampleDocRange)
+	p := doc.GetElementsByTagName("p")
+	p1, p2 := p[0], p[1]
+	r := doc.CreateRange()
+
+	// Inserting in a text node splits it.
+	r.SetStart(p1.GetFirstChild(), 2)
+	if err := r.InsertNode(mustCreateElement(doc, "x")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := `<p id="1">He<x/>llo <b>bold</b> world</p>`
+	if actual := serializeToString(p1); actual != serializeToString(mustParse(t, expected).GetDocumentElement()) {
+		t.Errorf("expected '%v', got '%v'", expected, actual)
+	}
+	// The collapsed range now selects the inserted node.
+	if r.GetStartContainer() != p1.GetFirstChild() || r.GetEndContainer() != p1 || r.GetEndOffset() != 2 {
+		t.Errorf("unexpected range %v", r)
+	}
+
+	// Inserting a fragment inserts its children.
+	frag := doc.CreateDocumentFragment()
+	frag.AppendChild(doc.CreateText("a"))
+	frag.AppendChild(mustCreateElement(doc, "y"))
+	r.SetStart(p2, 1)
+	r.SetEnd(p2, 2)
+	if err := r.InsertNode(frag); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected = `<p id="2">Second a<y/><i>italic</i></p>`
+	if actual := serializeToString(p2); actual != serializeToString(mustParse(t, expected).GetDocumentElement()) {
+		t.Errorf("expected '%v', got '%v'", expected, actual)
+	}
+	if r.GetStartOffset() != 1 || r.GetEndOffset() != 4 {
+		t.Errorf("unexpected range %v", r)
+	}
+
+	r.SelectNodeContents(p1)
+	if err := r.InsertNode(p1); err != ErrorHierarchyRequest {
+		t.Errorf("expected %v, got %v", ErrorHierarchyRequest, err)
+	}
+	comment, _ := doc.CreateComment("comment")
+	r.SetStart(comment, 0)
+	if err := r.InsertNode(mustCreateElement(doc, "z")); err != ErrorHierarchyRequest {
+		t.Errorf("expected %v, got %v", ErrorHierarchyRequest, err)
+	}
+}
+
+func TestRangeSurroundContents(t *testing.T) {
+	doc := mustParse(t, exampleDocRange)
+	p1 := doc.GetElementsByTagName("p")[0]
+	r := doc.CreateRange()
+	r.SetStart(p1.GetFirstChild(), 1)
+	r.SetEnd(p1.GetLastChild(), 3)
+
+	wrapper := mustCreateElement(doc, "span")
+	wrapper.AppendChild(doc.CreateText("discarded"))
+	if err := r.SurroundContents(wrapper); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := `<p id="1">H<span>ello <b>bold</b> wo</span>rld</p>`
+	if actual := serializeToString(p1); actual != serializeToString(mustParse(t, expected).GetDocumentElement()) {
+		t.Errorf("expected '%v', got '%v'", expected, actual)
+	}
+	if r.GetStartContainer() != p1 || r.GetStartOffset() != 1 || r.GetEndOffset() != 2 {
+		t.Errorf("expected the range to select the new parent, got %v", r)
+	}
+
+	// An element which is partially selected can not be surrounded.
+	r.SetStart(p1.GetFirstChild(), 0)
+	r.SetEnd(wrapper.GetFirstChild(), 2)
+	if err := r.SurroundContents(mustCreateElement(doc, "x")); err != ErrorBadBoundaryPoints {
+		t.Errorf("expected %v, got %v", ErrorBadBoundaryPoints, err)
+	}
+	r.SelectNodeContents(p1.GetFirstChild())
+	if err := r.SurroundContents(doc.CreateDocumentFragment()); err != ErrorInvalidNodeType {
+		t.Errorf("expected %v, got %v", ErrorInvalidNodeType, err)
+	}
+}
+
+func TestRangeMutations(t *testing.T) {
+	doc := mustParse(t, exampleDocRange)
+	p := doc.GetElementsByTagName("p")
+	p1, p2 := p[0], p[1]
+	root := doc.GetDocumentElement()
+	r := doc.CreateRange()
+	r.SetStart(root, 1)
+	r.SetEnd(root, 2)
+
+	// Inserting before the range shifts the offsets.
+	root.InsertBefore(mustCreateElement(doc, "x"), p1)
+	if r.GetStartOffset() != 2 || r.GetEndOffset() != 3 {
+		t.Errorf("unexpected range after InsertBefore: %v", r)
+	}
+	// Appending after the range leaves it alone.
+	root.AppendChild(mustCreateElement(doc, "y"))
+	if r.GetStartOffset() != 2 || r.GetEndOffset() != 3 {
+		t.Errorf("unexpected range after AppendChild: %v", r)
+	}
+	// Removing before the range shifts the offsets back.
+	root.RemoveChild(root.GetFirstChild())
+	if r.GetStartOffset() != 1 || r.GetEndOffset() != 2 {
+		t.Errorf("unexpected range after RemoveChild: %v", r)
+	}
+	// Replacing the selected node removes it from the range, like RemoveChild.
+	z := mustCreateElement(doc, "z")
+	root.ReplaceChild(z, p2)
+	if r.GetStartOffset() != 1 || r.GetEndOffset() != 1 {
+		t.Errorf("unexpected range after ReplaceChild: %v", r)
+	}
+
+	// Removing a container moves the boundary point to its parent.
+	r.SetStart(p1.GetFirstChild(), 3)
+	r.SetEnd(z, 0)
+	root.RemoveChild(p1)
+	if r.GetStartContainer() != root || r.GetStartOffset() != 0 || r.GetEndContainer() != z {
+		t.Errorf("unexpected range after removing a container: %v", r)
+	}
+
+	// Changing character data resets the offsets in it.
+	text := doc.CreateText("some text")
+	z.AppendChild(text)
+	r.SetStart(text, 5)
+	r.SetEnd(text, 9)
+	text.SetText("other")
+	if r.GetStartOffset() != 0 || r.GetEndOffset() != 0 {
+		t.Errorf("unexpected range after SetText: %v", r)
+	}
+
+	// A detached range is no longer updated.
+	r.SetStart(root, 1)
+	r.Detach()
+	root.InsertBefore(mustCreateElement(doc, "w"), root.GetFirstChild())
+	if r.GetStartOffset() != 1 {
+		t.Errorf("expected a detached range to stay, got %v", r)
+	}
+}
+
+func TestRangeUTF16Offsets(t *testing.T) {
+	doc := NewDocument()
+	root := mustCreateElement(doc, "root")
+	doc.AppendChild(root)
+	// The emoji takes two UTF-16 code units.
+	text := doc.CreateText("a😀b")
+	root.AppendChild(text)
+
+	r := doc.CreateRange()
+	r.SelectNodeContents(text)
+	if r.GetEndOffset() != 4 {
+		t.Errorf("expected 4, got %d", r.GetEndOffset())
+	}
+	if err := r.SetStart(text, 2); err != ErrorIndexSize {
+		t.Errorf("expected %v, got %v", ErrorIndexSize, err)
+	}
+	r.SetStart(text, 1)
+	r.SetEnd(text, 3)
+	if s, _ := r.ToString(); s != "😀" {
+		t.Errorf("unexpected string '%v'", s)
+	}
+	r.DeleteContents()
+	if text.GetText() != "ab" {
+		t.Errorf("unexpected text '%v'", text.GetText())
+	}
+}
+
+func TestRangeDetach(t *testing.T) {
+	doc := mustParse(t, exampleDocRange)
+	dd := doc.(*domDocument)
+
+	// The document keeps every Range until it is detached, so it can update them on mutations.
+	var ranges []Range
+	for i := 0; i < 100; i++ {
+		r := doc.CreateRange()
+		clone, _ := r.CloneRange()
+		ranges = append(ranges, r, clone)
+	}
+	if len(dd.ranges) != 200 {
+		t.Errorf("expected 200 ranges, got %d", len(dd.ranges))
+	}
+	for _, r := range ranges {
+		r.Detach()
+		r.Detach()
+	}
+	if len(dd.ranges) != 0 {
+		t.Errorf("expected no ranges, got %d", len(dd.ranges))
+	}
+	if _, err := ranges[0].CloneRange(); err != ErrorInvalidState {
+		t.Errorf("expected %v, got %v", ErrorInvalidState, err)
+	}
+}
@@ -0,0 +1,502 @@
+package dom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file contains the CSS selectors used by QuerySelector and QuerySelectorAll. The
+// supported subset of https://www.w3.org/TR/selectors-3/ consists of:
+//
+//	type and universal selectors:  name, *, ns|name, *|name, |name, ns|*
+//	attribute selectors:           [a], [a=v], [a^=v], [a*=v], [ns|a=v]
+//	pseudo-classes:                :first-child, :nth-child(an+b), :not(...)
+//	combinators:                   descendant (whitespace), child (>), adjacent sibling (+)
+//	                               and general sibling (~)
+//	groups of selectors:           a, b
+//
+// Namespace prefixes are resolved using the Node on which the query is done. A type
+// selector without a prefix matches elements in any namespace, an attribute selector
+// without a prefix only matches attributes without a namespace.
+
+// selector is a single selector of a group: a sequence of compound selectors, separated
+// by combinators.
+type selector struct {
+	compounds   []*selectorCompound
+	combinators []byte // The combinator between compounds[i] and compounds[i+1].
+}
+
+// selectorCompound is a sequence of simple selectors which all must match the element,
+// like ns|name[attr]:first-child.
+type selectorCompound struct {
+	name       selectorName
+	attributes []selectorAttribute
+	nthChild   []selectorNth       // :first-child is :nth-child(1).
+	not        []*selectorCompound // The compounds which must not match.
+}
+
+// selectorName is the (qualified) name of a type or attribute selector.
+type selectorName struct {
+	local        string // The local name, or * for any.
+	namespaceURI string
+	anyNamespace bool
+}
+
+// selectorAttribute is an attribute selector. The operator is empty when only the
+// presence of the attribute is tested.
+type selectorAttribute struct {
+	name  selectorName
+	op    string
+	value string
+}
+
+// selectorNth is the an+b argument of :nth-child.
+type selectorNth struct {
+	a, b int
+}
+
+// querySelectorAll returns the descendant elements of the Node matching any of the
+// selectors, in document order.
+func querySelectorAll(n Node, selectors string) ([]Element, error) {
+	group, err := compileSelectors(selectors, n)
+	if err != nil {
+		return nil, err
+	}
+	return getElementsMatching(n, func(elem Element) bool {
+		for _, sel := range group {
+			if sel.matches(elem, len(sel.compounds)-1) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// querySelector returns the first descendant element of the Node matching any of the
+// selectors, or nil if there is none.
+func querySelector(n Node, selectors string) (Element, error) {
+	elements, err := querySelectorAll(n, selectors)
+	if err != nil || len(elements) == 0 {
+		return nil, err
+	}
+	return elements[0], nil
+}
+
+// matches returns true if the element matches the compound at index i, and the part of
+// the selector before it.
+func (sel *selector) matches(elem Element, i int) bool {
+	if !sel.compounds[i].matches(elem) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	switch sel.combinators[i-1] {
+	case ' ':
+		for parent := parentElement(elem); parent != nil; parent = parentElement(parent) {
+			if sel.matches(parent, i-1) {
+				return true
+			}
+		}
+	case '>':
+		parent := parentElement(elem)
+		return parent != nil && sel.matches(parent, i-1)
+	case '+':
+		siblings := precedingElementSiblings(elem)
+		return len(siblings) > 0 && sel.matches(siblings[0], i-1)
+	case '~':
+		for _, sibling := range precedingElementSiblings(elem) {
+			if sel.matches(sibling, i-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches returns true if the element matches every simple selector of the compound.
+func (c *selectorCompound) matches(elem Element) bool {
+	if !c.name.matches(elem) {
+		return false
+	}
+
+	for _, attr := range c.attributes {
+		if !attr.matches(elem) {
+			return false
+		}
+	}
+
+	if len(c.nthChild) > 0 {
+		position := len(precedingElementSiblings(elem)) + 1
+		for _, nth := range c.nthChild {
+			if !nth.matches(position) {
+				return false
+			}
+		}
+	}
+
+	for _, not := range c.not {
+		if not.matches(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches returns true if the name of the Node matches. Nodes without a namespace are
+// matched by their node name, since they may have a prefix in their name.
+func (name selectorName) matches(n Node) bool {
+	if !name.anyNamespace && n.GetNamespaceURI() != name.namespaceURI {
+		return false
+	}
+	if name.local == "*" {
+		return true
+	}
+	return n.GetLocalName() == name.local || n.GetNamespaceURI() == "" && n.GetNodeName() == name.local
+}
+
+// matches returns true if the element has a matching attribute.
+func (sa selectorAttribute) matches(elem Element) bool {
+	for _, attr := range sortedAttributes(elem) {
+		if !sa.name.matches(attr) {
+			continue
+		}
+		value := attr.GetNodeValue()
+		switch sa.op {
+		case "":
+			return true
+		case "=":
+			if value == sa.value {
+				return true
+			}
+		case "^=":
+			// An empty value never matches, for the substring operators.
+			if sa.value != "" && strings.HasPrefix(value, sa.value) {
+				return true
+			}
+		case "*=":
+			if sa.value != "" && strings.Contains(value, sa.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches returns true if the position is an+b for any n >= 0.
+func (nth selectorNth) matches(position int) bool {
+	if nth.a == 0 {
+		return position == nth.b
+	}
+	n := position - nth.b
+	return n/nth.a >= 0 && n%nth.a == 0
+}
+
+// parentElement returns the parent of the element if it is an Element. Like in XPath,
+// entity references are transparent.
+func parentElement(elem Element) Element {
+	parent, _ := xpathParent(elem).(Element)
+	return parent
+}
+
+// precedingElementSiblings returns the elements preceding the element, nearest first.
+func precedingElementSiblings(elem Element) []Element {
+	var siblings []Element
+	for _, n := range xpathPrecedingSiblings(elem) {
+		if sibling, ok := n.(Element); ok {
+			siblings = append(siblings, sibling)
+		}
+	}
+	return siblings
+}
+
+// selectorParser parses a group of selectors. Prefixes are resolved using the resolver.
+type selectorParser struct {
+	dtdScanner
+	resolver Node
+}
+
+// compileSelectors parses the group of comma separated selectors. Prefixes are resolved
+// using the given Node.
+func compileSelectors(selectors string, resolver Node) ([]*selector, error) {
+	p := &selectorParser{dtdScanner: dtdScanner{input: selectors}, resolver: resolver}
+
+	var group []*selector
+	for {
+		p.skipSpace()
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, sel)
+		if p.eof() {
+			return group, nil
+		}
+		if !p.consume(",") {
+			return nil, p.unexpected()
+		}
+	}
+}
+
+// unexpected returns an error for the character at the current position.
+func (p *selectorParser) unexpected() error {
+	if p.eof() {
+		return fmt.Errorf("%v: unexpected end of selector '%v'", ErrorSyntax, p.input)
+	}
+	return fmt.Errorf("%v: unexpected '%c' at offset %d in selector '%v'", ErrorSyntax, p.peek(), p.pos, p.input)
+}
+
+// selector parses compounds separated by combinators, until a comma or the end.
+func (p *selectorParser) selector() (*selector, error) {
+	sel := &selector{}
+	for {
+		compound, err := p.compound()
+		if err != nil {
+			return nil, err
+		}
+		sel.compounds = append(sel.compounds, compound)
+
+		space := p.skipSpace()
+		if p.eof() || p.peek() == ',' {
+			return sel, nil
+		}
+		combinator := byte(' ')
+		if c := p.peek(); c == '>' || c == '+' || c == '~' {
+			combinator = c
+			p.pos++
+			p.skipSpace()
+		} else if !space {
+			return nil, p.unexpected()
+		}
+		sel.combinators = append(sel.combinators, combinator)
+	}
+}
+
+// compound parses a type selector, followed by attribute selectors and pseudo-classes.
+// The type selector can be left out, which means *.
+func (p *selectorParser) compound() (*selectorCompound, error) {
+	c := &selectorCompound{name: selectorName{local: "*", anyNamespace: true}}
+	start := p.pos
+	if p.peek() != '[' && p.peek() != ':' {
+		var err error
+		if c.name, err = p.qualifiedName(true); err != nil {
+			return nil, err
+		}
+	}
+
+	for !p.eof() {
+		switch {
+		case p.consume("["):
+			attr, err := p.attribute()
+			if err != nil {
+				return nil, err
+			}
+			c.attributes = append(c.attributes, attr)
+		case p.consume(":"):
+			if err := p.pseudoClass(c); err != nil {
+				return nil, err
+			}
+		default:
+			if p.pos == start {
+				return nil, p.unexpected()
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return nil, p.unexpected()
+	}
+	return c, nil
+}
+
+// qualifiedName parses a name with an optional namespace prefix, like ns|name, *|name or
+// |name. The wildcard * is allowed as local name for type selectors only.
+func (p *selectorParser) qualifiedName(typeSelector bool) (selectorName, error) {
+	name := selectorName{anyNamespace: typeSelector}
+
+	first, err := p.nameOrWildcard()
+	if err != nil {
+		return name, err
+	}
+	// A single | is a namespace separator, but |= is an attribute operator.
+	if p.peek() == '|' && !strings.HasPrefix(p.input[p.pos:], "|=") {
+		p.pos++
+		switch first {
+		case "*":
+			name.anyNamespace = true
+		case "":
+			name.anyNamespace = false
+		default:
+			uri, ok := p.resolver.LookupNamespaceURI(first)
+			if first == "xml" {
+				uri, ok = XMLNamespaceURI, true
+			}
+			if !ok || uri == "" {
+				return name, fmt.Errorf("%v: the prefix '%v' can not be resolved", ErrorNamespace, first)
+			}
+			name.anyNamespace = false
+			name.namespaceURI = uri
+		}
+		if first, err = p.nameOrWildcard(); err != nil {
+			return name, err
+		}
+	}
+
+	if first == "" || first == "*" && !typeSelector {
+		return name, p.unexpected()
+	}
+	name.local = first
+	return name, nil
+}
+
+// nameOrWildcard parses an identifier or *. It returns an empty string if there is neither,
+// which is used to detect a selector like |name.
+func (p *selectorParser) nameOrWildcard() (string, error) {
+	if p.consume("*") {
+		return "*", nil
+	}
+	return p.identifier()
+}
+
+// identifier parses a CSS identifier. A backslash escapes the next character, so names
+// like a\.b can be used.
+func (p *selectorParser) identifier() (string, error) {
+	var b strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == '\\':
+			p.pos++
+			if p.eof() {
+				return "", p.unexpected()
+			}
+			b.WriteByte(p.peek())
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c >= 0x80:
+			b.WriteByte(c)
+		default:
+			return b.String(), nil
+		}
+		p.pos++
+	}
+	return b.String(), nil
+}
+
+// attribute parses an attribute selector, after the opening bracket.
+func (p *selectorParser) attribute() (selectorAttribute, error) {
+	var attr selectorAttribute
+	var err error
+
+	p.skipSpace()
+	if attr.name, err = p.qualifiedName(false); err != nil {
+		return attr, err
+	}
+	p.skipSpace()
+	if p.consume("]") {
+		return attr, nil
+	}
+
+	for _, op := range []string{"=", "^=", "*="} {
+		if p.consume(op) {
+			attr.op = op
+			break
+		}
+	}
+	if attr.op == "" {
+		return attr, p.unexpected()
+	}
+
+	p.skipSpace()
+	if c := p.peek(); c == '"' || c == '\'' {
+		if attr.value, err = p.quoted(); err != nil {
+			return attr, fmt.Errorf("%v: %v", ErrorSyntax, err)
+		}
+	} else if attr.value, err = p.identifier(); err != nil || attr.value == "" {
+		return attr, p.unexpected()
+	}
+	p.skipSpace()
+	if !p.consume("]") {
+		return attr, p.unexpected()
+	}
+	return attr, nil
+}
+
+// pseudoClass parses a pseudo-class after the colon, and adds it to the compound.
+func (p *selectorParser) pseudoClass(c *selectorCompound) error {
+	name, err := p.identifier()
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(name) {
+	case "first-child":
+		c.nthChild = append(c.nthChild, selectorNth{a: 0, b: 1})
+		return nil
+	case "nth-child":
+		start := p.pos
+		if !p.consume("(") || !p.skipUntil(")") {
+			return p.unexpected()
+		}
+		nth, err := parseNth(p.input[start+1 : p.pos-1])
+		if err != nil {
+			return err
+		}
+		c.nthChild = append(c.nthChild, nth)
+		return nil
+	case "not":
+		if !p.consume("(") {
+			return p.unexpected()
+		}
+		p.skipSpace()
+		not, err := p.compound()
+		if err != nil {
+			return err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return p.unexpected()
+		}
+		c.not = append(c.not, not)
+		return nil
+	}
+	return fmt.Errorf("%v: unsupported pseudo-class ':%v'", ErrorSyntax, name)
+}
+
+// parseNth parses the an+b argument of :nth-child, including the keywords odd and even.
+func parseNth(s string) (selectorNth, error) {
+	expr := strings.ToLower(strings.Join(strings.Fields(s), ""))
+	switch expr {
+	case "odd":
+		return selectorNth{a: 2, b: 1}, nil
+	case "even":
+		return selectorNth{a: 2, b: 0}, nil
+	}
+
+	var nth selectorNth
+	var err error
+	i := strings.IndexByte(expr, 'n')
+	if i < 0 {
+		nth.b, err = strconv.Atoi(expr)
+	} else {
+		switch a := expr[:i]; a {
+		case "", "+":
+			nth.a = 1
+		case "-":
+			nth.a = -1
+		default:
+			nth.a, err = strconv.Atoi(a)
+		}
+		// The b part must have a sign, like 2n+1.
+		if b := expr[i+1:]; err == nil && b != "" {
+			if b[0] != '+' && b[0] != '-' {
+				return nth, fmt.Errorf("%v: invalid argument '%v' for :nth-child", ErrorSyntax, s)
+			}
+			nth.b, err = strconv.Atoi(b)
+		}
+	}
+	if err != nil {
+		return nth, fmt.Errorf("%v: invalid argument '%v' for :nth-child", ErrorSyntax, s)
+	}
+	return nth, nil
+}
@@ -0,0 +1,183 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+var exampleDocSelectors = `<?xml version="1.0" encoding="UTF-8"?>
+<shop xmlns:x="urn:extra">
+	<section name="books" kind="paper">
+		<item id="i1" lang="en-GB">Go</item>
+		<item id="i2" lang="nl" x:note="rare">XML</item>
+		<item id="i3" lang="en">DOM</item>
+		<special id="i4"/>
+	</section>
+	<section name="music">
+		<item id="i5">Album</item>
+		<x:item id="i6"/>
+	</section>
+	<footer/>
+</shop>`
+
+// elementIDs returns the id attributes of the elements, or their names if they have none.
+func elementIDs(elements []Element) string {
+	var ids []string
+	for _, elem := range elements {
+		if id := elem.GetAttribute("id"); id != "" {
+			ids = append(ids, id)
+		} else {
+			ids = append(ids, elem.GetNodeName())
+		}
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestQuerySelectorAll(t *testing.T) {
+	doc := mustParse(t, exampleDocSelectors)
+	// The parser does not keep prefixed namespace declarations, so declare one to
+	// resolve the prefix in selectors.
+	doc.GetDocumentElement().SetAttribute("xmlns:x", "urn:extra")
+
+	var tests = []struct {
+		selectors string
+		expected  string
+	}{
+		{"item", "i1 i2 i3 i5 i6"},
+		{"*|item", "i1 i2 i3 i5 i6"},
+		{"x|item", "i6"},
+		{"|item", "i1 i2 i3 i5"},
+		{"x|*", "i6"},
+		{"shop > *", "section section footer"},
+		{"shop item", "i1 i2 i3 i5 i6"},
+		{"shop > item", ""},
+		{"section[name=books] > item", "i1 i2 i3"},
+		{"[kind]", "section"},
+		{"[id='i2']", "i2"},
+		{"[ id = \"i2\" ]", "i2"},
+		{"[lang^=en]", "i1 i3"},
+		{"[lang^='']", ""},
+		{"[lang*=-]", "i1"},
+		{"item[lang][id^=i]", "i1 i2 i3"},
+		{"[x|note]", "i2"},
+		{"[note]", ""},
+		{"[*|note=rare]", "i2"},
+		{"item:first-child", "i1 i5"},
+		{":first-child", "shop section i1 i5"},
+		{"item:nth-child(2)", "i2 i6"},
+		{"section > :nth-child(odd)", "i1 i3 i5"},
+		{"section > :nth-child(even)", "i2 i4 i6"},
+		{"section > :nth-child(2n+1)", "i1 i3 i5"},
+		{"section > :nth-child(-n + 2)", "i1 i2 i5 i6"},
+		{"section > :nth-child(n+3)", "i3 i4"},
+		{"item:not([lang])", "i5 i6"},
+		{"section > :not(item):not(x|item)", "i4"},
+		{"item:not(:first-child)", "i2 i3 i6"},
+		{"item + item", "i2 i3 i6"},
+		{"item + special", "i4"},
+		{"item ~ special", "i4"},
+		{"special ~ item", ""},
+		{"section + section *", "i5 i6"},
+		{"section ~ footer", "footer"},
+		{"footer, special, item[id=i1]", "i1 i4 footer"},
+		{"item, item", "i1 i2 i3 i5 i6"},
+		{"SECTION", ""},
+	}
+
+	for _, test := range tests {
+		elements, err := doc.QuerySelectorAll(test.selectors)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.selectors, err)
+			continue
+		}
+		if actual := elementIDs(elements); actual != test.expected {
+			t.Errorf("%v: expected '%v', got '%v'", test.selectors, test.expected, actual)
+		}
+	}
+}
+
+func TestQuerySelectorElement(t *testing.T) {
+	doc := mustParse(t, exampleDocSelectors)
+
+	section := doc.GetElementsByTagName("section")[1]
+	elements, err := section.QuerySelectorAll("item")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if ids := elementIDs(elements); ids != "i5 i6" {
+		t.Errorf("expected 'i5 i6', got '%v'", ids)
+	}
+
+	// Selectors match against the whole document, but only descendants are returned.
+	elements, _ = section.QuerySelectorAll("shop section + section > item")
+	if ids := elementIDs(elements); ids != "i5 i6" {
+		t.Errorf("expected 'i5 i6', got '%v'", ids)
+	}
+	elements, _ = section.QuerySelectorAll("section")
+	if len(elements) != 0 {
+		t.Errorf("expected no elements, got %v", elements)
+	}
+
+	first, err := doc.QuerySelector("item:nth-child(3), special")
+	if err != nil || first == nil || first.GetAttribute("id") != "i3" {
+		t.Errorf("expected i3, got '%v' (%v)", first, err)
+	}
+	none, err := section.QuerySelector("footer")
+	if err != nil || none != nil {
+		t.Errorf("expected nil, got '%v' (%v)", none, err)
+	}
+
+	// Elements in entity references are found too.
+	input := `<!DOCTYPE a [<!ENTITY e "<b><c/></b>">]><a>&e;</a>`
+	doc = mustParse(t, input)
+	elements, _ = doc.QuerySelectorAll("a > b > c:first-child")
+	if len(elements) != 1 {
+		t.Errorf("expected one element, got %v", elements)
+	}
+}
+
+func TestQuerySelectorErrors(t *testing.T) {
+	doc := NewDocument()
+	root, _ := doc.CreateElement("root")
+	doc.AppendChild(root)
+
+	var selectors = []string{
+		"",
+		"a,",
+		",a",
+		"a >",
+		"a > > b",
+		"[a",
+		"[a=]",
+		"[a~=b]",
+		"[*]",
+		"[a='b]",
+		"a:last-child",
+		"a:nth-child(x)",
+		"a:nth-child(2n1)",
+		"a:nth-child(2",
+		"a:not()",
+		"a:not(b",
+		"a.b",
+		"#a",
+		"a\\",
+	}
+	for _, selector := range selectors {
+		if _, err := doc.QuerySelectorAll(selector); err == nil {
+			t.Errorf("%v: expected error, got none", selector)
+		} else if !strings.HasPrefix(err.Error(), "SYNTAX_ERR") {
+			t.Errorf("%v: expected a syntax error, got '%v'", selector, err)
+		}
+	}
+
+	if _, err := root.QuerySelector("ns|a"); err == nil || !strings.HasPrefix(err.Error(), "NAMESPACE_ERR") {
+		t.Errorf("expected a namespace error, got '%v'", err)
+	}
+
+	// Escaped characters are part of the name.
+	child, _ := doc.CreateElement("a.b")
+	root.AppendChild(child)
+	if elem, err := doc.QuerySelector("a\\.b"); err != nil || elem != child {
+		t.Errorf("expected '%v', got '%v' (%v)", child, elem, err)
+	}
+}
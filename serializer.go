@@ -25,7 +25,9 @@ func (s *Serializer) nodeContainsTextOnly(n Node) bool {
 	}
 
 	for _, c := range n.GetChildNodes() {
-		if c.GetNodeType() != TextNode {
+		switch c.GetNodeType() {
+		case TextNode, CDATASectionNode, EntityReferenceNode:
+		default:
 			return false
 		}
 	}
@@ -35,11 +37,24 @@ func (s *Serializer) nodeContainsTextOnly(n Node) bool {
 // Serialize writes the node plus its children to the writer w. The Serializer does not do any
 // specific mutations on the given Node to serialize, i.e. it will write it as-is. No normalizations,
 // alterations etc are done.
+//
+// Content which can not be written as well-formed XML is reported to the ErrorHandler of the
+// Configuration. A CDATA section containing ]]> is split, with a warning. A comment containing
+// -- is an error, and a processing instruction containing ?> is a fatal error, after which
+// nothing is written anymore. Writing also stops when the handler returns false.
 func (s *Serializer) Serialize(node Node, w io.Writer) {
 	// Must define the function here so we can refer to ourselves in
 	// the traverse function.
 	var traverse func(n Node, indent string)
 
+	// Set when the serialization is stopped by a problem.
+	stopped := false
+	report := func(severity ErrorSeverity, errType string, n Node, message string) {
+		if !reportError(s.Configuration.ErrorHandler, newNodeError(severity, errType, n, "%v", message)) {
+			stopped = true
+		}
+	}
+
 	if !s.Configuration.OmitXMLDeclaration {
 		fmt.Fprintf(w, "%s", XMLDeclaration)
 		if s.Configuration.PrettyPrint {
@@ -48,6 +63,16 @@ func (s *Serializer) Serialize(node Node, w io.Writer) {
 	}
 
 	traverse = func(n Node, indent string) {
+		if stopped {
+			return
+		}
+		// Entity references are written as a reference. The children are the expansion
+		// of the entity, so these are not written.
+		if n.GetNodeType() == EntityReferenceNode {
+			fmt.Fprintf(w, "&%s;", n.GetNodeName())
+			return
+		}
+
 		switch t := n.(type) {
 		case Element:
 			// When pretty printing, indent the <element> string with the specified amount of indent chars.
@@ -68,8 +93,8 @@ func (s *Serializer) Serialize(node Node, w io.Writer) {
 
 			// Add any attributes
 			if t.GetAttributes() != nil {
-				for _, val := range t.GetAttributes().GetItems() {
-					attr := val.(Attr)
+				for i := 0; i < t.GetAttributes().Length(); i++ {
+					attr := t.GetAttributes().Item(i).(Attr)
 					fmt.Fprintf(w, " %s=\"%s\"", attr.GetNodeName(), attr.GetNodeValue())
 				}
 			}
@@ -87,29 +112,59 @@ func (s *Serializer) Serialize(node Node, w io.Writer) {
 			}
 
 		case Text:
-			// Contains only whitespaces? If so, write the text as-is.
-			if strings.TrimSpace(t.GetText()) == "" {
+			// CDATA sections are written as-is, with any ]]> in the content split over two sections.
+			if t.GetNodeType() == CDATASectionNode {
+				if strings.Contains(t.GetText(), "]]>") {
+					report(SeverityWarning, "cdata-sections-splitted", t, "the CDATA section is split at the ]]> marker")
+				}
+				fmt.Fprintf(w, "<![CDATA[%s]]>", splitCDATA(t.GetText()))
+			} else if strings.TrimSpace(t.GetText()) == "" {
+				// Contains only whitespaces? If so, write the text as-is.
 				fmt.Fprintf(w, "%s", t.GetText())
 			} else {
 				// Else escape any text where necessary.
 				fmt.Fprintf(w, "%s", escape(t.GetText()))
 			}
 		case Comment:
+			if strings.Contains(t.GetComment(), "--") {
+				report(SeverityError, "wf-invalid-character", t, "the comment contains '--'")
+				if stopped {
+					return
+				}
+			}
 			// When pretty printing, indent the comment with the indent level.
 			if s.Configuration.PrettyPrint {
 				fmt.Fprintf(w, "%s", indent)
 			}
 			fmt.Fprintf(w, "<!-- %s -->\n", t.GetComment())
+		case DocumentType:
+			fmt.Fprintf(w, "<!DOCTYPE %s", t.GetName())
+			if t.GetPublicID() != "" {
+				fmt.Fprintf(w, " PUBLIC \"%s\" \"%s\"", t.GetPublicID(), t.GetSystemID())
+			} else if t.GetSystemID() != "" {
+				fmt.Fprintf(w, " SYSTEM \"%s\"", t.GetSystemID())
+			}
+			if t.GetInternalSubset() != "" {
+				fmt.Fprintf(w, " [%s]", t.GetInternalSubset())
+			}
+			fmt.Fprintf(w, ">")
+			if s.Configuration.PrettyPrint {
+				fmt.Fprintln(w)
+			}
 		case ProcessingInstruction:
 			// TODO: proper serialization of target/data. Must include valid chars etc.
-			// Also, if target/data contains '?>', generate a fatal error.
+			if strings.Contains(t.GetData(), "?>") {
+				report(SeverityFatalError, "wf-invalid-character", t, "the processing instruction contains '?>'")
+				return
+			}
 			fmt.Fprintf(w, "<?%v %v?>", t.GetTarget(), t.GetData())
 		}
 
 		// For each child node, call traverse() again.
 		for _, node := range n.GetChildNodes() {
-			// Don't indent the first element when the first node is a DocumentNode.
-			if n.GetNodeType() == DocumentNode {
+			// Don't indent the first element when the first node is a DocumentNode or a
+			// DocumentFragmentNode. Neither of them are written themselves.
+			if n.GetNodeType() == DocumentNode || n.GetNodeType() == DocumentFragmentNode {
 				traverse(node, "")
 			} else {
 				// Serialize this child. Call traverse again with an increased indent character.
@@ -117,6 +172,9 @@ func (s *Serializer) Serialize(node Node, w io.Writer) {
 			}
 		}
 
+		if stopped {
+			return
+		}
 		// Check if and how we should write an element ending: </element>
 		switch t := n.(type) {
 		case Element:
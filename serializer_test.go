@@ -137,3 +137,67 @@ func TestSerializationAfterReading(t *testing.T) {
 
 	t.Logf(serializeToString(newdoc))
 }
+
+func TestSerializationCDATASections(t *testing.T) {
+	doc := NewDocument()
+	root, _ := doc.CreateElement("script")
+	doc.AppendChild(root)
+	root.AppendChild(doc.CreateCDATASection("if (a < b && c]]>d) {}"))
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<script><![CDATA[if (a < b && c]]]]><![CDATA[>d) {}]]></script>
+`
+	actual := serializeToString(doc)
+	if expected != actual {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, actual)
+	}
+
+	// The serialized CDATA section must parse back to the same content.
+	parsed := mustParse(t, actual)
+	if content := parsed.GetDocumentElement().GetTextContent(); content != "if (a < b && c]]>d) {}" {
+		t.Errorf("unexpected round-trip content '%v'", content)
+	}
+}
+
+func TestSerializationDoctype(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?><!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd"><html/>`
+	doc := mustParse(t, input)
+
+	w := &strings.Builder{}
+	NewSerializer().Serialize(doc, w)
+	if w.String() != input {
+		t.Errorf("Expected:\n%s\nActual:\n%s", input, w.String())
+	}
+
+	doc = NewDocument()
+	doc.AppendChild(newDocumentType(doc, "root", "", "root.dtd"))
+	root, _ := doc.CreateElement("root")
+	doc.AppendChild(root)
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE root SYSTEM "root.dtd">
+<root/>
+`
+	if actual := serializeToString(doc); actual != expected {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, actual)
+	}
+}
+
+func TestSerializationEntityReferences(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?><!DOCTYPE root [<!ENTITY e "<b>entity</b>">]><root>x &e; y</root>`
+	doc := mustParse(t, input)
+
+	w := &strings.Builder{}
+	NewSerializer().Serialize(doc, w)
+	if w.String() != input {
+		t.Errorf("Expected:\n%s\nActual:\n%s", input, w.String())
+	}
+
+	// Entity references are considered text when pretty printing.
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE root [<!ENTITY e "<b>entity</b>">]>
+<root>x &e; y</root>
+`
+	if actual := serializeToString(doc); actual != expected {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, actual)
+	}
+}
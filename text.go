@@ -6,19 +6,23 @@ import (
 )
 
 // domText. We don't 'inherit' from CharacterData, that's a bit too convoluted...
-// Maybe we'll implement that some other time.
+// Maybe we'll implement that some other time. The domCDATASection does embed the
+// domText though, since it only differs in its type and name.
 type domText struct {
 	localName     string
 	parentNode    Node
 	ownerDocument Document
+	state         nodeState // The state the DOM keeps for this node.
 
 	// Text specific things
 	data string
+	self Text // The node embedding this domText, or the domText itself.
 }
 
 func newText(owner Document) Text {
 	t := &domText{}
 	t.ownerDocument = owner
+	t.self = t
 	return t
 }
 
@@ -68,17 +72,17 @@ func (dt *domText) GetOwnerDocument() Document {
 }
 
 func (dt *domText) AppendChild(child Node) error {
-	return fmt.Errorf("%v: %v does not allow child nodes", ErrorHierarchyRequest, TextNode)
+	return fmt.Errorf("%v: %v does not allow child nodes", ErrorHierarchyRequest, dt.self.GetNodeType())
 }
 
 func (dt *domText) RemoveChild(oldChild Node) (Node, error) {
-	return nil, fmt.Errorf("%v: %v does not allow child nodes - nothing to remove", ErrorHierarchyRequest, TextNode)
+	return nil, fmt.Errorf("%v: %v does not allow child nodes - nothing to remove", ErrorHierarchyRequest, dt.self.GetNodeType())
 }
 func (dt *domText) ReplaceChild(newChild, oldChild Node) (Node, error) {
-	return nil, fmt.Errorf("%v: %v does not allow child nodes - nothing to replace", ErrorHierarchyRequest, TextNode)
+	return nil, fmt.Errorf("%v: %v does not allow child nodes - nothing to replace", ErrorHierarchyRequest, dt.self.GetNodeType())
 }
 func (dt *domText) InsertBefore(newChild, refChild Node) (Node, error) {
-	return nil, fmt.Errorf("%v: %v does not allow child nodes - nothing to insert", ErrorHierarchyRequest, TextNode)
+	return nil, fmt.Errorf("%v: %v does not allow child nodes - nothing to insert", ErrorHierarchyRequest, dt.self.GetNodeType())
 }
 
 func (dt *domText) HasChildNodes() bool {
@@ -86,11 +90,11 @@ func (dt *domText) HasChildNodes() bool {
 }
 
 func (dt *domText) GetPreviousSibling() Node {
-	return getPreviousSibling(dt)
+	return getPreviousSibling(dt.self)
 }
 
 func (dt *domText) GetNextSibling() Node {
-	return getNextSibling(dt)
+	return getNextSibling(dt.self)
 }
 
 // GetNamespaceURI returns an empty string for Text nodes.
@@ -125,14 +129,55 @@ func (dt *domText) SetTextContent(content string) {
 }
 
 func (dt *domText) CloneNode(deep bool) Node {
-	cloneText := dt.ownerDocument.CreateText(dt.data)
+	return dt.self.cloneNode(dt.ownerDocument, deep, NodeCloned)
+}
+
+func (dt *domText) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	cloneText := owner.CreateText(dt.data)
+	notifyUserDataHandlers(operation, dt.self, cloneText)
 	return cloneText
 }
 
+func (dt *domText) Normalize() {
+	normalize(dt.self)
+}
+
+func (dt *domText) IsSameNode(other Node) bool {
+	return isSameNode(dt.self, other)
+}
+
+func (dt *domText) IsEqualNode(other Node) bool {
+	return isEqualNode(dt.self, other)
+}
+
+func (dt *domText) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(dt.self, other)
+}
+
+func (dt *domText) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(dt.self, key, data, handler)
+}
+
+func (dt *domText) GetUserData(key string) interface{} {
+	return getUserData(dt.self, key)
+}
+
 func (dt *domText) ImportNode(n Node, deep bool) Node {
 	return importNode(dt.ownerDocument, n, deep)
 }
 
+func (dt *domText) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(dt.self, eventType, listener, useCapture)
+}
+
+func (dt *domText) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(dt.self, eventType, listener, useCapture)
+}
+
+func (dt *domText) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(dt.self, evt)
+}
+
 // Private functions:
 func (dt *domText) setParentNode(parent Node) {
 	dt.parentNode = parent
@@ -142,6 +187,10 @@ func (dt *domText) setOwnerDocument(doc Document) {
 	dt.ownerDocument = doc
 }
 
+func (dt *domText) getState() *nodeState {
+	return &dt.state
+}
+
 // Text specifics:
 
 // GetText returns the character data of this text node, unescaped.
@@ -152,7 +201,65 @@ func (dt *domText) GetText() string {
 // SetText sets the character data of the XML node. The data can be unescaped
 // XML, since GetText() will take care of conversion.
 func (dt *domText) SetText(data string) {
-	dt.data = data
+	replaceData(dt.self, 0, dataLength(dt.data), data)
+}
+
+// GetLength returns the length of the character data in UTF-16 code units.
+func (dt *domText) GetLength() int {
+	return dataLength(dt.data)
+}
+
+// SubstringData returns count UTF-16 code units of the character data, starting at offset.
+func (dt *domText) SubstringData(offset, count int) (string, error) {
+	return getSubstringData(dt.self, offset, count)
+}
+
+// AppendData appends the string to the end of the character data.
+func (dt *domText) AppendData(arg string) error {
+	return modifyData(dt.self, dataLength(dt.data), 0, arg)
+}
+
+// InsertData inserts the string into the character data at the offset in UTF-16 code units.
+func (dt *domText) InsertData(offset int, arg string) error {
+	return modifyData(dt.self, offset, 0, arg)
+}
+
+// DeleteData removes count UTF-16 code units from the character data, starting at offset.
+func (dt *domText) DeleteData(offset, count int) error {
+	return modifyData(dt.self, offset, count, "")
+}
+
+// ReplaceData replaces count UTF-16 code units of the character data, starting at offset, with
+// the string.
+func (dt *domText) ReplaceData(offset, count int, arg string) error {
+	return modifyData(dt.self, offset, count, arg)
+}
+
+// SplitText breaks the Text node in two at the offset, keeping both nodes in the tree.
+func (dt *domText) SplitText(offset int) (Text, error) {
+	if isReadOnly(dt.self) {
+		return nil, ErrorNoModificationAllowed
+	}
+	newNode, err := splitText(dt.self, offset)
+	if err != nil {
+		return nil, err
+	}
+	return newNode.(Text), nil
+}
+
+// GetWholeText returns the data of the Text node, and of the text nodes logically adjacent to it.
+func (dt *domText) GetWholeText() string {
+	return wholeText(dt.self)
+}
+
+// ReplaceWholeText replaces the data of the Text node and of the text nodes logically adjacent
+// to it with the content.
+func (dt *domText) ReplaceWholeText(content string) (Text, error) {
+	n, err := replaceWholeText(dt.self, content)
+	if n == nil {
+		return nil, err
+	}
+	return n.(Text), err
 }
 
 // IsElementContentWhitespace returns true when the Text node contains ignorable
@@ -169,5 +276,5 @@ func (dt *domText) String() string {
 	} else {
 		d = strings.TrimSpace(dt.GetText())
 	}
-	return fmt.Sprintf("%s: '%s'", dt.GetNodeType(), d)
+	return fmt.Sprintf("%s: '%s'", dt.self.GetNodeType(), d)
 }
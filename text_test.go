@@ -2,6 +2,7 @@ package dom
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -127,3 +128,210 @@ func TestTextIsIgnorableWhitespace(t *testing.T) {
 		}
 	}
 }
+
+func TestTextCharacterData(t *testing.T) {
+	doc := NewDocument()
+	cdata := doc.CreateCDATASection("")
+	comment, _ := doc.CreateComment("")
+
+	for _, cd := range []CharacterData{doc.CreateText(""), cdata, comment} {
+		// The emoji is a single rune, but two UTF-16 code units.
+		var steps = []struct {
+			op       func() error
+			expected string
+		}{
+			{func() error { return cd.AppendData("héllo") }, "héllo"},
+			{func() error { return cd.AppendData(" 😀!") }, "héllo 😀!"},
+			{func() error { return cd.InsertData(0, ">") }, ">héllo 😀!"},
+			{func() error { return cd.InsertData(9, "x") }, ">héllo 😀x!"},
+			{func() error { return cd.DeleteData(0, 1) }, "héllo 😀x!"},
+			{func() error { return cd.ReplaceData(1, 4, "ey") }, "hey 😀x!"},
+			{func() error { return cd.DeleteData(4, 100) }, "hey "},
+		}
+		for _, step := range steps {
+			if err := step.op(); err != nil {
+				t.Errorf("%v: unexpected error: %v", cd.GetNodeType(), err)
+			}
+			if cd.GetNodeValue() != step.expected {
+				t.Errorf("%v: expected '%v', got '%v'", cd.GetNodeType(), step.expected, cd.GetNodeValue())
+			}
+		}
+
+		cd.AppendData("😀😀")
+		if cd.GetLength() != 8 {
+			t.Errorf("%v: expected a length of 8, got %v", cd.GetNodeType(), cd.GetLength())
+		}
+		if s, err := cd.SubstringData(4, 2); s != "😀" || err != nil {
+			t.Errorf("%v: unexpected result '%v', %v", cd.GetNodeType(), s, err)
+		}
+		if s, err := cd.SubstringData(6, 10); s != "😀" || err != nil {
+			t.Errorf("%v: unexpected result '%v', %v", cd.GetNodeType(), s, err)
+		}
+
+		if _, err := cd.SubstringData(9, 0); err != ErrorIndexSize {
+			t.Errorf("%v: expected %v, got %v", cd.GetNodeType(), ErrorIndexSize, err)
+		}
+		if err := cd.InsertData(-1, "x"); err != ErrorIndexSize {
+			t.Errorf("%v: expected %v, got %v", cd.GetNodeType(), ErrorIndexSize, err)
+		}
+		if err := cd.DeleteData(0, -1); err != ErrorIndexSize {
+			t.Errorf("%v: expected %v, got %v", cd.GetNodeType(), ErrorIndexSize, err)
+		}
+		if err := cd.ReplaceData(9, 0, "x"); err != ErrorIndexSize {
+			t.Errorf("%v: expected %v, got %v", cd.GetNodeType(), ErrorIndexSize, err)
+		}
+	}
+}
+
+func TestTextSurrogatePairs(t *testing.T) {
+	doc := NewDocument()
+	comment, _ := doc.CreateComment("")
+
+	for _, cd := range []CharacterData{doc.CreateText(""), doc.CreateCDATASection(""), comment} {
+		// Offset 2 is between the two UTF-16 code units of the emoji.
+		cd.AppendData("a😀b")
+		var errs = []struct {
+			name string
+			err  error
+		}{
+			{"InsertData(2)", cd.InsertData(2, "X")},
+			{"DeleteData(1, 1)", cd.DeleteData(1, 1)},
+			{"DeleteData(2, 1)", cd.DeleteData(2, 1)},
+			{"ReplaceData(0, 2)", cd.ReplaceData(0, 2, "X")},
+		}
+		for _, e := range errs {
+			if e.err != ErrorIndexSize {
+				t.Errorf("%v: %v: expected %v, got %v", cd.GetNodeType(), e.name, ErrorIndexSize, e.err)
+			}
+		}
+		if _, err := cd.SubstringData(2, 1); err != ErrorIndexSize {
+			t.Errorf("%v: expected %v, got %v", cd.GetNodeType(), ErrorIndexSize, err)
+		}
+		if _, err := cd.SubstringData(0, 2); err != ErrorIndexSize {
+			t.Errorf("%v: expected %v, got %v", cd.GetNodeType(), ErrorIndexSize, err)
+		}
+		if cd.GetNodeValue() != "a😀b" {
+			t.Errorf("%v: data was modified to '%v'", cd.GetNodeType(), cd.GetNodeValue())
+		}
+
+		// Offsets around the pair are fine.
+		if err := cd.InsertData(3, "X"); err != nil || cd.GetNodeValue() != "a😀Xb" {
+			t.Errorf("%v: unexpected result '%v', %v", cd.GetNodeType(), cd.GetNodeValue(), err)
+		}
+		if s, err := cd.SubstringData(1, 2); s != "😀" || err != nil {
+			t.Errorf("%v: unexpected result '%v', %v", cd.GetNodeType(), s, err)
+		}
+	}
+}
+
+func TestTextSplitText(t *testing.T) {
+	doc := mustParse(t, `<p>find the hit here</p>`)
+	p := doc.GetDocumentElement()
+	text := p.GetFirstChild().(Text)
+
+	// Wrap "hit" in an <em> element.
+	hit, err := text.SplitText(9)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	rest, _ := hit.SplitText(3)
+	em := mustCreateElement(doc, "em")
+	p.ReplaceChild(em, hit)
+	em.AppendChild(hit)
+
+	expected := `<p>find the <em>hit</em> here</p>`
+	if actual := serializeToString(p); actual != serializeToString(mustParse(t, expected).GetDocumentElement()) {
+		t.Errorf("expected '%v', got '%v'", expected, actual)
+	}
+	if rest.GetNodeType() != TextNode || rest.GetPreviousSibling() != em {
+		t.Errorf("unexpected node %v", rest)
+	}
+
+	if _, err := text.SplitText(100); err != ErrorIndexSize {
+		t.Errorf("expected %v, got %v", ErrorIndexSize, err)
+	}
+
+	// The offset may not split a surrogate pair, and the tree is left alone when it does.
+	emoji := doc.CreateText("a😀b")
+	em.AppendChild(emoji)
+	if _, err := emoji.SplitText(2); err != ErrorIndexSize {
+		t.Errorf("expected %v, got %v", ErrorIndexSize, err)
+	}
+	if emoji.GetNodeValue() != "a😀b" || emoji.GetNextSibling() != nil {
+		t.Errorf("unexpected text '%v' or sibling %v", emoji.GetNodeValue(), emoji.GetNextSibling())
+	}
+	if second, err := emoji.SplitText(3); err != nil || second.GetNodeValue() != "b" || emoji.GetNodeValue() != "a😀" {
+		t.Errorf("unexpected result %v, %v", second, err)
+	}
+
+	// A detached node can be split as well, and a CDATA section splits into a CDATA section.
+	cdata := doc.CreateCDATASection("abcd")
+	second, err := cdata.SplitText(1)
+	if err != nil || second.GetNodeType() != CDATASectionNode || second.GetNodeValue() != "bcd" || cdata.GetNodeValue() != "a" {
+		t.Errorf("unexpected result %v, %v", second, err)
+	}
+}
+
+func TestTextWholeText(t *testing.T) {
+	var tests = []struct {
+		xml      string
+		index    int    // The index of the Text child of the document element to start at.
+		expected string // The whole text.
+		replaced string // The document after replacing the whole text with "new".
+		err      bool
+	}{
+		{`<r>a<![CDATA[b]]>c<x/>d</r>`, 0, "abc", `<r>new<x/>d</r>`, false},
+		{`<r>a<![CDATA[b]]>c<x/>d</r>`, 4, "d", `<r>a<![CDATA[b]]>c<x/>new</r>`, false},
+		{`<!DOCTYPE r [<!ENTITY e "ent">]><r>a&e;b</r>`, 2, "aentb", `<r>new</r>`, false},
+		{`<!DOCTYPE r [<!ENTITY e "ent<x/>">]><r>a&e;b</r>`, 0, "aent", "", true},
+		{`<!DOCTYPE r [<!ENTITY e "ent<x/>">]><r>a&e;b</r>`, 2, "b", `<!DOCTYPE r [<!ENTITY e "ent<x/>">]><r>a&e;new</r>`, false},
+	}
+
+	for _, test := range tests {
+		doc, err := NewParser(strings.NewReader(test.xml)).Parse()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		root := doc.GetDocumentElement()
+		text := root.GetChildNodes()[test.index].(Text)
+		if actual := text.GetWholeText(); actual != test.expected {
+			t.Errorf("%v: expected '%v', got '%v'", test.xml, test.expected, actual)
+		}
+
+		replaced, err := text.ReplaceWholeText("new")
+		if test.err {
+			if err == nil {
+				t.Errorf("%v: expected an error", test.xml)
+			}
+			continue
+		}
+		if err != nil || replaced != text {
+			t.Errorf("%v: unexpected result %v, %v", test.xml, replaced, err)
+		}
+		if actual := serializeToString(root); actual != serializeToString(mustParse(t, test.replaced).GetDocumentElement()) {
+			t.Errorf("%v: expected '%v', got '%v'", test.xml, test.replaced, actual)
+		}
+	}
+
+	// The whole text is removed when replaced by an empty string.
+	doc := mustParse(t, `<r>a<![CDATA[b]]><x/></r>`)
+	root := doc.GetDocumentElement()
+	if replaced, err := root.GetFirstChild().(Text).ReplaceWholeText(""); replaced != nil || err != nil {
+		t.Errorf("unexpected result %v, %v", replaced, err)
+	}
+	if actual := serializeToString(root); actual != serializeToString(mustParse(t, `<r><x/></r>`).GetDocumentElement()) {
+		t.Errorf("expected '<r><x/></r>', got '%v'", actual)
+	}
+
+	// Text in the replacement text of an entity is read only.
+	doc = mustParse(t, `<!DOCTYPE r [<!ENTITY e "ent">]><r>&e;</r>`)
+	inEntity := doc.GetDocumentElement().GetFirstChild().GetFirstChild().(Text)
+	if inEntity.GetWholeText() != "ent" {
+		t.Errorf("expected 'ent', got '%v'", inEntity.GetWholeText())
+	}
+	if _, err := inEntity.ReplaceWholeText("x"); err != ErrorNoModificationAllowed {
+		t.Errorf("expected %v, got %v", ErrorNoModificationAllowed, err)
+	}
+}
@@ -0,0 +1,520 @@
+package dom
+
+import (
+	"fmt"
+)
+
+// This file contains the DOM Level 2 Traversal module, which provides the NodeIterator
+// and TreeWalker to traverse a (sub)tree without writing a recursive function for it.
+// See https://www.w3.org/TR/DOM-Level-2-Traversal-Range/traversal.html
+
+// WhatToShow is a bitmask of the node types which are shown by a NodeIterator or TreeWalker.
+// Nodes of other types are skipped, as if a NodeFilter returned FilterSkip for them.
+type WhatToShow uint32
+
+// The flags of WhatToShow, one for each NodeType. Use ShowNodeType to get the flag of a
+// NodeType, and combine flags with the | operator.
+const (
+	ShowElement               WhatToShow = 1 << ElementNode
+	ShowAttribute             WhatToShow = 1 << AttributeNode
+	ShowText                  WhatToShow = 1 << TextNode
+	ShowCDATASection          WhatToShow = 1 << CDATASectionNode
+	ShowEntityReference       WhatToShow = 1 << EntityReferenceNode
+	ShowEntity                WhatToShow = 1 << EntityNode
+	ShowProcessingInstruction WhatToShow = 1 << ProcessingInstructionNode
+	ShowComment               WhatToShow = 1 << CommentNode
+	ShowDocument              WhatToShow = 1 << DocumentNode
+	ShowDocumentType          WhatToShow = 1 << DocumentTypeNode
+	ShowDocumentFragment      WhatToShow = 1 << DocumentFragmentNode
+	ShowAll                   WhatToShow = 0xFFFFFFFF
+)
+
+// ShowNodeType returns the WhatToShow flag of the given NodeType.
+func ShowNodeType(t NodeType) WhatToShow {
+	return 1 << t
+}
+
+// Shows returns true if nodes of the given NodeType are shown.
+func (w WhatToShow) Shows(t NodeType) bool {
+	return w&ShowNodeType(t) != 0
+}
+
+// FilterResult is the result of a NodeFilter.
+type FilterResult uint8
+
+// Enumeration of the results of a NodeFilter.
+const (
+	// FilterAccept accepts the node, so it is returned by the traversal.
+	FilterAccept FilterResult = iota + 1
+	// FilterReject rejects the node. A TreeWalker skips the children of the node as
+	// well, a NodeIterator treats it like FilterSkip.
+	FilterReject
+	// FilterSkip skips the node, but its children are still considered.
+	FilterSkip
+)
+
+// String returns the string representation of the FilterResult, using the default
+// representation by the W3 specification.
+func (r FilterResult) String() string {
+	switch r {
+	case FilterAccept:
+		return "FILTER_ACCEPT"
+	case FilterReject:
+		return "FILTER_REJECT"
+	case FilterSkip:
+		return "FILTER_SKIP"
+	default:
+		return "???"
+	}
+}
+
+// NodeFilter decides whether a node is visible to a NodeIterator or TreeWalker. It is
+// only called for nodes which are shown according to the WhatToShow flags.
+type NodeFilter interface {
+	AcceptNode(n Node) FilterResult
+}
+
+// NodeFilterFunc is an adapter to use ordinary functions as a NodeFilter.
+type NodeFilterFunc func(n Node) FilterResult
+
+// AcceptNode calls f(n).
+func (f NodeFilterFunc) AcceptNode(n Node) FilterResult {
+	return f(n)
+}
+
+// NodeIterator iterates over the nodes of a subtree in document order, as a flat list.
+// The iterator stays valid when nodes are removed from the tree during iteration, as long
+// as it is not detached. Because of that, the owner document of the root keeps a reference
+// to every iterator until Detach is called, and updates it on every removal. Always detach
+// an iterator when done with it, or it is never garbage collected.
+type NodeIterator interface {
+	GetRoot() Node                      // Returns the root node of the iterator.
+	GetWhatToShow() WhatToShow          // Returns the node types which are shown.
+	GetFilter() NodeFilter              // Returns the filter, which may be nil.
+	GetExpandEntityReferences() bool    // Returns true if the children of entity references are visible.
+	GetReferenceNode() Node             // Returns the node the iterator is positioned at.
+	IsPointerBeforeReferenceNode() bool // Returns true if the iterator is positioned before the reference node.
+	NextNode() (Node, error)            // Returns the next node, or nil if there are no more.
+	PreviousNode() (Node, error)        // Returns the previous node, or nil if there are no more.
+	Detach()                            // Releases the iterator. Using it afterwards results in an ErrorInvalidState.
+}
+
+// TreeWalker navigates a subtree using a logical view of the tree, which only contains
+// the nodes which are shown and accepted. The navigation methods return nil when there
+// is no such node, in which case the current node does not change.
+type TreeWalker interface {
+	GetRoot() Node                   // Returns the root node of the walker.
+	GetWhatToShow() WhatToShow       // Returns the node types which are shown.
+	GetFilter() NodeFilter           // Returns the filter, which may be nil.
+	GetExpandEntityReferences() bool // Returns true if the children of entity references are visible.
+	GetCurrentNode() Node            // Returns the node the walker is positioned at.
+	SetCurrentNode(n Node) error     // Positions the walker at the node, which may be any node.
+
+	ParentNode() Node      // Moves to the closest visible ancestor.
+	FirstChild() Node      // Moves to the first visible child.
+	LastChild() Node       // Moves to the last visible child.
+	PreviousSibling() Node // Moves to the previous visible sibling.
+	NextSibling() Node     // Moves to the next visible sibling.
+	PreviousNode() Node    // Moves to the previous visible node in document order.
+	NextNode() Node        // Moves to the next visible node in document order.
+}
+
+// traversal contains what is shared between the NodeIterator and TreeWalker.
+type traversal struct {
+	root       Node
+	whatToShow WhatToShow
+	filter     NodeFilter
+	expand     bool // Expand entity references.
+}
+
+func (t *traversal) GetRoot() Node {
+	return t.root
+}
+
+func (t *traversal) GetWhatToShow() WhatToShow {
+	return t.whatToShow
+}
+
+func (t *traversal) GetFilter() NodeFilter {
+	return t.filter
+}
+
+func (t *traversal) GetExpandEntityReferences() bool {
+	return t.expand
+}
+
+// acceptNode checks the node against the WhatToShow flags, and then the filter.
+func (t *traversal) acceptNode(n Node) FilterResult {
+	if !t.whatToShow.Shows(n.GetNodeType()) {
+		return FilterSkip
+	}
+	if t.filter == nil {
+		return FilterAccept
+	}
+	return t.filter.AcceptNode(n)
+}
+
+// children returns the children of the node, which are none for entity references
+// when they are not expanded.
+func (t *traversal) children(n Node) []Node {
+	if !t.expand && n.GetNodeType() == EntityReferenceNode {
+		return nil
+	}
+	return n.GetChildNodes()
+}
+
+func (t *traversal) firstChild(n Node) Node {
+	if children := t.children(n); len(children) > 0 {
+		return children[0]
+	}
+	return nil
+}
+
+func (t *traversal) lastChild(n Node) Node {
+	if children := t.children(n); len(children) > 0 {
+		return children[len(children)-1]
+	}
+	return nil
+}
+
+// following returns the node following n in document order, within the root.
+func (t *traversal) following(n Node) Node {
+	if child := t.firstChild(n); child != nil {
+		return child
+	}
+	for ; n != nil && n != t.root; n = n.GetParentNode() {
+		if sibling := n.GetNextSibling(); sibling != nil {
+			return sibling
+		}
+	}
+	return nil
+}
+
+// preceding returns the node preceding n in document order, within the root.
+func (t *traversal) preceding(n Node) Node {
+	if n == t.root {
+		return nil
+	}
+	sibling := n.GetPreviousSibling()
+	if sibling == nil {
+		return n.GetParentNode()
+	}
+	for child := t.lastChild(sibling); child != nil; child = t.lastChild(sibling) {
+		sibling = child
+	}
+	return sibling
+}
+
+// domNodeIterator implements the NodeIterator. The owner document keeps a reference to
+// every iterator which is not detached, to update it when nodes are removed.
+type domNodeIterator struct {
+	traversal
+	document      *domDocument
+	referenceNode Node
+	beforeNode    bool // Pointer before the reference node.
+	detached      bool
+}
+
+func (it *domNodeIterator) GetReferenceNode() Node {
+	return it.referenceNode
+}
+
+func (it *domNodeIterator) IsPointerBeforeReferenceNode() bool {
+	return it.beforeNode
+}
+
+func (it *domNodeIterator) NextNode() (Node, error) {
+	return it.traverse(true)
+}
+
+func (it *domNodeIterator) PreviousNode() (Node, error) {
+	return it.traverse(false)
+}
+
+// traverse moves the iterator forward or backward until an accepted node is found.
+func (it *domNodeIterator) traverse(next bool) (Node, error) {
+	if it.detached {
+		return nil, fmt.Errorf("%v: the NodeIterator is detached", ErrorInvalidState)
+	}
+
+	node := it.referenceNode
+	beforeNode := it.beforeNode
+	for {
+		if next {
+			if !beforeNode {
+				if node = it.following(node); node == nil {
+					return nil, nil
+				}
+			}
+			beforeNode = false
+		} else {
+			if beforeNode {
+				if node = it.preceding(node); node == nil {
+					return nil, nil
+				}
+			}
+			beforeNode = true
+		}
+
+		if it.acceptNode(node) == FilterAccept {
+			break
+		}
+	}
+
+	it.referenceNode = node
+	it.beforeNode = beforeNode
+	return node, nil
+}
+
+// Detach releases the iterator from its document, so it is not updated anymore.
+func (it *domNodeIterator) Detach() {
+	if it.detached {
+		return
+	}
+	it.detached = true
+	if it.document != nil {
+		it.document.removeIterator(it)
+	}
+}
+
+// nodeRemoving moves the reference node out of the subtree of the node which is about
+// to be removed, if necessary, so the iterator remains valid.
+func (it *domNodeIterator) nodeRemoving(n Node) {
+	if n == it.root || !isInclusiveAncestor(n, it.referenceNode) || !isInclusiveAncestor(it.root, n) {
+		return
+	}
+
+	if it.beforeNode {
+		// Move to the first node following the removed subtree.
+		var next Node
+		for x := n; x != nil && x != it.root && next == nil; x = x.GetParentNode() {
+			next = x.GetNextSibling()
+		}
+		if next != nil {
+			it.referenceNode = next
+			return
+		}
+		it.beforeNode = false
+	}
+
+	// Move to the last node preceding the removed subtree.
+	if sibling := n.GetPreviousSibling(); sibling != nil {
+		for child := it.lastChild(sibling); child != nil; child = it.lastChild(sibling) {
+			sibling = child
+		}
+		it.referenceNode = sibling
+	} else {
+		it.referenceNode = n.GetParentNode()
+	}
+}
+
+func (it *domNodeIterator) String() string {
+	return fmt.Sprintf("NodeIterator: %v", it.referenceNode)
+}
+
+// isInclusiveAncestor returns true if the Node a is the Node n, or one of its ancestors.
+func isInclusiveAncestor(a, n Node) bool {
+	for ; n != nil; n = n.GetParentNode() {
+		if n == a {
+			return true
+		}
+	}
+	return false
+}
+
+// domTreeWalker implements the TreeWalker.
+type domTreeWalker struct {
+	traversal
+	currentNode Node
+}
+
+func (tw *domTreeWalker) GetCurrentNode() Node {
+	return tw.currentNode
+}
+
+func (tw *domTreeWalker) SetCurrentNode(n Node) error {
+	if n == nil {
+		return fmt.Errorf("%v: the current node can not be nil", ErrorNotSupported)
+	}
+	tw.currentNode = n
+	return nil
+}
+
+func (tw *domTreeWalker) ParentNode() Node {
+	for node := tw.currentNode; node != nil && node != tw.root; {
+		node = node.GetParentNode()
+		if node != nil && tw.acceptNode(node) == FilterAccept {
+			tw.currentNode = node
+			return node
+		}
+	}
+	return nil
+}
+
+func (tw *domTreeWalker) FirstChild() Node {
+	return tw.traverseChildren(true)
+}
+
+func (tw *domTreeWalker) LastChild() Node {
+	return tw.traverseChildren(false)
+}
+
+// traverseChildren moves to the first or last visible child of the current node. Children
+// of skipped nodes are visible children as well.
+func (tw *domTreeWalker) traverseChildren(first bool) Node {
+	node := tw.lastChild(tw.currentNode)
+	if first {
+		node = tw.firstChild(tw.currentNode)
+	}
+
+	for node != nil {
+		result := tw.acceptNode(node)
+		if result == FilterAccept {
+			tw.currentNode = node
+			return node
+		}
+		if result == FilterSkip {
+			child := tw.lastChild(node)
+			if first {
+				child = tw.firstChild(node)
+			}
+			if child != nil {
+				node = child
+				continue
+			}
+		}
+
+		// Move to the next sibling, or to the next sibling of an ancestor.
+		for node != nil {
+			sibling := node.GetPreviousSibling()
+			if first {
+				sibling = node.GetNextSibling()
+			}
+			if sibling != nil {
+				node = sibling
+				break
+			}
+			parent := node.GetParentNode()
+			if parent == nil || parent == tw.root || parent == tw.currentNode {
+				return nil
+			}
+			node = parent
+		}
+	}
+	return nil
+}
+
+func (tw *domTreeWalker) PreviousSibling() Node {
+	return tw.traverseSiblings(false)
+}
+
+func (tw *domTreeWalker) NextSibling() Node {
+	return tw.traverseSiblings(true)
+}
+
+// traverseSiblings moves to the next or previous visible sibling of the current node.
+func (tw *domTreeWalker) traverseSiblings(next bool) Node {
+	node := tw.currentNode
+	if node == tw.root {
+		return nil
+	}
+
+	sibling := func(n Node) Node {
+		if next {
+			return n.GetNextSibling()
+		}
+		return n.GetPreviousSibling()
+	}
+	child := func(n Node) Node {
+		if next {
+			return tw.firstChild(n)
+		}
+		return tw.lastChild(n)
+	}
+
+	for {
+		for s := sibling(node); s != nil; {
+			node = s
+			result := tw.acceptNode(node)
+			if result == FilterAccept {
+				tw.currentNode = node
+				return node
+			}
+			// The children of a skipped node are siblings in the logical view.
+			s = child(node)
+			if result == FilterReject || s == nil {
+				s = sibling(node)
+			}
+		}
+
+		node = node.GetParentNode()
+		if node == nil || node == tw.root || tw.acceptNode(node) == FilterAccept {
+			return nil
+		}
+	}
+}
+
+func (tw *domTreeWalker) PreviousNode() Node {
+	node := tw.currentNode
+	for node != tw.root {
+		for sibling := node.GetPreviousSibling(); sibling != nil; sibling = node.GetPreviousSibling() {
+			node = sibling
+			// The last visible descendant precedes the sibling itself.
+			result := tw.acceptNode(node)
+			for result != FilterReject && tw.lastChild(node) != nil {
+				node = tw.lastChild(node)
+				result = tw.acceptNode(node)
+			}
+			if result == FilterAccept {
+				tw.currentNode = node
+				return node
+			}
+		}
+
+		if node == tw.root || node.GetParentNode() == nil {
+			return nil
+		}
+		node = node.GetParentNode()
+		if tw.acceptNode(node) == FilterAccept {
+			tw.currentNode = node
+			return node
+		}
+	}
+	return nil
+}
+
+func (tw *domTreeWalker) NextNode() Node {
+	node := tw.currentNode
+	result := FilterAccept
+	for {
+		for result != FilterReject && tw.firstChild(node) != nil {
+			node = tw.firstChild(node)
+			if result = tw.acceptNode(node); result == FilterAccept {
+				tw.currentNode = node
+				return node
+			}
+		}
+
+		// Move to the next sibling of the node, or of its closest ancestor having one.
+		var sibling Node
+		for temp := node; temp != nil && sibling == nil; temp = temp.GetParentNode() {
+			if temp == tw.root {
+				return nil
+			}
+			sibling = temp.GetNextSibling()
+		}
+		if sibling == nil {
+			return nil
+		}
+
+		node = sibling
+		if result = tw.acceptNode(node); result == FilterAccept {
+			tw.currentNode = node
+			return node
+		}
+	}
+}
+
+func (tw *domTreeWalker) String() string {
+	return fmt.Sprintf("TreeWalker: %v", tw.currentNode)
+}
@@ -0,0 +1,328 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+var exampleDocTraversal = `<!DOCTYPE a [<!ENTITY e "<x/>y">]><a><b><c/>text<d/></b><!-- comment --><e>&e;</e><f><g/></f></a>`
+
+// names returns the node names of the nodes, separated by spaces.
+func names(nodes []Node) string {
+	var s []string
+	for _, n := range nodes {
+		s = append(s, n.GetNodeName())
+	}
+	return strings.Join(s, " ")
+}
+
+func TestWhatToShow(t *testing.T) {
+	if ShowElement != 0x1 || ShowText != 0x4 || ShowComment != 0x80 || ShowDocumentFragment != 0x400 {
+		t.Error("expected the flags to match the values of the specification")
+	}
+	show := ShowElement | ShowComment
+	if !show.Shows(ElementNode) || !show.Shows(CommentNode) || show.Shows(TextNode) {
+		t.Errorf("unexpected flags %b", show)
+	}
+	if ShowNodeType(CDATASectionNode) != ShowCDATASection {
+		t.Errorf("expected %b, got %b", ShowCDATASection, ShowNodeType(CDATASectionNode))
+	}
+}
+
+func TestNodeIterator(t *testing.T) {
+	doc := mustParse(t, exampleDocTraversal)
+
+	var tests = []struct {
+		root       Node
+		whatToShow WhatToShow
+		filter     NodeFilter
+		expand     bool
+		expected   string
+	}{
+		{doc, ShowAll, nil, true, "#document a a b c #text d #comment e e x #text f g"},
+		{doc, ShowElement, nil, true, "a b c d e x f g"},
+		{doc, ShowElement, nil, false, "a b c d e f g"},
+		{doc, ShowElement | ShowEntityReference, nil, false, "a b c d e e f g"},
+		{doc, ShowDocumentType | ShowComment, nil, true, "a #comment"},
+		{doc.GetElementsByTagName("b")[0], ShowAll, nil, true, "b c #text d"},
+		{doc.GetElementsByTagName("c")[0], ShowAll, nil, true, "c"},
+		// Rejected nodes are skipped by an iterator, but not their children.
+		{doc, ShowElement, NodeFilterFunc(func(n Node) FilterResult {
+			if n.GetNodeName() == "b" || n.GetNodeName() == "f" {
+				return FilterReject
+			}
+			return FilterAccept
+		}), true, "a c d e x g"},
+	}
+
+	for _, test := range tests {
+		it, err := doc.CreateNodeIterator(test.root, test.whatToShow, test.filter, test.expand)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		var nodes []Node
+		for n, _ := it.NextNode(); n != nil; n, _ = it.NextNode() {
+			nodes = append(nodes, n)
+		}
+		if actual := names(nodes); actual != test.expected {
+			t.Errorf("expected '%v', got '%v'", test.expected, actual)
+		}
+
+		// Iterating backwards returns the same nodes in reverse.
+		var reversed []Node
+		for n, _ := it.PreviousNode(); n != nil; n, _ = it.PreviousNode() {
+			reversed = append([]Node{n}, reversed...)
+		}
+		if actual := names(reversed); actual != test.expected {
+			t.Errorf("expected '%v' in reverse, got '%v'", test.expected, actual)
+		}
+		it.Detach()
+	}
+
+	// The direction can be changed at any time.
+	it, _ := doc.CreateNodeIterator(doc.GetDocumentElement(), ShowElement, nil, true)
+	it.NextNode()
+	it.NextNode()
+	n1, _ := it.NextNode()
+	n2, _ := it.PreviousNode()
+	n3, _ := it.PreviousNode()
+	if n1.GetNodeName() != "c" || n2 != n1 || n3.GetNodeName() != "b" {
+		t.Errorf("expected c c b, got %v %v %v", n1, n2, n3)
+	}
+	if it.GetReferenceNode() != n3 || !it.IsPointerBeforeReferenceNode() {
+		t.Errorf("expected the pointer before %v, got %v", n3, it.GetReferenceNode())
+	}
+
+	it.Detach()
+	if _, err := it.NextNode(); err == nil {
+		t.Error("expected error, got none")
+	}
+	if _, err := doc.CreateNodeIterator(nil, ShowAll, nil, true); err == nil {
+		t.Error("expected error, got none")
+	}
+}
+
+func TestNodeIteratorRemoval(t *testing.T) {
+	doc := mustParse(t, exampleDocTraversal)
+	it, _ := doc.CreateNodeIterator(doc, ShowElement, nil, true)
+
+	// Remove every node while iterating.
+	var nodes []Node
+	for n, _ := it.NextNode(); n != nil; n, _ = it.NextNode() {
+		nodes = append(nodes, n)
+		if n.GetNodeName() == "b" {
+			// Remove the current node, including the children which weren't visited yet.
+			n.GetParentNode().RemoveChild(n)
+		}
+		if n.GetNodeName() == "e" {
+			// Remove the next node.
+			f := doc.GetElementsByTagName("f")[0]
+			f.GetParentNode().RemoveChild(f)
+		}
+	}
+	if actual := names(nodes); actual != "a b e x" {
+		t.Errorf("expected 'a b e x', got '%v'", actual)
+	}
+
+	// The pointer is before the reference node, which is removed.
+	doc = mustParse(t, exampleDocTraversal)
+	root := doc.GetDocumentElement()
+	it, _ = doc.CreateNodeIterator(root, ShowElement, nil, true)
+	it.NextNode()
+	it.NextNode()
+	b, _ := it.PreviousNode()
+	root.RemoveChild(b)
+	if it.GetReferenceNode().GetNodeName() != "#comment" || !it.IsPointerBeforeReferenceNode() {
+		t.Errorf("expected the pointer before the comment, got %v", it.GetReferenceNode())
+	}
+	if n, _ := it.NextNode(); n.GetNodeName() != "e" {
+		t.Errorf("expected e, got %v", n)
+	}
+
+	// The last node is removed, while the pointer is before it.
+	g := doc.GetElementsByTagName("g")[0]
+	it, _ = doc.CreateNodeIterator(root, ShowElement, nil, true)
+	for n, _ := it.NextNode(); n != g; n, _ = it.NextNode() {
+	}
+	it.PreviousNode()
+	g.GetParentNode().RemoveChild(g)
+	if it.GetReferenceNode().GetNodeName() != "f" || it.IsPointerBeforeReferenceNode() {
+		t.Errorf("expected the pointer after f, got %v", it.GetReferenceNode())
+	}
+	if n, _ := it.NextNode(); n != nil {
+		t.Errorf("expected nil, got %v", n)
+	}
+
+	// Replacing, moving and setting the text content remove nodes too.
+	e := doc.GetElementsByTagName("e")[0]
+	it, _ = doc.CreateNodeIterator(root, ShowAll, nil, true)
+	for n, _ := it.NextNode(); n.GetNodeName() != "x"; n, _ = it.NextNode() {
+	}
+	e.SetTextContent("new")
+	if it.GetReferenceNode() != e {
+		t.Errorf("expected %v, got %v", e, it.GetReferenceNode())
+	}
+	if n, _ := it.NextNode(); n == nil || n.GetNodeValue() != "new" {
+		t.Errorf("expected the new text, got %v", n)
+	}
+	f := doc.GetElementsByTagName("f")[0]
+	h, _ := doc.CreateElement("h")
+	root.ReplaceChild(h, f)
+	if it.GetReferenceNode().GetNodeValue() != "new" {
+		t.Errorf("expected the text, got %v", it.GetReferenceNode())
+	}
+	if n, _ := it.NextNode(); n != h {
+		t.Errorf("expected %v, got %v", h, n)
+	}
+
+	// Removals outside of the root don't matter.
+	it, _ = doc.CreateNodeIterator(e, ShowAll, nil, true)
+	it.NextNode()
+	root.RemoveChild(e)
+	if it.GetReferenceNode() != e {
+		t.Errorf("expected %v, got %v", e, it.GetReferenceNode())
+	}
+}
+
+func TestNodeIteratorDetach(t *testing.T) {
+	doc := mustParse(t, exampleDocTraversal)
+	dd := doc.(*domDocument)
+
+	// The document keeps every iterator until it is detached, so it can update them on removals.
+	var iterators []NodeIterator
+	for i := 0; i < 100; i++ {
+		it, _ := doc.CreateNodeIterator(doc, ShowAll, nil, true)
+		iterators = append(iterators, it)
+	}
+	if len(dd.iterators) != 100 {
+		t.Errorf("expected 100 iterators, got %d", len(dd.iterators))
+	}
+	for _, it := range iterators {
+		it.Detach()
+		it.Detach()
+	}
+	if len(dd.iterators) != 0 {
+		t.Errorf("expected no iterators, got %d", len(dd.iterators))
+	}
+	if _, err := iterators[0].NextNode(); err == nil || !strings.HasPrefix(err.Error(), ErrorInvalidState.Error()) {
+		t.Errorf("expected %v, got %v", ErrorInvalidState, err)
+	}
+}
+
+func TestTreeWalker(t *testing.T) {
+	doc := mustParse(t, exampleDocTraversal)
+	root := doc.GetDocumentElement()
+
+	tw, err := doc.CreateTreeWalker(root, ShowElement, nil, true)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	if tw.GetRoot() != root || tw.GetCurrentNode() != root || tw.GetWhatToShow() != ShowElement || !tw.GetExpandEntityReferences() {
+		t.Error("unexpected properties")
+	}
+
+	var steps = []struct {
+		move     func() Node
+		expected string
+	}{
+		{tw.ParentNode, ""},
+		{tw.PreviousSibling, ""},
+		{tw.FirstChild, "b"},
+		{tw.FirstChild, "c"},
+		{tw.NextSibling, "d"},
+		{tw.NextSibling, ""},
+		{tw.ParentNode, "b"},
+		{tw.NextSibling, "e"},
+		{tw.LastChild, "x"},
+		{tw.NextNode, "f"},
+		{tw.PreviousSibling, "e"},
+		{tw.PreviousNode, "d"},
+		{tw.PreviousNode, "c"},
+		{tw.PreviousNode, "b"},
+		{tw.PreviousNode, "a"},
+		{tw.PreviousNode, ""},
+		{tw.LastChild, "f"},
+		{tw.NextNode, "g"},
+		{tw.NextNode, ""},
+	}
+	for i, step := range steps {
+		before := tw.GetCurrentNode()
+		n := step.move()
+		switch {
+		case step.expected == "" && n != nil:
+			t.Errorf("step %d: expected nil, got %v", i, n)
+		case step.expected == "" && tw.GetCurrentNode() != before:
+			t.Errorf("step %d: expected the current node to stay at %v, got %v", i, before, tw.GetCurrentNode())
+		case step.expected != "" && (n == nil || n.GetNodeName() != step.expected || tw.GetCurrentNode() != n):
+			t.Errorf("step %d: expected %v, got %v", i, step.expected, n)
+		}
+	}
+
+	if err := tw.SetCurrentNode(nil); err == nil {
+		t.Error("expected error, got none")
+	}
+}
+
+func TestTreeWalkerFilter(t *testing.T) {
+	doc := mustParse(t, exampleDocTraversal)
+	root := doc.GetDocumentElement()
+
+	// Skipped nodes are left out, but their children are not. Rejected nodes are left
+	// out with their children.
+	filter := NodeFilterFunc(func(n Node) FilterResult {
+		switch n.GetNodeName() {
+		case "b", "f":
+			return FilterSkip
+		case "e":
+			return FilterReject
+		}
+		return FilterAccept
+	})
+	tw, _ := doc.CreateTreeWalker(root, ShowElement|ShowComment, filter, true)
+
+	var nodes []Node
+	for n := tw.NextNode(); n != nil; n = tw.NextNode() {
+		nodes = append(nodes, n)
+	}
+	if actual := names(nodes); actual != "c d #comment g" {
+		t.Errorf("expected 'c d #comment g', got '%v'", actual)
+	}
+
+	nodes = nil
+	for n := tw.PreviousNode(); n != nil; n = tw.PreviousNode() {
+		nodes = append(nodes, n)
+	}
+	if actual := names(nodes); actual != "#comment d c a" {
+		t.Errorf("expected '#comment d c a', got '%v'", actual)
+	}
+
+	// In the logical view, the children of skipped nodes are children of the root.
+	tw.SetCurrentNode(root)
+	nodes = nil
+	for n := tw.FirstChild(); n != nil; n = tw.NextSibling() {
+		nodes = append(nodes, n)
+	}
+	if actual := names(nodes); actual != "c d #comment g" {
+		t.Errorf("expected 'c d #comment g', got '%v'", actual)
+	}
+	if n := tw.ParentNode(); n != root {
+		t.Errorf("expected %v, got %v", root, n)
+	}
+	if n := tw.LastChild(); n == nil || n.GetNodeName() != "g" {
+		t.Errorf("expected g, got %v", n)
+	}
+	if n := tw.PreviousSibling(); n == nil || n.GetNodeName() != "#comment" {
+		t.Errorf("expected the comment, got %v", n)
+	}
+
+	// Without expansion, the entity reference has no children.
+	tw, _ = doc.CreateTreeWalker(doc.GetElementsByTagName("e")[0], ShowAll, nil, false)
+	if n := tw.FirstChild(); n == nil || n.GetNodeType() != EntityReferenceNode {
+		t.Errorf("expected the entity reference, got %v", n)
+	}
+	if n := tw.FirstChild(); n != nil {
+		t.Errorf("expected nil, got %v", n)
+	}
+}
@@ -2,6 +2,8 @@ package dom
 
 import (
 	"errors"
+	"io"
+	"strings"
 )
 
 // This file contains the definitions of errors, interfaces and other constants
@@ -34,6 +36,37 @@ var ErrorWrongDocument = errors.New("WRONG_DOCUMENT_ERR: the child was created f
 // The DOM user must explicitly create/clone Attr nodes to re-use them in other elements.
 var ErrorAttrInUse = errors.New("INUSE_ATTRIBUTE_ERR: the attribute is already an attribute of another Element")
 
+// ErrorNoModificationAllowed is returned when an attempt is made to modify a read-only
+// Node, like an Entity or the children of an EntityReference.
+var ErrorNoModificationAllowed = errors.New("NO_MODIFICATION_ALLOWED_ERR: an attempt was made to modify an object where modifications are not allowed")
+
+// ErrorNamespace is returned when an attempt is made to create or change an object in
+// a way which is incorrect with regard to namespaces, or when a prefix can not be
+// resolved to a namespace URI.
+var ErrorNamespace = errors.New("NAMESPACE_ERR: an attempt was made to create or change an object in a way which is incorrect with regard to namespaces")
+
+// ErrorSyntax is returned when an invalid or illegal string is specified, like a CSS
+// selector which can not be parsed.
+var ErrorSyntax = errors.New("SYNTAX_ERR: an invalid or illegal string was specified")
+
+// ErrorInvalidState is returned when an attempt is made to use an object that is not,
+// or is no longer, usable. For example a NodeIterator after it has been detached.
+var ErrorInvalidState = errors.New("INVALID_STATE_ERR: an attempt was made to use an object that is not, or is no longer, usable")
+
+// ErrorIndexSize is returned when an index or size is negative, or greater than the
+// allowed value. For example an offset past the end of the character data of a Text node.
+var ErrorIndexSize = errors.New("INDEX_SIZE_ERR: the index or size is negative, or greater than the allowed value")
+
+// ErrorTypeMismatch is returned when the type of a value is incompatible with the expected
+// type, like a string for a boolean parameter of a DOMConfiguration.
+var ErrorTypeMismatch = errors.New("TYPE_MISMATCH_ERR: the type of the object is incompatible with the expected type")
+
+// XMLNamespaceURI is the namespace URI which is bound to the xml prefix by definition.
+const XMLNamespaceURI = "http://www.w3.org/XML/1998/namespace"
+
+// XMLNSNamespaceURI is the namespace URI which is bound to the xmlns prefix by definition.
+const XMLNSNamespaceURI = "http://www.w3.org/2000/xmlns/"
+
 // XMLDeclaration is the usually default XML processing instruction at the
 // start of XML documents. This is merely added as a convenience. It's the
 // same declaration which the encoding/xml package has, except it does not
@@ -56,6 +89,7 @@ const (
 	DocumentNode
 	DocumentTypeNode
 	DocumentFragmentNode
+	XPathNamespaceNode
 )
 
 // String returns the string representation of the NodeType, using the default
@@ -84,16 +118,47 @@ func (n NodeType) String() string {
 		return "DOCUMENT_TYPE_NODE"
 	case DocumentFragmentNode:
 		return "DOCUMENT_FRAGMENT_NODE"
+	case XPathNamespaceNode:
+		return "XPATH_NAMESPACE_NODE"
 	default:
 		return "???"
 	}
 }
 
+// DocumentPosition is the bitmask returned by Node.CompareDocumentPosition, which describes
+// the position of the other Node relative to the Node it was called on.
+type DocumentPosition uint8
+
+// Enumeration of the bits of a DocumentPosition.
+const (
+	DocumentPositionDisconnected           DocumentPosition = 1 << iota // The nodes are not in the same tree.
+	DocumentPositionPreceding                                           // The other node precedes the node.
+	DocumentPositionFollowing                                           // The other node follows the node.
+	DocumentPositionContains                                            // The other node is an ancestor of the node.
+	DocumentPositionContainedBy                                         // The other node is a descendant of the node.
+	DocumentPositionImplementationSpecific                              // The order is determined by the implementation.
+)
+
+// String returns the names of the bits which are set, as used by the W3 specification,
+// separated by a '|'.
+func (p DocumentPosition) String() string {
+	names := []string{"DISCONNECTED", "PRECEDING", "FOLLOWING", "CONTAINS", "CONTAINED_BY", "IMPLEMENTATION_SPECIFIC"}
+	var set []string
+	for i, name := range names {
+		if p&(1<<i) != 0 {
+			set = append(set, name)
+		}
+	}
+	return strings.Join(set, "|")
+}
+
 // Node is the primary interface for the entire Document Object Model. It represents
 // a single node in the document tree. While all objects implementing the Node
 // interface expose methods for dealing with children, not all objects implementing
 // the Node interface may have children.
 type Node interface {
+	EventTarget
+
 	GetNodeName() string   // Returns the node name.
 	GetNodeType() NodeType // Returns the node type (e.g. ElementNode, CommentNode, ...)
 	GetNodeValue() string  // Returns the node value.
@@ -117,6 +182,22 @@ type Node interface {
 
 	CloneNode(deep bool) Node          // Creates a duplicate of the current node.
 	ImportNode(n Node, deep bool) Node // Imports a node from another document to this document, without altering or removing the source node from the original document.
+	Normalize()                        // Merges adjacent Text nodes and removes empty ones, in the whole subtree of this node.
+	IsSameNode(other Node) bool        // Returns true if the other Node is this very same Node.
+	IsEqualNode(other Node) bool       // Returns true if the other Node is structurally equal to this Node, including the subtree.
+
+	// CompareDocumentPosition returns the position of the other Node relative to this Node in
+	// document order. Attributes follow their owner element, which contains them, and precede
+	// the children of the element. Nodes in different trees are disconnected, but are still
+	// ordered consistently.
+	CompareDocumentPosition(other Node) DocumentPosition
+
+	// SetUserData attaches the data to this Node under the key, and returns the data which was
+	// attached under the key before, if any. Nil data removes the key. The handler, if not nil,
+	// is notified when this Node is cloned, imported, deleted, renamed or adopted.
+	SetUserData(key string, data interface{}, handler UserDataHandler) interface{}
+	// GetUserData returns the data attached to this Node under the key, or nil.
+	GetUserData(key string) interface{}
 
 	GetPreviousSibling() Node // Gets the Node immediately preceding this Node. Returns nil if no previous sibling exists.
 	GetNextSibling() Node     // Gets the Node immediately following this Node. Returns nil if no following sibling exists.
@@ -133,6 +214,11 @@ type Node interface {
 
 	setParentNode(Node)        // Sets the parent node of this Node.
 	setOwnerDocument(Document) // Sets the owner document of the Node. Used by ImportNode() for example.
+	getState() *nodeState      // Returns the state the DOM keeps for this Node, such as its user data.
+
+	// cloneNode creates a duplicate of the current node, owned by the Document owner, and
+	// notifies the user data handlers of the operation (NodeCloned or NodeImported).
+	cloneNode(owner Document, deep bool, operation UserDataOperation) Node
 }
 
 // ProcessingInstruction interface represents a "processing instruction", used
@@ -155,41 +241,95 @@ type Attr interface {
 	Node
 
 	GetName() string
-	IsSpecified() bool
+	IsSpecified() bool // False if the attribute has a default value from the DTD, which is not set explicitly.
+	IsId() bool        // True if the attribute is of type ID.
 	GetValue() string
 	SetValue(string)
 	GetOwnerElement() Element
 
 	setOwnerElement(Element) // setOwnerElement is necessary to add an owner after creation.
 	setName(string)          // setName sets the attribute name. Used for normalizing attributes.
+	setSpecified(bool)       // setSpecified marks the attribute as (not) explicitly specified.
+	setID(bool)              // setID marks the attribute as being of type ID.
 }
 
 // Element represents an element in an HTML or XML document. It implements the Node interface.
 type Element interface {
 	Node
 
-	GetTagName() string                                            // Gets the tag name of this element.
-	SetAttribute(name, value string) error                         // Convenience function to add an attribute.
-	SetAttributeNode(a Attr) error                                 // Sets an attribute based on the Attr type.
-	GetAttribute(name string) string                               // Convenience function to get an attribute value.
-	GetElementsByTagName(string) []Element                         // Find all descendant elements of the current element.
-	GetElementsByTagNameNS(namespaceURI, tagname string) []Element // Like GetElementsByTagName, except with a namespace URI.
+	GetTagName() string                                               // Gets the tag name of this element.
+	SetAttribute(name, value string) error                            // Convenience function to add an attribute.
+	SetAttributeNS(namespaceURI, qualifiedName, value string) error   // Adds an attribute with a namespace URI, or changes its value.
+	SetAttributeNode(a Attr) error                                    // Sets an attribute based on the Attr type.
+	SetAttributeNodeNS(a Attr) error                                  // Sets an attribute, replacing the one with the same namespace URI and local name.
+	GetAttribute(name string) string                                  // Convenience function to get an attribute value.
+	GetAttributeNS(namespaceURI, localName string) string             // Gets the value of the attribute with the namespace URI and local name.
+	GetAttributeNode(name string) Attr                                // Gets the attribute node with the given name, or nil.
+	GetAttributeNodeNS(namespaceURI, localName string) Attr           // Gets the attribute node with the namespace URI and local name, or nil.
+	HasAttribute(name string) bool                                    // Returns true if the element has the attribute.
+	HasAttributeNS(namespaceURI, localName string) bool               // Returns true if the element has the attribute with the namespace URI and local name.
+	RemoveAttribute(name string) error                                // Removes the attribute with the given name, if any.
+	RemoveAttributeNS(namespaceURI, localName string) error           // Removes the attribute with the namespace URI and local name, if any.
+	RemoveAttributeNode(a Attr) (Attr, error)                         // Removes the attribute node, and returns it.
+	SetIdAttribute(name string, isID bool) error                      // Marks the attribute with the given name as (not) being of type ID.
+	SetIdAttributeNS(namespaceURI, localName string, isID bool) error // Like SetIdAttribute, except with a namespace URI and local name.
+	SetIdAttributeNode(idAttr Attr, isID bool) error                  // Marks the attribute node as (not) being of type ID.
+	GetElementsByTagName(string) []Element                            // Find all descendant elements of the current element.
+	GetElementsByTagNameNS(namespaceURI, tagname string) []Element    // Like GetElementsByTagName, except with a namespace URI.
+	QuerySelector(selectors string) (Element, error)                  // Finds the first descendant element matching the CSS selectors.
+	QuerySelectorAll(selectors string) ([]Element, error)             // Finds all descendant elements matching the CSS selectors, in document order.
 
 	setTagName(string)                // Sets the tagname when necessary.
 	normalizeNamespaces(counter *int) // Normalizes namespaces. See https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/namespaces-algorithms.html#normalizeDocumentAlgo
 }
 
+// CharacterData holds the operations on the character data of Text, CDATASection and Comment
+// nodes. Offsets and counts are in UTF-16 code units, as the specification requires, so a
+// character outside the Basic Multilingual Plane counts for two units. When the offset is out
+// of range, ErrorIndexSize is returned. A count going past the end of the data is clamped.
+type CharacterData interface {
+	Node
+
+	GetLength() int                                  // Returns the length of the data in UTF-16 code units.
+	SubstringData(offset, count int) (string, error) // Returns count code units of the data, starting at offset.
+	AppendData(arg string) error                     // Appends the string to the end of the data.
+	InsertData(offset int, arg string) error         // Inserts the string at the offset.
+	DeleteData(offset, count int) error              // Removes count code units, starting at offset.
+	ReplaceData(offset, count int, arg string) error // Replaces count code units, starting at offset, with the string.
+}
+
 // Text represents character data within an element. It implements the Node interface.
 // Note that the name of the methods defined on this interface are not aligned with the specifications,
 // due to the fact the Go's interfaces will not see a correct difference between this Text
 // interface, or the ProcessingInstruction interface when the methods have the same signatures.
 type Text interface {
-	Node
+	CharacterData
 
 	GetText() string  // Gets the character data of this Text node.
 	SetText(s string) // Sets the character data of this Text node.
 
 	IsElementContentWhitespace() bool // Return true if the Text node contains "ignorable whitespace".
+
+	// SplitText breaks the node in two at the offset in UTF-16 code units. The data after the
+	// offset moves to a new node of the same type, which is inserted as the next sibling of
+	// this node, and returned.
+	SplitText(offset int) (Text, error)
+	// GetWholeText returns the data of this node, and of the text nodes logically adjacent to
+	// it, in document order. These are the Text and CDATASection nodes which can be reached
+	// without passing anything other than entity references.
+	GetWholeText() string
+	// ReplaceWholeText replaces the data of this node with the content, and removes the text
+	// nodes logically adjacent to it. It returns this node, or nil if the content is empty,
+	// in which case this node is removed as well.
+	ReplaceWholeText(content string) (Text, error)
+}
+
+// CDATASection is used to escape blocks of text containing characters that would
+// otherwise be regarded as markup, like <![CDATA[ <script>...</script> ]]>. It is
+// a Text node in every other aspect. Note that every Text satisfies this interface,
+// so use GetNodeType() to check for CDATASectionNode.
+type CDATASection interface {
+	Text
 }
 
 // DocumentType belongs to a Document, but can also be nil. The DocumentType
@@ -200,9 +340,30 @@ type Text interface {
 type DocumentType interface {
 	Node
 
-	GetName() string     // Gets the name of the DTD; i.e. the name immediately following the DOCTYPE keyword.
-	GetPublicID() string // Returns the public identifier of the external subset.
-	GetSystemID() string // Returns the system identifier of the external subset. This may be an absolute URI or not.
+	GetName() string           // Gets the name of the DTD; i.e. the name immediately following the DOCTYPE keyword.
+	GetPublicID() string       // Returns the public identifier of the external subset.
+	GetSystemID() string       // Returns the system identifier of the external subset. This may be an absolute URI or not.
+	GetInternalSubset() string // Returns the internal subset as a string, without the delimiting square brackets.
+	GetEntities() NamedNodeMap // Returns the general entities, both external and internal, declared in the DTD.
+}
+
+// Entity represents a known entity, either parsed or unparsed, in an XML document.
+// The children of an Entity are the parsed replacement text, if it is available.
+// An Entity, and its children, are read-only. It implements the Node interface.
+type Entity interface {
+	Node
+
+	GetPublicID() string     // Returns the public identifier of the entity, if it is an external entity.
+	GetSystemID() string     // Returns the system identifier of the entity, if it is an external entity.
+	GetNotationName() string // Returns the notation name for unparsed entities, or an empty string for parsed entities.
+}
+
+// EntityReference represents a reference to an entity in the document, like &name;.
+// Its children are a read-only copy of the children of the Entity it refers to, if
+// the entity is known. It implements the Node interface. Note that every Node satisfies
+// this interface, so use GetNodeType() to check for EntityReferenceNode.
+type EntityReference interface {
+	Node
 }
 
 // Document is the root of the Document Object Model. It implements the Node interface. As per the spec,
@@ -218,17 +379,30 @@ type Document interface {
 	// returns it. Use an empty string if no namespace is necessary. See
 	// CreateElement(string).
 	CreateElementNS(namespaceURI, tagName string) (Element, error)
+	// Creates an empty DocumentFragment and returns it.
+	CreateDocumentFragment() DocumentFragment
 	// Creates a Text node given the specified string and returns it.
 	CreateText(string) Text
+	// Creates a CDATASection node with the given content and returns it.
+	CreateCDATASection(data string) CDATASection
 	// Creates an Attr of the given name and returns it.
 	CreateAttribute(name string) (Attr, error)
 	// Creates an Attr using the given namespace URI and name.
 	CreateAttributeNS(namespaceURI, name string) (Attr, error)
+	// CreateEntityReference creates an EntityReference with the given name. If the
+	// entity is declared in the DocumentType, the reference gets a copy of its children.
+	CreateEntityReference(name string) (EntityReference, error)
 	// CreateComment creates a Comment node with the given comment content. If
 	// the comment contains a double hyphen (--), this should generate an error.
 	CreateComment(comment string) (Comment, error)
 	// CreateProcessingInstruction creates a processing instruction and returns it.
 	CreateProcessingInstruction(target, data string) (ProcessingInstruction, error)
+	// Gets the DocumentType associated with this Document, or nil if there is none.
+	GetDoctype() DocumentType
+	// GetImplementation returns the DOMImplementation which handles this Document.
+	GetImplementation() DOMImplementation
+	// GetDomConfig returns the DOMConfiguration which is used by NormalizeDocument.
+	GetDomConfig() DOMConfiguration
 	// Gets the document element, which should be the first (and only) child Node
 	// of the Document. Can be nil if none is set yet.
 	GetDocumentElement() Element
@@ -238,14 +412,64 @@ type Document interface {
 	// GetElementsByTagNameNS finds all descendant elements of the current element,
 	// with the given tag name and namespace URI, in document order.
 	GetElementsByTagNameNS(namespaceURI, tagname string) []Element
+	// QuerySelector finds the first descendant element matching the group of CSS
+	// selectors, or nil if there is none. Prefixes are resolved using the Document.
+	QuerySelector(selectors string) (Element, error)
+	// QuerySelectorAll finds all descendant elements matching the group of CSS
+	// selectors, in document order. Prefixes are resolved using the Document.
+	QuerySelectorAll(selectors string) ([]Element, error)
+	// CreateNodeIterator creates a NodeIterator over the subtree of the root, showing the
+	// node types in whatToShow which are accepted by the (optional) filter. The children of
+	// entity references are only visited when entityReferenceExpansion is true. The Document
+	// keeps a reference to the iterator to update it on removals, so it must be detached
+	// once it is no longer used.
+	CreateNodeIterator(root Node, whatToShow WhatToShow, filter NodeFilter, entityReferenceExpansion bool) (NodeIterator, error)
+	// CreateTreeWalker creates a TreeWalker over the subtree of the root, positioned at the
+	// root. See CreateNodeIterator for the other arguments.
+	CreateTreeWalker(root Node, whatToShow WhatToShow, filter NodeFilter, entityReferenceExpansion bool) (TreeWalker, error)
+	// CreateRange creates a Range, with both boundary points at the start of the Document.
+	// The Document keeps a reference to the Range to update it on mutations, so it must be
+	// detached once it is no longer used.
+	CreateRange() Range
+	// CreateEvent creates an uninitialized Event of the given interface, which is either
+	// "Event" or "CustomEvent".
+	CreateEvent(eventInterface string) (Event, error)
+	// NotifyMutationObservers delivers the queued records of the MutationObservers which
+	// observe nodes of this Document.
+	NotifyMutationObservers()
+
+	NormalizeDocument() // Puts the Document in 'normal form': merges adjacent Text nodes, and normalizes the namespaces.
+
+	// AdoptNode moves the source Node, with its attributes and descendants, from its document
+	// to this Document. Unlike ImportNode, the nodes are not copied. The source is removed from
+	// its parent, or from its owner element for an Attr, and is returned. The children of
+	// entity references are replaced by the expansion from the DocumentType of this Document.
+	// Document, DocumentType and Entity nodes can not be adopted.
+	AdoptNode(source Node) (Node, error)
+	// GetElementById returns the first Element in document order with an attribute of type ID
+	// which has the given value, or nil. Attributes are of type ID when they are declared as
+	// such in the DTD, when they are xml:id attributes, or when they are marked using
+	// Element.SetIdAttribute.
+	GetElementById(elementID string) Element
+	// RenameNode changes the qualified name and namespace URI of an Element or Attr of this
+	// Document, and returns it. The node itself is renamed, no new node is created. It returns
+	// an ErrorNamespace when the qualified name does not fit the namespace URI.
+	RenameNode(n Node, namespaceURI, qualifiedName string) (Node, error)
+}
 
-	NormalizeDocument() // Puts the Document in 'normal form'.
+// DocumentFragment is a "lightweight" or "minimal" Document object, which can hold
+// a portion of a document tree. When a DocumentFragment is inserted into a Document
+// or Element, not the fragment itself but all of its children are inserted, in order,
+// leaving the fragment empty. It implements the Node interface. Note that every Node
+// satisfies this interface, so use GetNodeType() to check for DocumentFragmentNode.
+type DocumentFragment interface {
+	Node
 }
 
 // Comment represents a comment node in an XML tree (e.g. <!-- ... -->). It implements
 // the Node interface.
 type Comment interface {
-	Node
+	CharacterData
 
 	GetComment() string        // Returns the comment text of this node.
 	SetComment(comment string) // Sets the comment text of this node.
@@ -253,25 +477,46 @@ type Comment interface {
 
 // NamedNodeMap represents collections of nodes that can be accessed by name.
 type NamedNodeMap interface {
-	GetNamedItem(string) Node  // Gets a named item identified by the given string. Returns nil if nothing is found.
-	SetNamedItem(Node) error   // Adds a new item. The node's NodeName is used as a key.
-	RemoveNamedItem(string)    // Removes the item identified by the given string.
-	GetItems() map[string]Node // Gets the items as a Go map.
-	Length() int               // Gets the amount of items in the named node map.
+	GetNamedItem(string) Node                                       // Gets a named item identified by the given string. Returns nil if nothing is found.
+	GetNamedItemNS(namespaceURI, localName string) Node             // Gets the item with the namespace URI and local name. Returns nil if nothing is found.
+	SetNamedItem(Node) error                                        // Adds a new item, or replaces one. The node's NodeName is used as a key.
+	SetNamedItemNS(Node) error                                      // Adds a new item, or replaces one. The node's namespace URI and local name are used as a key.
+	RemoveNamedItem(string) (Node, error)                           // Removes the item identified by the given string, and returns it.
+	RemoveNamedItemNS(namespaceURI, localName string) (Node, error) // Removes the item with the namespace URI and local name, and returns it.
+	Item(index int) Node                                            // Gets the item at the index, in insertion order. Returns nil if the index is out of range.
+	GetItems() map[string]Node                                      // Gets a copy of the items as a Go map.
+	Length() int                                                    // Gets the amount of items in the named node map.
+}
+
+// DTDResolver resolves the external subset of a DTD, given its public and system identifiers.
+// Resolve may return a nil reader and nil error, when it doesn't know about the DTD.
+type DTDResolver interface {
+	Resolve(publicID, systemID string) (io.Reader, error)
+}
+
+// DTDResolverFunc is an adapter to use ordinary functions as a DTDResolver.
+type DTDResolverFunc func(publicID, systemID string) (io.Reader, error)
+
+// Resolve calls f(publicID, systemID).
+func (f DTDResolverFunc) Resolve(publicID, systemID string) (io.Reader, error) {
+	return f(publicID, systemID)
 }
 
 // Configuration contains fields which can control the output of the Parser
 // and Serializer. Note that not (all configuration are specified or used (yet).
 type Configuration struct {
-	CDataSections            bool   // Keep CDataSection Nodes in the Document.
-	Comments                 bool   // Keep Comment nodes in the Document.
-	ElementContentWhitespace bool   // Keep all whitespaces in the Document.
-	Namespaces               bool   // Perform namespace processing as defined in https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/namespaces-algorithms.html#normalizeDocumentAlgo
-	NamespaceDeclarations    bool   // Include (true) or discard (false) namespace declaration attributes.
-	NormalizeCharacters      bool   // Perform or do not perform character normalization.
-	OmitXMLDeclaration       bool   // Omits XML declaration during serialization. Default: false.
-	PrettyPrint              bool   // Pretty print during serialization. Default: false.
-	IndentCharacter          string // Indent character, if pretty printing. Default is four spaces.
+	CDataSections            bool            // Keep CDataSection Nodes in the Document.
+	Comments                 bool            // Keep Comment nodes in the Document.
+	ElementContentWhitespace bool            // Keep all whitespaces in the Document.
+	Entities                 bool            // Keep EntityReference nodes (true), or replace them by their expansion (false).
+	Namespaces               bool            // Perform namespace processing as defined in https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/namespaces-algorithms.html#normalizeDocumentAlgo
+	NamespaceDeclarations    bool            // Include (true) or discard (false) namespace declaration attributes.
+	NormalizeCharacters      bool            // Perform or do not perform character normalization.
+	OmitXMLDeclaration       bool            // Omits XML declaration during serialization. Default: false.
+	PrettyPrint              bool            // Pretty print during serialization. Default: false.
+	Validate                 bool            // Validate the Document against the DTD while parsing. Default: false.
+	IndentCharacter          string          // Indent character, if pretty printing. Default is four spaces.
+	ErrorHandler             DOMErrorHandler // Notified of the problems found while parsing or serializing. Optional.
 }
 
 // NewConfiguration creates a Configuration object with the defaults as per the DOM spec.
@@ -280,11 +525,13 @@ func NewConfiguration() Configuration {
 		CDataSections:            true,
 		Comments:                 true,
 		ElementContentWhitespace: true,
+		Entities:                 true,
 		Namespaces:               true,
 		NamespaceDeclarations:    true,
 		NormalizeCharacters:      false,
 		OmitXMLDeclaration:       false,
 		PrettyPrint:              false,
+		Validate:                 false,
 		IndentCharacter:          "    ",
 	}
 }
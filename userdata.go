@@ -0,0 +1,123 @@
+package dom
+
+// This file contains the user data which applications can attach to nodes, and the
+// handlers which are notified when such a node is cloned, imported, deleted, renamed
+// or adopted. See https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#UserDataHandler
+
+// UserDataOperation is the operation on a Node which a UserDataHandler is notified of.
+type UserDataOperation uint8
+
+// Enumeration of the operations on nodes with user data.
+const (
+	// NodeCloned is passed when the node is cloned, using CloneNode.
+	NodeCloned UserDataOperation = iota + 1
+	// NodeImported is passed when the node is imported into another document, using ImportNode.
+	NodeImported
+	// NodeDeleted is passed when the DOM discards the node. Go has no control over the
+	// moment objects are deleted, so this is only passed for the nodes which are discarded
+	// by the DOM itself: the Text nodes merged by Normalize, the nodes discarded by
+	// NormalizeDocument, the Text nodes removed by ReplaceWholeText, and the nodes removed
+	// by Range.DeleteContents.
+	NodeDeleted
+	// NodeRenamed is passed when the node is renamed.
+	NodeRenamed
+	// NodeAdopted is passed when the node is adopted by another document.
+	NodeAdopted
+)
+
+// String returns the string representation of the UserDataOperation, using the default
+// representation by the W3 specification.
+func (op UserDataOperation) String() string {
+	switch op {
+	case NodeCloned:
+		return "NODE_CLONED"
+	case NodeImported:
+		return "NODE_IMPORTED"
+	case NodeDeleted:
+		return "NODE_DELETED"
+	case NodeRenamed:
+		return "NODE_RENAMED"
+	case NodeAdopted:
+		return "NODE_ADOPTED"
+	default:
+		return "???"
+	}
+}
+
+// UserDataHandler is notified of operations on the node which it was registered at, along
+// with the user data, using Node.SetUserData. The src is the node the operation was applied
+// to, and dst is the node which was created by it, or nil if there is none.
+type UserDataHandler interface {
+	Handle(operation UserDataOperation, key string, data interface{}, src, dst Node)
+}
+
+// UserDataHandlerFunc is an adapter to use ordinary functions as a UserDataHandler.
+type UserDataHandlerFunc func(operation UserDataOperation, key string, data interface{}, src, dst Node)
+
+// Handle calls f(operation, key, data, src, dst).
+func (f UserDataHandlerFunc) Handle(operation UserDataOperation, key string, data interface{}, src, dst Node) {
+	f(operation, key, data, src, dst)
+}
+
+// userDataEntry is the user data of a Node for a single key.
+type userDataEntry struct {
+	key     string
+	data    interface{}
+	handler UserDataHandler
+}
+
+// setUserData implements Node.SetUserData for the Node n. The user data is kept by the
+// Node itself, in the order the keys were added.
+func setUserData(n Node, key string, data interface{}, handler UserDataHandler) interface{} {
+	state := n.getState()
+	for i, entry := range state.userData {
+		if entry.key != key {
+			continue
+		}
+		old := entry.data
+		if data == nil {
+			state.userData = append(state.userData[:i:i], state.userData[i+1:]...)
+		} else {
+			entry.data = data
+			entry.handler = handler
+		}
+		return old
+	}
+
+	if data != nil {
+		state.userData = append(state.userData, &userDataEntry{key: key, data: data, handler: handler})
+	}
+	return nil
+}
+
+// getUserData implements Node.GetUserData for the Node n.
+func getUserData(n Node, key string) interface{} {
+	for _, entry := range n.getState().userData {
+		if entry.key == key {
+			return entry.data
+		}
+	}
+	return nil
+}
+
+// notifyUserDataHandlers calls the handlers of the user data of the Node src for the
+// operation. The dst is the node which was created by the operation, if any.
+func notifyUserDataHandlers(operation UserDataOperation, src, dst Node) {
+	for _, entry := range src.getState().userData {
+		if entry.handler != nil {
+			entry.handler.Handle(operation, entry.key, entry.data, src, dst)
+		}
+	}
+}
+
+// notifyUserDataDeleted calls the handlers of the user data of the Node n, its attributes
+// and its descendants with NodeDeleted.
+func notifyUserDataDeleted(n Node) {
+	notifyUserDataHandlers(NodeDeleted, n, nil)
+	for _, attr := range attributeList(n) {
+		notifyUserDataHandlers(NodeDeleted, attr, nil)
+	}
+	for _, child := range n.GetChildNodes() {
+		notifyUserDataDeleted(child)
+	}
+}
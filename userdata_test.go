@@ -0,0 +1,115 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUserData(t *testing.T) {
+	doc := NewDocument()
+	elem := mustCreateElement(doc, "elem")
+
+	if old := elem.SetUserData("source", "a.xml", nil); old != nil {
+		t.Errorf("expected no old data, got %v", old)
+	}
+	elem.SetUserData("line", 3, nil)
+	if old := elem.SetUserData("source", "b.xml", nil); old != "a.xml" {
+		t.Errorf("expected 'a.xml', got %v", old)
+	}
+	if data := elem.GetUserData("source"); data != "b.xml" {
+		t.Errorf("expected 'b.xml', got %v", data)
+	}
+	if data := elem.GetUserData("line"); data != 3 {
+		t.Errorf("expected 3, got %v", data)
+	}
+
+	// Nil data removes the key.
+	if old := elem.SetUserData("source", nil, nil); old != "b.xml" {
+		t.Errorf("expected 'b.xml', got %v", old)
+	}
+	if data := elem.GetUserData("source"); data != nil {
+		t.Errorf("expected no data, got %v", data)
+	}
+
+	// The data is kept per node.
+	if data := mustCreateElement(doc, "other").GetUserData("line"); data != nil {
+		t.Errorf("expected no data, got %v", data)
+	}
+	doc.SetUserData("line", 1, nil)
+	if data := doc.GetUserData("line"); data != 1 {
+		t.Errorf("expected 1, got %v", data)
+	}
+}
+
+func TestUserDataHandler(t *testing.T) {
+	doc := mustParse(t, `<r a="1"><child/>text</r>`)
+	root := doc.GetDocumentElement()
+	child := root.GetFirstChild()
+	attr := root.GetAttributeNode("a")
+
+	var log []string
+	handler := UserDataHandlerFunc(func(op UserDataOperation, key string, data interface{}, src, dst Node) {
+		entry := fmt.Sprintf("%v:%v=%v:%v", op, key, data, src.GetNodeName())
+		if dst != nil {
+			entry += "->" + dst.GetNodeName()
+			if dst == src {
+				t.Errorf("expected a new node for %v", op)
+			}
+		}
+		log = append(log, entry)
+	})
+	root.SetUserData("id", 1, handler)
+	child.SetUserData("id", 2, handler)
+	attr.SetUserData("id", 3, handler)
+	root.SetUserData("quiet", 4, nil)
+
+	var tests = []struct {
+		op       func()
+		expected string
+	}{
+		{func() { root.CloneNode(false) }, "NODE_CLONED:id=3:a->a NODE_CLONED:id=1:r->r"},
+		{func() { root.CloneNode(true) }, "NODE_CLONED:id=3:a->a NODE_CLONED:id=2:child->child NODE_CLONED:id=1:r->r"},
+		{func() { NewDocument().ImportNode(root, true) }, "NODE_IMPORTED:id=3:a->a NODE_IMPORTED:id=1:r->r NODE_IMPORTED:id=2:child->child"},
+		{func() { NewDocument().ImportNode(child, false) }, "NODE_IMPORTED:id=2:child->child"},
+	}
+	for _, test := range tests {
+		log = nil
+		test.op()
+		if actual := strings.Join(log, " "); actual != test.expected {
+			t.Errorf("expected '%v', got '%v'", test.expected, actual)
+		}
+	}
+
+	// Text nodes merged by Normalize are deleted.
+	log = nil
+	extra := doc.CreateText(" more")
+	extra.SetUserData("id", 5, handler)
+	root.AppendChild(extra)
+	root.Normalize()
+	if actual := strings.Join(log, " "); actual != "NODE_DELETED:id=5:#text" {
+		t.Errorf("expected the merged text to be deleted, got '%v'", actual)
+	}
+}
+
+func TestUserDataHandlerReentrant(t *testing.T) {
+	doc := NewDocument()
+	elem := mustCreateElement(doc, "elem")
+	target := NewDocument()
+
+	var log []string
+	elem.SetUserData("key", "value", UserDataHandlerFunc(func(op UserDataOperation, key string, data interface{}, src, dst Node) {
+		log = append(log, op.String())
+		if op == NodeImported {
+			if dst.GetOwnerDocument() != target {
+				t.Error("expected the imported node to be owned by the target document")
+			}
+			// Cloning while the node is being imported is still reported as cloning.
+			src.CloneNode(false)
+		}
+	}))
+	target.ImportNode(elem, true)
+	if actual := strings.Join(log, " "); actual != "NODE_IMPORTED NODE_CLONED" {
+		t.Errorf("expected 'NODE_IMPORTED NODE_CLONED', got '%v'", actual)
+	}
+}
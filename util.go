@@ -5,7 +5,9 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"sync/atomic"
 )
 
 // PrintTree prints the whole tree starting from the given Node 'node' to the
@@ -30,6 +32,13 @@ func escape(s string) string {
 	return b.String()
 }
 
+// splitCDATA splits any occurrence of the ]]> sequence in the data of a CDATA section,
+// by ending the section after the ]] and starting a new one before the >. This makes
+// it safe to write the data within a <![CDATA[ ... ]]> block.
+func splitCDATA(s string) string {
+	return strings.Replace(s, "]]>", "]]]]><![CDATA[>", -1)
+}
+
 // getElementsBy finds descandant elements with the given (optional) namespaceURI and tagname.
 // When the 'includeNamespace' is set to true, the namespace URI is explicitly checked for
 // equality. If false, no namespace check will be done. The elements are returned as a 'live'
@@ -39,21 +48,27 @@ func escape(s string) string {
 // Element is created without the Document's CreateElementNS() method, it will NOT have a
 // namespace. Even after an xmlns attribute is added. Xerces does it like this too.
 func getElementsBy(parent Node, namespaceURI, tagname string, includeNamespace bool) []Element {
+	return getElementsMatching(parent, func(elem Element) bool {
+		if includeNamespace {
+			// include namespace equality, if chosen.
+			return elem.GetLocalName() == tagname && elem.GetNamespaceURI() == namespaceURI
+		}
+		// do not include namespace equality, just the tagname
+		return elem.GetNodeName() == tagname
+	})
+}
+
+// getElementsMatching finds the descendant elements of the given parent node for which the
+// function 'match' returns true, in document order.
+func getElementsMatching(parent Node, match func(elem Element) bool) []Element {
 	var elements []Element
 
 	var traverse func(n Node)
 	traverse = func(n Node) {
 		for _, child := range n.GetChildNodes() {
 			// only check elements:
-			if elem, ok := child.(Element); ok {
-				if includeNamespace && elem.GetLocalName() == tagname && elem.GetNamespaceURI() == namespaceURI {
-					// include namespace equality, if chosen.
-					elements = append(elements, elem)
-				} else if !includeNamespace && elem.GetNodeName() == tagname {
-					// do not include namespace equality, just the tagname
-					elements = append(elements, elem)
-				}
-
+			if elem, ok := child.(Element); ok && match(elem) {
+				elements = append(elements, elem)
 			}
 
 			traverse(child)
@@ -107,24 +122,27 @@ func getNextSibling(node Node) Node {
 }
 
 // importNode is a generic function used by all Node types to import a Node to the specified Document.
-// A clone is internally created, where the parent will be nil. It merely creates a clone, sets the
-// owner document of the clone node (+ child nodes, if 'deep' is true) to the Document 'doc'.
+// A clone is internally created, where the parent will be nil. The clone (+ child nodes, if 'deep' is
+// true) is owned by the Document 'doc', and reported to the user data handlers as imported.
 func importNode(doc Document, n Node, deep bool) Node {
 	// TODO: per spec, Document types cannot be imported and should return an error.
 
+	switch n.GetNodeType() {
+	case EntityReferenceNode:
+		// Only the reference itself is imported. The expansion is taken from the DTD
+		// of the target document, if the entity is declared there.
+		ref, _ := doc.CreateEntityReference(n.GetNodeName())
+		notifyUserDataHandlers(NodeImported, n, ref)
+		return ref
+	case EntityNode:
+		// The children of an entity are read-only, so they can't be appended below.
+		return n.cloneNode(doc, true, NodeImported)
+	}
+
 	// Start by cloning the specified node, deep or not. This clone will not have a parent.
 	// Do not do a deep clone at this point, we'll do that below, while traversing children
-	// if 'deep' is set to true.
-	clone := n.CloneNode(false)
-	// Set the owner document of the clone to our specified document.
-	clone.setOwnerDocument(doc)
-	// If the clone has attributes (should be Elements only), set the owner document there too.
-	attrs := clone.GetAttributes()
-	if attrs != nil {
-		for _, v := range attrs.GetItems() {
-			v.setOwnerDocument(doc)
-		}
-	}
+	// if 'deep' is set to true. The attributes of an Element are cloned along.
+	clone := n.cloneNode(doc, false, NodeImported)
 
 	// Prematurely return when we don't do a deep import.
 	if !deep {
@@ -154,8 +172,9 @@ func MoveNamespacesToRoot(d Document) {
 		//   3a. if declared, use that prefix.
 		//   3b. if not declared, declare it in base.
 		if e, ok := n.(Element); ok {
-			for k, cruft := range e.GetAttributes().GetItems() {
+			for _, cruft := range attributeList(e) {
 				attr := cruft.(Attr) // This type assertion should always succeed.
+				k := attr.GetNodeName()
 
 				if strings.HasPrefix(k, "xmlns") {
 					e.GetAttributes().RemoveNamedItem(k)
@@ -217,3 +236,609 @@ func MoveNamespacesToRoot(d Document) {
 
 	traverse(d)
 }
+
+// indexOf returns the index of the Node n in the given slice of nodes, or -1 if
+// the Node is not found.
+func indexOf(nodes []Node, n Node) int {
+	for i, node := range nodes {
+		if node == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertFragment moves all the children of the DocumentFragment 'frag' to the Node
+// 'parent', in order, before the reference child 'refChild'. If refChild is nil, the
+// children are appended at the end. After a successful call, the fragment is empty.
+func insertFragment(parent, frag, refChild Node) error {
+	// Copy the children first, since inserting them removes them from the fragment.
+	children := append([]Node(nil), frag.GetChildNodes()...)
+	for _, child := range children {
+		if _, err := parent.InsertBefore(child, refChild); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceWithFragment replaces the child 'oldChild' of the Node 'parent' with all the
+// children of the DocumentFragment 'frag', in order. The old child is returned.
+func replaceWithFragment(parent, frag, oldChild Node) (Node, error) {
+	if oldChild.GetParentNode() != parent {
+		return nil, ErrorNotFound
+	}
+	// Remove the old child first, so a Document will accept a new document element.
+	refChild := oldChild.GetNextSibling()
+	if _, err := parent.RemoveChild(oldChild); err != nil {
+		return nil, err
+	}
+	if err := insertFragment(parent, frag, refChild); err != nil {
+		return nil, err
+	}
+	return oldChild, nil
+}
+
+// setOwnerDocumentDeep sets the owner document of the Node n, its attributes, and all
+// of its descendants to the given Document.
+func setOwnerDocumentDeep(n Node, doc Document) {
+	n.setOwnerDocument(doc)
+	if attrs := n.GetAttributes(); attrs != nil {
+		for i := 0; i < attrs.Length(); i++ {
+			attrs.Item(i).setOwnerDocument(doc)
+		}
+	}
+	for _, child := range n.GetChildNodes() {
+		setOwnerDocumentDeep(child, doc)
+	}
+}
+
+// cloneChildren returns a deep clone of every child of the Node n.
+func cloneChildren(n Node) []Node {
+	return cloneChildrenInto(n.GetOwnerDocument(), n, NodeCloned)
+}
+
+// cloneChildrenInto returns a deep clone of every child of the Node n, owned by the Document
+// owner. The user data handlers are notified of the operation.
+func cloneChildrenInto(owner Document, n Node, operation UserDataOperation) []Node {
+	var clones []Node
+	for _, child := range n.GetChildNodes() {
+		clones = append(clones, child.cloneNode(owner, true, operation))
+	}
+	return clones
+}
+
+// getChildTextContent returns the concatenated text content of the children of the
+// Node n, excluding comments and processing instructions.
+func getChildTextContent(n Node) string {
+	textContent := ""
+	for _, child := range n.GetChildNodes() {
+		if child.GetNodeType() == CommentNode || child.GetNodeType() == ProcessingInstructionNode {
+			continue
+		}
+		textContent += child.GetTextContent()
+	}
+	return textContent
+}
+
+// attributeList returns the attributes of the Node in order, as a slice which stays the
+// same when the attributes are changed while iterating over it.
+func attributeList(n Node) []Node {
+	attrs := n.GetAttributes()
+	if attrs == nil {
+		return nil
+	}
+	list := make([]Node, attrs.Length())
+	for i := range list {
+		list[i] = attrs.Item(i)
+	}
+	return list
+}
+
+// sortedAttributes returns the attributes of the Node sorted by name, so they can be
+// visited in a predictable order.
+func sortedAttributes(n Node) []Node {
+	if n.GetAttributes() == nil {
+		return nil
+	}
+	attrs := attributeList(n)
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].GetNodeName() < attrs[j].GetNodeName()
+	})
+	return attrs
+}
+
+// treeParent returns the parent of the Node in the tree. This is the parent node, except
+// for attributes and XPath namespace nodes, which belong to their owner element.
+func treeParent(n Node) Node {
+	switch t := n.(type) {
+	case Attr:
+		if owner := t.GetOwnerElement(); owner != nil {
+			return owner
+		}
+		return nil
+	case XPathNamespace:
+		return t.GetOwnerElement()
+	}
+	return n.GetParentNode()
+}
+
+// treeRoot returns the top-most ancestor of the Node, or the Node itself if it has no parent.
+func treeRoot(n Node) Node {
+	for parent := treeParent(n); parent != nil; parent = treeParent(n) {
+		n = parent
+	}
+	return n
+}
+
+// sortDocumentOrder sorts the nodes in document order, as defined by compareDocumentPosition.
+// An element is followed by its namespace nodes (sorted by prefix), its attributes (sorted
+// by name) and then by its children. Nodes of different trees are grouped
+// per tree. Rather than comparing the nodes pairwise, the trees are visited once to rank
+// the nodes, which is a lot cheaper for large node sets.
+func sortDocumentOrder(nodes []Node) {
+	if len(nodes) < 2 {
+		return
+	}
+
+	var roots []Node
+	namespaces := make(map[Node][]Node)
+	for _, n := range nodes {
+		// An Attr satisfies the XPathNamespace interface as well, so check the node type.
+		if ns, ok := n.(XPathNamespace); ok && n.GetNodeType() == XPathNamespaceNode {
+			namespaces[ns.GetOwnerElement()] = append(namespaces[ns.GetOwnerElement()], ns)
+		}
+		if root := treeRoot(n); indexOf(roots, root) < 0 {
+			roots = append(roots, root)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return sequenceOf(roots[i]) < sequenceOf(roots[j])
+	})
+
+	// Rank every node of the trees by visiting them in document order.
+	rank := make(map[Node]int)
+	var traverse func(n Node)
+	traverse = func(n Node) {
+		rank[n] = len(rank)
+		nsNodes := namespaces[n]
+		sort.Slice(nsNodes, func(i, j int) bool {
+			return nsNodes[i].GetNodeName() < nsNodes[j].GetNodeName()
+		})
+		for _, ns := range nsNodes {
+			rank[ns] = len(rank)
+		}
+		for _, attr := range sortedAttributes(n) {
+			rank[attr] = len(rank)
+		}
+		for _, child := range n.GetChildNodes() {
+			traverse(child)
+		}
+	}
+	for _, root := range roots {
+		traverse(root)
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return rank[nodes[i]] < rank[nodes[j]]
+	})
+}
+
+// nodeState is the state the DOM keeps for every Node, apart from its place in the tree.
+// Since it is part of the node itself, it moves along when the node is adopted by another
+// Document, and it is released together with the node.
+type nodeState struct {
+	userData      []*userDataEntry        // The user data, in the order the keys were added.
+	registrations []*mutationRegistration // The MutationObservers registered at the node.
+	listeners     []*eventListenerEntry   // The event listeners, in the order they were added.
+	sequence      uint64                  // Orders disconnected trees, see sequenceOf.
+}
+
+// lastSequence is the last sequence number handed out by sequenceOf.
+var lastSequence uint64
+
+// sequenceOf returns the sequence number of the Node n, which is assigned the first time it
+// is asked for. The roots of disconnected trees have no order of their own, so they are
+// ordered by their sequence number, which stays the same for as long as the node lives.
+func sequenceOf(n Node) uint64 {
+	state := n.getState()
+	if state.sequence == 0 {
+		state.sequence = atomic.AddUint64(&lastSequence, 1)
+	}
+	return state.sequence
+}
+
+// liveDocument returns the Document which keeps track of the live objects for the Node
+// n, which is either n itself or its owner document. It returns nil if there is none.
+func liveDocument(n Node) *domDocument {
+	if doc, ok := n.(*domDocument); ok {
+		return doc
+	}
+	doc, _ := n.GetOwnerDocument().(*domDocument)
+	return doc
+}
+
+// notifyRemoving notifies the owner document of the parent Node that the child is about
+// to be removed from it, so the live objects of the Document can be updated.
+func notifyRemoving(parent, child Node) {
+	if doc := liveDocument(parent); doc != nil {
+		doc.nodeRemoving(child)
+	}
+}
+
+// notifyInserted notifies the owner document of the parent Node that the child has just
+// been inserted into it, so the live objects of the Document can be updated.
+func notifyInserted(parent, child Node) {
+	if doc := liveDocument(parent); doc != nil {
+		doc.nodeInserted(child)
+	}
+}
+
+// compareTreeOrder returns -1 if the Node a precedes the Node b in document order, 1 if
+// it follows b, or 0 if both are the same Node.
+func compareTreeOrder(a, b Node) int {
+	position := compareDocumentPosition(a, b)
+	switch {
+	case position == 0:
+		return 0
+	case position&DocumentPositionFollowing != 0:
+		return -1
+	}
+	return 1
+}
+
+// compareDocumentPosition implements Node.CompareDocumentPosition. See
+// https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#Node3-compareDocumentPosition
+func compareDocumentPosition(n, other Node) DocumentPosition {
+	if n == other {
+		return 0
+	}
+	if other == nil {
+		return DocumentPositionDisconnected | DocumentPositionImplementationSpecific
+	}
+
+	pathA, pathB := treePath(n), treePath(other)
+	if pathA[0] != pathB[0] {
+		// The order of the trees is arbitrary, but must be consistent.
+		if sequenceOf(pathA[0]) > sequenceOf(pathB[0]) {
+			return DocumentPositionDisconnected | DocumentPositionImplementationSpecific | DocumentPositionPreceding
+		}
+		return DocumentPositionDisconnected | DocumentPositionImplementationSpecific | DocumentPositionFollowing
+	}
+
+	i := 1
+	for i < len(pathA) && i < len(pathB) && pathA[i] == pathB[i] {
+		i++
+	}
+	if i == len(pathB) {
+		return DocumentPositionContains | DocumentPositionPreceding
+	}
+	if i == len(pathA) {
+		return DocumentPositionContainedBy | DocumentPositionFollowing
+	}
+
+	var position DocumentPosition
+	a, b := pathA[i], pathB[i]
+	if a.GetNodeType() == b.GetNodeType() && (a.GetNodeType() == AttributeNode || a.GetNodeType() == XPathNamespaceNode) {
+		// The order of the attributes of an element is not defined by the specification.
+		position = DocumentPositionImplementationSpecific
+	}
+	if compareSiblings(pathA[i-1], a, b) < 0 {
+		return position | DocumentPositionFollowing
+	}
+	return position | DocumentPositionPreceding
+}
+
+// compareSiblings compares two different nodes which share the parent in the tree. The
+// namespace nodes of an element come first, sorted by prefix, followed by the attributes,
+// sorted by name, and then by the children.
+func compareSiblings(parent, a, b Node) int {
+	kind := func(n Node) int {
+		switch n.GetNodeType() {
+		case XPathNamespaceNode:
+			return 0
+		case AttributeNode:
+			return 1
+		}
+		return 2
+	}
+	if kind(a) != kind(b) {
+		return kind(a) - kind(b)
+	}
+
+	if kind(a) < 2 {
+		return strings.Compare(a.GetNodeName(), b.GetNodeName())
+	}
+	children := parent.GetChildNodes()
+	return indexOf(children, a) - indexOf(children, b)
+}
+
+// treePath returns the inclusive ancestors of the Node n in the tree, starting at the root.
+// The ancestors of attributes and namespace nodes include their owner element.
+func treePath(n Node) []Node {
+	var path []Node
+	for ; n != nil; n = treeParent(n) {
+		path = append(path, n)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// normalize merges the adjacent Text nodes in the subtree of the Node n, and removes the
+// empty ones. CDATA sections are neither merged nor removed. The subtrees of entity
+// references are left as they are, since these are read only.
+func normalize(n Node) {
+	if isReadOnly(n) {
+		return
+	}
+	var previous Text
+	for _, child := range append([]Node(nil), n.GetChildNodes()...) {
+		if child.GetNodeType() != TextNode {
+			previous = nil
+			normalize(child)
+			continue
+		}
+		text := child.(Text)
+		switch {
+		case text.GetLength() == 0:
+			n.RemoveChild(text)
+			notifyUserDataDeleted(text)
+		case previous != nil:
+			previous.AppendData(text.GetText())
+			n.RemoveChild(text)
+			notifyUserDataDeleted(text)
+		default:
+			previous = text
+		}
+	}
+}
+
+// documentNormalizer holds the state of a single call to NormalizeDocument.
+type documentNormalizer struct {
+	config   *domConfiguration
+	entities NamedNodeMap // The entities of the DocumentType, or nil if there is none.
+	stopped  bool         // True when the DOMErrorHandler stopped the processing.
+}
+
+// report passes a problem about the Node n to the DOMErrorHandler of the configuration, and
+// stops the processing if the handler says so.
+func (dn *documentNormalizer) report(severity ErrorSeverity, errType string, n Node, format string, args ...interface{}) {
+	if !reportError(dn.config.errorHandler, newNodeError(severity, errType, n, format, args...)) {
+		dn.stopped = true
+	}
+}
+
+// normalizeContent applies the parameters comments, cdata-sections, split-cdata-sections and
+// entities of the configuration to the subtree of the Node n.
+func (dn *documentNormalizer) normalizeContent(n Node) {
+	if isReadOnly(n) {
+		return
+	}
+	for _, child := range append([]Node(nil), n.GetChildNodes()...) {
+		if dn.stopped {
+			return
+		}
+		dn.normalizeChild(n, child)
+	}
+}
+
+// normalizeChild applies the configuration to the child of the Node parent, and its subtree.
+// References to the entities which are declared internally are replaced by their expansion,
+// which is normalized in turn.
+func (dn *documentNormalizer) normalizeChild(parent, child Node) {
+	switch child.GetNodeType() {
+	case CommentNode:
+		if !dn.config.getBoolean("comments") {
+			parent.RemoveChild(child)
+			notifyUserDataDeleted(child)
+		}
+	case CDATASectionNode:
+		cdata := child.(CDATASection)
+		switch {
+		case !dn.config.getBoolean("cdata-sections"):
+			parent.ReplaceChild(child.GetOwnerDocument().CreateText(cdata.GetText()), child)
+			notifyUserDataDeleted(child)
+		case !strings.Contains(cdata.GetText(), "]]>"):
+		case dn.config.getBoolean("split-cdata-sections"):
+			splitCDATASection(cdata)
+			dn.report(SeverityWarning, "cdata-sections-splitted", child, "the CDATA section is split at the ]]> marker")
+		default:
+			dn.report(SeverityError, "invalid-data-in-cdata-section", child, "the CDATA section contains the ]]> marker")
+		}
+	case EntityReferenceNode:
+		if dn.config.getBoolean("entities") || dn.entities == nil {
+			return
+		}
+		if entity, ok := dn.entities.GetNamedItem(child.GetNodeName()).(*domEntity); !ok || !entity.internal {
+			// The expansion of external entities is unknown, so these are kept.
+			return
+		}
+		expansion := cloneChildren(child)
+		for _, node := range expansion {
+			parent.InsertBefore(node, child)
+		}
+		parent.RemoveChild(child)
+		notifyUserDataDeleted(child)
+		for _, node := range expansion {
+			dn.normalizeChild(parent, node)
+		}
+	default:
+		dn.normalizeContent(child)
+	}
+}
+
+// checkWellFormed reports the names and character data in the subtree of the Node n which
+// can not be serialized as well-formed XML, for the well-formed parameter.
+func (dn *documentNormalizer) checkWellFormed(n Node) {
+	switch n.GetNodeType() {
+	case ElementNode, EntityReferenceNode:
+		if !XMLName(n.GetNodeName()).IsValid() {
+			dn.report(SeverityError, "wf-invalid-character-in-node-name", n, "'%v' is not a valid name", n.GetNodeName())
+		}
+		for _, attr := range attributeList(n) {
+			if !XMLName(attr.GetNodeName()).IsValid() {
+				dn.report(SeverityError, "wf-invalid-character-in-node-name", attr, "'%v' is not a valid name", attr.GetNodeName())
+			} else if hasInvalidChar(attr.GetNodeValue()) {
+				dn.report(SeverityError, "wf-invalid-character", attr, "the value of attribute '%v' contains an invalid character", attr.GetNodeName())
+			}
+		}
+	case TextNode, CDATASectionNode:
+		if hasInvalidChar(n.GetNodeValue()) {
+			dn.report(SeverityError, "wf-invalid-character", n, "the %v contains an invalid character", n.GetNodeType())
+		}
+	case CommentNode:
+		data := n.GetNodeValue()
+		if hasInvalidChar(data) || strings.Contains(data, "--") || strings.HasSuffix(data, "-") {
+			dn.report(SeverityError, "wf-invalid-character", n, "the comment contains '--' or an invalid character")
+		}
+	case ProcessingInstructionNode:
+		if !XMLName(n.GetNodeName()).IsValid() {
+			dn.report(SeverityError, "wf-invalid-character-in-node-name", n, "'%v' is not a valid target", n.GetNodeName())
+		} else if data := n.GetNodeValue(); hasInvalidChar(data) || strings.Contains(data, "?>") {
+			dn.report(SeverityError, "wf-invalid-character", n, "the processing instruction contains '?>' or an invalid character")
+		}
+	}
+	if n.GetNodeType() == EntityReferenceNode {
+		// The expansion is checked where the entity is declared.
+		return
+	}
+	for _, child := range n.GetChildNodes() {
+		if dn.stopped {
+			return
+		}
+		dn.checkWellFormed(child)
+	}
+}
+
+// splitCDATASection splits the CDATA section at every ]]> marker it contains, since the
+// marker ends a CDATA section in XML. The ]] stays in the first section, and the > moves to
+// the next one.
+func splitCDATASection(cdata CDATASection) {
+	parts := strings.Split(cdata.GetText(), "]]>")
+	if len(parts) == 1 {
+		return
+	}
+	parent, next := cdata.GetParentNode(), cdata.GetNextSibling()
+	cdata.SetText(parts[0] + "]]")
+	for i, part := range parts[1:] {
+		data := ">" + part
+		if i < len(parts)-2 {
+			data += "]]"
+		}
+		parent.InsertBefore(cdata.GetOwnerDocument().CreateCDATASection(data), next)
+	}
+}
+
+// removeWhitespace removes the Text nodes from the subtree of the Node n which only contain
+// whitespace, for the element-content-whitespace parameter of NormalizeDocument.
+func removeWhitespace(n Node) {
+	if isReadOnly(n) {
+		return
+	}
+	for _, child := range append([]Node(nil), n.GetChildNodes()...) {
+		if text, ok := child.(Text); ok && child.GetNodeType() == TextNode && text.IsElementContentWhitespace() {
+			n.RemoveChild(child)
+			notifyUserDataDeleted(child)
+			continue
+		}
+		removeWhitespace(child)
+	}
+}
+
+// removeNamespaceDeclarations removes the xmlns attributes from the elements in the subtree
+// of the Node n, for the namespace-declarations parameter of NormalizeDocument.
+func removeNamespaceDeclarations(n Node) {
+	if isReadOnly(n) {
+		return
+	}
+	if n.GetNodeType() == ElementNode {
+		for _, attr := range attributeList(n) {
+			if name := attr.GetNodeName(); name == "xmlns" || strings.HasPrefix(name, "xmlns:") {
+				n.GetAttributes().RemoveNamedItem(name)
+			}
+		}
+	}
+	for _, child := range n.GetChildNodes() {
+		removeNamespaceDeclarations(child)
+	}
+}
+
+// isSameNode returns true if the Node n and the other Node are the same object.
+func isSameNode(n, other Node) bool {
+	return other != nil && n == other
+}
+
+// isEqualNode implements Node.IsEqualNode. The nodes are equal when the type, name, local
+// name, namespace URI, prefix and value are the same, the attributes are equal regardless
+// of their order, and the children are equal in order. For a DocumentType, the identifiers,
+// internal subset and entities must be equal as well. See
+// https://www.w3.org/TR/2004/REC-DOM-Level-3-Core-20040407/core.html#Node3-isEqualNode
+func isEqualNode(n, other Node) bool {
+	if n == nil || other == nil {
+		return n == other
+	}
+	if n.GetNodeType() != other.GetNodeType() ||
+		n.GetNodeName() != other.GetNodeName() ||
+		n.GetLocalName() != other.GetLocalName() ||
+		n.GetNamespaceURI() != other.GetNamespaceURI() ||
+		n.GetNamespacePrefix() != other.GetNamespacePrefix() ||
+		n.GetNodeValue() != other.GetNodeValue() {
+		return false
+	}
+
+	switch n.GetNodeType() {
+	case DocumentTypeNode:
+		dt, odt := n.(DocumentType), other.(DocumentType)
+		if dt.GetPublicID() != odt.GetPublicID() ||
+			dt.GetSystemID() != odt.GetSystemID() ||
+			dt.GetInternalSubset() != odt.GetInternalSubset() ||
+			!isEqualNamedNodeMap(dt.GetEntities(), odt.GetEntities()) {
+			return false
+		}
+	case EntityNode:
+		e, oe := n.(Entity), other.(Entity)
+		if e.GetPublicID() != oe.GetPublicID() ||
+			e.GetSystemID() != oe.GetSystemID() ||
+			e.GetNotationName() != oe.GetNotationName() {
+			return false
+		}
+	}
+
+	if !isEqualNamedNodeMap(n.GetAttributes(), other.GetAttributes()) {
+		return false
+	}
+	children, otherChildren := n.GetChildNodes(), other.GetChildNodes()
+	if len(children) != len(otherChildren) {
+		return false
+	}
+	for i := range children {
+		if !isEqualNode(children[i], otherChildren[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEqualNamedNodeMap returns true if both maps have the same length, and every node in one
+// map has an equal node in the other map, by the same name.
+func isEqualNamedNodeMap(m, other NamedNodeMap) bool {
+	length, otherLength := 0, 0
+	if m != nil {
+		length = m.Length()
+	}
+	if other != nil {
+		otherLength = other.Length()
+	}
+	if length != otherLength {
+		return false
+	}
+	for i := 0; i < length; i++ {
+		n := m.Item(i)
+		if !isEqualNode(n, other.GetNamedItem(n.GetNodeName())) {
+			return false
+		}
+	}
+	return true
+}
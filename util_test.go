@@ -1,6 +1,8 @@
 package dom
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -84,3 +86,156 @@ func TestUtilEscape(t *testing.T) {
 // 		}
 // 	}
 // }
+
+func TestNormalize(t *testing.T) {
+	doc := mustParse(t, `<!DOCTYPE r [<!ENTITY e "ent">]><r>a<x>b</x><![CDATA[c]]>d&e;</r>`)
+	root := doc.GetDocumentElement()
+	x := root.GetChildNodes()[1].(Element)
+	ref := root.GetLastChild()
+
+	// Add adjacent and empty text nodes.
+	root.InsertBefore(doc.CreateText("1"), x)
+	root.InsertBefore(doc.CreateText(""), x)
+	root.InsertBefore(doc.CreateText("2"), x)
+	x.AppendChild(doc.CreateText(""))
+	x.AppendChild(doc.CreateText("3"))
+	root.InsertBefore(doc.CreateText("4"), ref)
+	root.AppendChild(doc.CreateText(""))
+	root.AppendChild(doc.CreateText("5"))
+	root.AppendChild(doc.CreateCDATASection(""))
+
+	doc.NormalizeDocument()
+
+	var children []string
+	for _, c := range root.GetChildNodes() {
+		children = append(children, fmt.Sprintf("%v:%v", c.GetNodeName(), c.GetNodeValue()))
+	}
+	expected := "#text:a12 x: #cdata-section:c #text:d4 e: #text:5 #cdata-section:"
+	if actual := strings.Join(children, " "); actual != expected {
+		t.Errorf("expected '%v', got '%v'", expected, actual)
+	}
+	if len(x.GetChildNodes()) != 1 || x.GetFirstChild().GetNodeValue() != "b3" {
+		t.Errorf("expected the children of x to be merged, got %v", x.GetChildNodes())
+	}
+	if len(ref.GetChildNodes()) != 1 {
+		t.Errorf("expected the entity reference to be unchanged, got %v", ref.GetChildNodes())
+	}
+}
+
+func TestIsEqualNode(t *testing.T) {
+	const xml = `<!DOCTYPE r [<!ENTITY e "ent">]><r xmlns:p="urn:p" a="1" b="2"><p:x>text&e;<![CDATA[c]]></p:x><!--c--><?pi data?></r>`
+	var tests = []struct {
+		other    string
+		expected bool
+	}{
+		{xml, true},
+		// Attributes are compared regardless of their order.
+		{`<!DOCTYPE r [<!ENTITY e "ent">]><r b="2" xmlns:p="urn:p" a="1"><p:x>text&e;<![CDATA[c]]></p:x><!--c--><?pi data?></r>`, true},
+		{`<!DOCTYPE r [<!ENTITY e "ent">]><r xmlns:p="urn:p" a="1" b="3"><p:x>text&e;<![CDATA[c]]></p:x><!--c--><?pi data?></r>`, false},
+		{`<!DOCTYPE r [<!ENTITY e "ent">]><r xmlns:p="urn:p" a="1"><p:x>text&e;<![CDATA[c]]></p:x><!--c--><?pi data?></r>`, false},
+		{`<!DOCTYPE r [<!ENTITY e "ent">]><r xmlns:p="urn:q" a="1" b="2"><p:x>text&e;<![CDATA[c]]></p:x><!--c--><?pi data?></r>`, false},
+		{`<!DOCTYPE r [<!ENTITY e "other">]><r xmlns:p="urn:p" a="1" b="2"><p:x>text&e;<![CDATA[c]]></p:x><!--c--><?pi data?></r>`, false},
+		{`<!DOCTYPE r [<!ENTITY e "ent">]><r xmlns:p="urn:p" a="1" b="2"><p:x>text&e;c</p:x><!--c--><?pi data?></r>`, false},
+		{`<!DOCTYPE r [<!ENTITY e "ent">]><r xmlns:p="urn:p" a="1" b="2"><p:x>text&e;<![CDATA[c]]></p:x><?pi data?><!--c--></r>`, false},
+		{`<!DOCTYPE r [<!ENTITY e "ent">]><r xmlns:p="urn:p" a="1" b="2"><p:x>text&e;<![CDATA[c]]></p:x><!--c--><?pi other?></r>`, false},
+		{`<r xmlns:p="urn:p" a="1" b="2"><p:x>text<![CDATA[c]]></p:x><!--c--><?pi data?></r>`, false},
+	}
+
+	doc := mustParse(t, xml)
+	for _, test := range tests {
+		other := mustParse(t, test.other)
+		if actual := doc.IsEqualNode(other); actual != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.other, test.expected, actual)
+		}
+		if actual := other.IsEqualNode(doc); actual != test.expected {
+			t.Errorf("%v: expected %v the other way around, got %v", test.other, test.expected, actual)
+		}
+	}
+
+	// A clone is equal, but not the same.
+	root := doc.GetDocumentElement()
+	clone := root.CloneNode(true)
+	if !root.IsEqualNode(clone) || root.IsSameNode(clone) || !root.IsSameNode(root) {
+		t.Error("expected the clone to be equal, but not the same")
+	}
+	if root.IsEqualNode(nil) || root.IsSameNode(nil) {
+		t.Error("expected a node not to be equal to nil")
+	}
+	clone.(Element).SetAttribute("b", "changed")
+	if root.IsEqualNode(clone) {
+		t.Error("expected the changed clone not to be equal")
+	}
+}
+
+func TestCompareDocumentPosition(t *testing.T) {
+	doc := mustParse(t, `<r><a x="1" y="2"><b/></a><c/></r>`)
+	r := doc.GetDocumentElement()
+	a := r.GetFirstChild().(Element)
+	b := a.GetFirstChild()
+	c := r.GetLastChild()
+	x := a.GetAttributeNode("x")
+	y := a.GetAttributeNode("y")
+
+	var tests = []struct {
+		n, other Node
+		expected DocumentPosition
+	}{
+		{a, a, 0},
+		{a, c, DocumentPositionFollowing},
+		{c, a, DocumentPositionPreceding},
+		{b, c, DocumentPositionFollowing},
+		{r, b, DocumentPositionContainedBy | DocumentPositionFollowing},
+		{b, doc, DocumentPositionContains | DocumentPositionPreceding},
+		{a, x, DocumentPositionContainedBy | DocumentPositionFollowing},
+		{x, a, DocumentPositionContains | DocumentPositionPreceding},
+		{x, r, DocumentPositionContains | DocumentPositionPreceding},
+		{x, b, DocumentPositionFollowing},
+		{b, x, DocumentPositionPreceding},
+		{x, c, DocumentPositionFollowing},
+		{x, y, DocumentPositionImplementationSpecific | DocumentPositionFollowing},
+		{y, x, DocumentPositionImplementationSpecific | DocumentPositionPreceding},
+	}
+	for _, test := range tests {
+		if actual := test.n.CompareDocumentPosition(test.other); actual != test.expected {
+			t.Errorf("%v, %v: expected %v, got %v", test.n, test.other, test.expected, actual)
+		}
+	}
+
+	// Disconnected nodes are ordered consistently, in both directions.
+	detached := mustCreateElement(doc, "detached")
+	other := NewDocument()
+	for _, n := range []Node{detached, other} {
+		forward, backward := b.CompareDocumentPosition(n), n.CompareDocumentPosition(b)
+		if forward&DocumentPositionDisconnected == 0 || forward&DocumentPositionImplementationSpecific == 0 {
+			t.Errorf("%v: expected a disconnected position, got %v", n, forward)
+		}
+		if forward&(DocumentPositionPreceding|DocumentPositionFollowing) == backward&(DocumentPositionPreceding|DocumentPositionFollowing) {
+			t.Errorf("%v: expected opposite positions, got %v and %v", n, forward, backward)
+		}
+		if position := b.CompareDocumentPosition(n); position != forward {
+			t.Errorf("%v: expected the same position every time, got %v and %v", n, forward, position)
+		}
+	}
+	// The trees are ordered by the first time they are compared, not by their address.
+	first, second := mustCreateElement(doc, "first"), mustCreateElement(doc, "second")
+	if position := first.CompareDocumentPosition(second); position&DocumentPositionFollowing == 0 {
+		t.Errorf("expected the second tree to follow the first, got %v", position)
+	}
+	child := mustCreateElement(doc, "child")
+	second.AppendChild(child)
+	if position := child.CompareDocumentPosition(first); position&DocumentPositionPreceding == 0 {
+		t.Errorf("expected the first tree to precede the second, got %v", position)
+	}
+	if position := b.CompareDocumentPosition(nil); position&DocumentPositionDisconnected == 0 {
+		t.Errorf("expected nil to be disconnected, got %v", position)
+	}
+
+	// Sorting the nodes agrees with comparing them.
+	nodes := []Node{c, y, detached, b, r, x, doc, a}
+	sortDocumentOrder(nodes)
+	for i := 1; i < len(nodes); i++ {
+		if nodes[i-1].CompareDocumentPosition(nodes[i])&DocumentPositionFollowing == 0 {
+			t.Errorf("expected %v to follow %v, in %v", nodes[i], nodes[i-1], nodes)
+		}
+	}
+}
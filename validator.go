@@ -0,0 +1,281 @@
+package dom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ValidationError is a violation of a constraint declared in the DTD, found by the
+// Validator.
+type ValidationError struct {
+	Node    Node   // The Node violating the constraint.
+	Message string // Description of the violation.
+}
+
+func (ve *ValidationError) Error() string {
+	return ve.Message
+}
+
+// ValidationErrors contains every violation found while validating. It is returned as
+// an error by the Parser when validation is enabled.
+type ValidationErrors []*ValidationError
+
+func (ve ValidationErrors) Error() string {
+	if len(ve) == 1 {
+		return ve[0].Error()
+	}
+	return fmt.Sprintf("%v (and %d more validation errors)", ve[0], len(ve)-1)
+}
+
+// Validator validates a Document, or a subtree of a Document, against the element and
+// attribute-list declarations in the DTD of the Document.
+type Validator struct {
+	Resolver DTDResolver // Reads the external subset of the DTD, if it was not read yet. Optional.
+}
+
+// NewValidator creates a new Validator without a resolver.
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// validation holds the state of a single call to Validate.
+type validation struct {
+	doctype *domDocumentType
+	ids     map[string]int // The number of elements having a given ID, in the whole Document.
+	errors  ValidationErrors
+}
+
+// Validate validates the given Node and its descendants, and returns every violation
+// found. When validating a Document, the document element must match the name of the
+// DTD. Attribute defaults from the DTD are applied to the elements along the way. An
+// error is only returned if the external subset of the DTD could not be read.
+func (v *Validator) Validate(n Node) (ValidationErrors, error) {
+	doc, ok := n.(Document)
+	if !ok {
+		doc = n.GetOwnerDocument()
+	}
+	var doctype *domDocumentType
+	if doc != nil {
+		doctype, _ = doc.GetDoctype().(*domDocumentType)
+	}
+	if doctype == nil {
+		return ValidationErrors{{Node: n, Message: "the document has no DTD"}}, nil
+	}
+	if err := doctype.loadExternalSubset(v.Resolver); err != nil {
+		return nil, err
+	}
+
+	vd := &validation{doctype: doctype, ids: make(map[string]int)}
+
+	// IDs must be unique in the whole tree, and IDREFs may refer to any element in the
+	// tree, even when only validating a part of it.
+	top := n
+	for top.GetParentNode() != nil {
+		top = top.GetParentNode()
+	}
+	vd.collectIDs(top)
+
+	if n == doc {
+		root := doc.GetDocumentElement()
+		if root == nil {
+			vd.report(doc, "the document has no document element")
+		} else if root.GetNodeName() != doctype.GetName() {
+			vd.report(root, "the document element '%v' does not match the DTD name '%v'", root.GetNodeName(), doctype.GetName())
+		}
+	}
+	vd.validate(n)
+
+	return vd.errors, nil
+}
+
+// report adds a violation for the given Node.
+func (vd *validation) report(n Node, format string, args ...interface{}) {
+	vd.errors = append(vd.errors, &ValidationError{Node: n, Message: fmt.Sprintf(format, args...)})
+}
+
+// collectIDs counts the values of the attributes declared as ID in the tree.
+func (vd *validation) collectIDs(n Node) {
+	if elem, ok := n.(Element); ok {
+		for _, decl := range vd.doctype.attributes[elem.GetNodeName()] {
+			if attr := elem.GetAttributes().GetNamedItem(decl.name); attr != nil && decl.attrType == "ID" {
+				vd.ids[decl.normalize(attr.GetNodeValue())]++
+			}
+		}
+	}
+	for _, child := range n.GetChildNodes() {
+		vd.collectIDs(child)
+	}
+}
+
+// validate validates the Node if it is an Element, and all of its descendants.
+func (vd *validation) validate(n Node) {
+	if elem, ok := n.(Element); ok {
+		vd.validateContent(elem)
+		vd.doctype.applyAttributeDecls(elem)
+		vd.validateAttributes(elem)
+	}
+	for _, child := range n.GetChildNodes() {
+		vd.validate(child)
+	}
+}
+
+// validateContent checks the children of the element against its declaration.
+func (vd *validation) validateContent(elem Element) {
+	decl := vd.doctype.elements[elem.GetNodeName()]
+	if decl == nil {
+		vd.report(elem, "element '%v' is not declared", elem.GetNodeName())
+		return
+	}
+
+	// Collect the child elements, and whether there is any character data which is not
+	// whitespace. Entity references are replaced by their expansion.
+	var names []string
+	var text bool
+	var collect func(n Node)
+	collect = func(n Node) {
+		for _, child := range n.GetChildNodes() {
+			switch child.GetNodeType() {
+			case ElementNode:
+				names = append(names, child.GetNodeName())
+			case CDATASectionNode:
+				text = true
+			case TextNode:
+				text = text || strings.TrimSpace(child.GetNodeValue()) != ""
+			case EntityReferenceNode:
+				collect(child)
+			}
+		}
+	}
+	collect(elem)
+
+	switch decl.contentType {
+	case contentEmpty:
+		if elem.HasChildNodes() {
+			vd.report(elem, "element '%v' must be empty", elem.GetNodeName())
+		}
+	case contentMixed:
+		for _, name := range names {
+			if !contains(decl.mixed, name) {
+				vd.report(elem, "element '%v' is not allowed in element '%v'", name, elem.GetNodeName())
+			}
+		}
+	case contentChildren:
+		if text {
+			vd.report(elem, "character data is not allowed in element '%v'", elem.GetNodeName())
+		}
+		if !decl.model.matches(names) {
+			vd.report(elem, "the content of element '%v' does not match %v", elem.GetNodeName(), decl.model)
+		}
+	}
+}
+
+// validateAttributes checks the attributes of the element against the attribute-list
+// declaration of the element.
+func (vd *validation) validateAttributes(elem Element) {
+	name := elem.GetNodeName()
+
+	// Sort the attributes, so the violations are reported in a predictable order.
+	var attrNames []string
+	for attrName := range elem.GetAttributes().GetItems() {
+		attrNames = append(attrNames, attrName)
+	}
+	sort.Strings(attrNames)
+
+	for _, attrName := range attrNames {
+		attr := elem.GetAttributes().GetNamedItem(attrName).(Attr)
+		// Namespace declarations are not subject to validation.
+		if attr.GetNamespacePrefix() == "xmlns" || attrName == "xmlns" {
+			continue
+		}
+		decl := vd.doctype.getAttributeDecl(name, attrName)
+		if decl == nil {
+			vd.report(attr, "attribute '%v' of element '%v' is not declared", attrName, name)
+			continue
+		}
+		vd.validateValue(attr, decl)
+	}
+
+	for _, decl := range vd.doctype.attributes[name] {
+		if decl.mode == "#REQUIRED" && elem.GetAttributes().GetNamedItem(decl.name) == nil {
+			vd.report(elem, "attribute '%v' is required on element '%v'", decl.name, name)
+		}
+	}
+}
+
+// validateValue checks the value of the attribute against its declared type.
+func (vd *validation) validateValue(attr Attr, decl *attributeDecl) {
+	value := attr.GetValue()
+	if decl.mode == "#FIXED" && value != decl.value {
+		vd.report(attr, "attribute '%v' must have the fixed value '%v'", decl.name, decl.value)
+	}
+
+	switch decl.attrType {
+	case "ID":
+		if !XMLName(value).IsValid() {
+			vd.report(attr, "ID '%v' of attribute '%v' is not a valid name", value, decl.name)
+		} else if vd.ids[value] > 1 {
+			vd.report(attr, "ID '%v' of attribute '%v' is not unique", value, decl.name)
+		}
+	case "IDREF", "IDREFS":
+		for _, ref := range strings.Fields(value) {
+			if vd.ids[ref] == 0 {
+				vd.report(attr, "attribute '%v' refers to the unknown ID '%v'", decl.name, ref)
+			}
+		}
+	case "ENTITY", "ENTITIES":
+		for _, name := range strings.Fields(value) {
+			entity, ok := vd.doctype.entities.GetNamedItem(name).(Entity)
+			if !ok || entity.GetNotationName() == "" {
+				vd.report(attr, "attribute '%v' refers to '%v', which is not an unparsed entity", decl.name, name)
+			}
+		}
+	case "NMTOKEN", "NMTOKENS":
+		for _, token := range strings.Fields(value) {
+			if !isNmtoken(token) {
+				vd.report(attr, "value '%v' of attribute '%v' is not a valid name token", token, decl.name)
+			}
+		}
+	case "ENUMERATION", "NOTATION":
+		if !contains(decl.values, value) {
+			vd.report(attr, "value '%v' of attribute '%v' is not one of %v", value, decl.name, decl.values)
+		}
+	}
+
+	// Single valued types must have exactly one token, the others at least one.
+	switch decl.attrType {
+	case "IDREF", "ENTITY", "NMTOKEN":
+		if len(strings.Fields(value)) != 1 {
+			vd.report(attr, "attribute '%v' must have a single value", decl.name)
+		}
+	case "IDREFS", "ENTITIES", "NMTOKENS":
+		if len(strings.Fields(value)) == 0 {
+			vd.report(attr, "attribute '%v' must have at least one value", decl.name)
+		}
+	}
+}
+
+// isNmtoken returns true if the string is a valid name token, which is a name that may
+// start with any of the name characters.
+func isNmtoken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.Is(nameStartChars, r) && !unicode.Is(nameChars, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains returns true if the slice contains the string.
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
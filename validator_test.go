@@ -0,0 +1,291 @@
+package dom
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+var exampleDocValid = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE book [
+  <!ELEMENT book (title, chapter+, appendix?)>
+  <!ELEMENT title (#PCDATA)>
+  <!ELEMENT chapter (#PCDATA | ref | br)*>
+  <!ELEMENT appendix ANY>
+  <!ELEMENT ref EMPTY>
+  <!ELEMENT br EMPTY>
+  <!ATTLIST book
+      version CDATA #FIXED "1.0"
+      lang    (en|nl) "en">
+  <!ATTLIST chapter
+      id      ID     #REQUIRED
+      kind    NMTOKEN "normal">
+  <!ATTLIST ref
+      to      IDREF  #REQUIRED>
+]>
+<book>
+  <title>The book</title>
+  <chapter id="one" kind="  intro ">First<br/>chapter</chapter>
+  <chapter id="two">See <ref to="one"/></chapter>
+</book>`
+
+func TestContentParticleMatches(t *testing.T) {
+	var tests = []struct {
+		model    string
+		names    string
+		expected bool
+	}{
+		{"(a)", "a", true},
+		{"(a)", "", false},
+		{"(a,b)", "a b", true},
+		{"(a,b)", "b a", false},
+		{"(a|b)", "b", true},
+		{"(a|b)", "a b", false},
+		{"(a*,b)", "a a a b", true},
+		{"(a*,b)", "b", true},
+		{"(a+,b)", "b", false},
+		{"(a?,b?)", "", true},
+		{"(a,(b|c)*,d?)+", "a b c c a d", true},
+		{"(a,(b|c)*,d?)+", "a d d", false},
+		{"((a,b)*,a)", "a b a b a", true},
+		{"((a?)*)", "a a", true},
+	}
+
+	for _, test := range tests {
+		s := &dtdScanner{input: test.model}
+		cp, err := s.particle()
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.model, err)
+			continue
+		}
+		if cp.String() != test.model {
+			t.Errorf("expected '%v', got '%v'", test.model, cp)
+		}
+		if actual := cp.matches(strings.Fields(test.names)); actual != test.expected {
+			t.Errorf("%v matching '%v': expected %v, got %v", test.model, test.names, test.expected, actual)
+		}
+	}
+}
+
+func TestValidatorValid(t *testing.T) {
+	doc := mustParse(t, exampleDocValid)
+
+	violations, err := NewValidator().Validate(doc)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(violations) > 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+}
+
+func TestValidatorAttributeDefaults(t *testing.T) {
+	doc := mustParse(t, exampleDocValid)
+
+	book := doc.GetDocumentElement()
+	version := book.GetAttributes().GetNamedItem("version").(Attr)
+	if version.GetValue() != "1.0" || version.IsSpecified() {
+		t.Errorf("expected a default attribute which is not specified, got '%v'", version)
+	}
+	if version.GetOwnerElement() != book {
+		t.Error("expected the default attribute to be owned by the element")
+	}
+	if book.GetAttribute("lang") != "en" {
+		t.Errorf("expected 'en', got '%v'", book.GetAttribute("lang"))
+	}
+
+	chapters := doc.GetElementsByTagName("chapter")
+	kind := chapters[0].GetAttributes().GetNamedItem("kind").(Attr)
+	if kind.GetValue() != "intro" || !kind.IsSpecified() {
+		t.Errorf("expected the specified, normalized value 'intro', got '%v'", kind)
+	}
+	if chapters[1].GetAttribute("kind") != "normal" {
+		t.Errorf("expected 'normal', got '%v'", chapters[1].GetAttribute("kind"))
+	}
+
+	id := chapters[0].GetAttributes().GetNamedItem("id").(Attr)
+	if !id.IsId() {
+		t.Error("expected the attribute to be an ID")
+	}
+	if kind.IsId() {
+		t.Error("expected the attribute not to be an ID")
+	}
+
+	// Setting the value explicitly makes the attribute specified.
+	version.SetValue("1.0")
+	if !version.IsSpecified() {
+		t.Error("expected the attribute to be specified")
+	}
+}
+
+func TestValidatorViolations(t *testing.T) {
+	input := `<!DOCTYPE book [
+  <!ELEMENT book (title, chapter+)>
+  <!ELEMENT title (#PCDATA)>
+  <!ELEMENT chapter (#PCDATA | ref)*>
+  <!ELEMENT ref EMPTY>
+  <!ATTLIST book version CDATA #FIXED "1.0" lang (en|nl) #IMPLIED>
+  <!ATTLIST chapter id ID #REQUIRED>
+  <!ATTLIST ref to IDREF #REQUIRED>
+]>
+<book version="2.0" lang="de" extra="yes">
+  text
+  <chapter id="one"><title/></chapter>
+  <chapter id="one"><ref to="two">no</ref></chapter>
+  <chapter/>
+  <unknown/>
+</book>`
+	doc := mustParse(t, input)
+
+	violations, err := NewValidator().Validate(doc)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+
+	expected := []string{
+		"character data is not allowed in element 'book'",
+		"the content of element 'book' does not match (title,chapter+)",
+		"attribute 'extra' of element 'book' is not declared",
+		"value 'de' of attribute 'lang' is not one of [en nl]",
+		"attribute 'version' must have the fixed value '1.0'",
+		"element 'title' is not allowed in element 'chapter'",
+		"ID 'one' of attribute 'id' is not unique",
+		"ID 'one' of attribute 'id' is not unique",
+		"element 'ref' must be empty",
+		"attribute 'to' refers to the unknown ID 'two'",
+		"attribute 'id' is required on element 'chapter'",
+		"element 'unknown' is not declared",
+	}
+	if len(violations) != len(expected) {
+		t.Errorf("expected %d violations, got %d: %v", len(expected), len(violations), violations)
+		t.FailNow()
+	}
+	for i, v := range violations {
+		if v.Message != expected[i] {
+			t.Errorf("expected '%v', got '%v'", expected[i], v.Message)
+		}
+		if v.Node == nil {
+			t.Errorf("expected a node for '%v'", v.Message)
+		}
+	}
+
+	// The offending node is reported.
+	if violations[2].Node.GetNodeName() != "extra" || violations[2].Node.GetNodeType() != AttributeNode {
+		t.Errorf("expected the attribute 'extra', got '%v'", violations[2].Node)
+	}
+	if violations[11].Node.GetNodeName() != "unknown" {
+		t.Errorf("expected the element 'unknown', got '%v'", violations[11].Node)
+	}
+}
+
+func TestValidatorSubtree(t *testing.T) {
+	doc := mustParse(t, exampleDocValid)
+
+	// Build a chapter in memory, referring to an ID outside of the subtree.
+	chapter, _ := doc.CreateElement("chapter")
+	ref, _ := doc.CreateElement("ref")
+	ref.SetAttribute("to", "two")
+	chapter.AppendChild(ref)
+	doc.GetDocumentElement().AppendChild(chapter)
+
+	violations, _ := NewValidator().Validate(chapter)
+	if len(violations) != 1 || violations[0].Message != "attribute 'id' is required on element 'chapter'" {
+		t.Errorf("unexpected violations %v", violations)
+	}
+	if chapter.GetAttribute("kind") != "normal" {
+		t.Error("expected default attributes to be applied to the subtree")
+	}
+
+	violations, _ = NewValidator().Validate(NewDocument())
+	if len(violations) != 1 || violations[0].Message != "the document has no DTD" {
+		t.Errorf("unexpected violations %v", violations)
+	}
+}
+
+func TestValidatorExternalSubset(t *testing.T) {
+	input := `<!DOCTYPE note SYSTEM "note.dtd" [<!ATTLIST note lang CDATA "nl">]><note lang="en" to="me">&greeting;</note>`
+	external := `<?xml version="1.0" encoding="UTF-8"?>
+<!ELEMENT note (#PCDATA)>
+<!ATTLIST note
+    to   CDATA #REQUIRED
+    lang CDATA "en"
+    kind CDATA "memo">
+<!ENTITY greeting "hello">`
+
+	var resolved []string
+	parser := NewParser(strings.NewReader(input))
+	parser.Configuration.Validate = true
+	parser.Resolver = DTDResolverFunc(func(publicID, systemID string) (io.Reader, error) {
+		resolved = append(resolved, systemID)
+		return strings.NewReader(external), nil
+	})
+	doc, err := parser.Parse()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	if len(resolved) != 1 || resolved[0] != "note.dtd" {
+		t.Errorf("expected the external subset to be resolved once, got %v", resolved)
+	}
+
+	note := doc.GetDocumentElement()
+	if note.GetTextContent() != "hello" {
+		t.Errorf("expected the entity from the external subset to be expanded, got '%v'", note.GetTextContent())
+	}
+	if note.GetAttribute("kind") != "memo" {
+		t.Errorf("expected 'memo', got '%v'", note.GetAttribute("kind"))
+	}
+
+	// Failing resolvers fail the parsing.
+	parser = NewParser(strings.NewReader(input))
+	parser.Resolver = DTDResolverFunc(func(publicID, systemID string) (io.Reader, error) {
+		return nil, errors.New("not found")
+	})
+	if _, err := parser.Parse(); err == nil {
+		t.Error("expected error, got none")
+	}
+}
+
+func TestParserValidate(t *testing.T) {
+	input := `<!DOCTYPE root [<!ELEMENT root EMPTY>]><root>not empty</root>`
+	parser := NewParser(strings.NewReader(input))
+	parser.Configuration.Validate = true
+	_, err := parser.Parse()
+
+	violations, ok := err.(ValidationErrors)
+	if !ok {
+		t.Errorf("expected validation errors, got '%v'", err)
+		t.FailNow()
+	}
+	if len(violations) != 1 || violations[0].Node.GetNodeName() != "root" {
+		t.Errorf("unexpected violations %v", violations)
+	}
+
+	// Without validation, the document is fine.
+	if _, err := NewParser(strings.NewReader(input)).Parse(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDTDMalformedDeclarations(t *testing.T) {
+	var subsets = []string{
+		`<!ELEMENT a>`,
+		`<!ELEMENT a (b|c,d)>`,
+		`<!ELEMENT a (#PCDATA|b)>`,
+		`<!ELEMENT a (b,c>`,
+		`<!ATTLIST a b WHAT #IMPLIED>`,
+		`<!ATTLIST a b (x|y #IMPLIED>`,
+		`<!ATTLIST a b CDATA #FIXED>`,
+		`<!ATTLIST a b NOTATION(x) #IMPLIED>`,
+		`<![INCLUDE[ <!ELEMENT a ANY> ]]>`,
+	}
+
+	for _, subset := range subsets {
+		input := `<!DOCTYPE a [` + subset + `]><a/>`
+		if _, err := NewParser(strings.NewReader(input)).Parse(); err == nil {
+			t.Errorf("%v: expected error, got none", subset)
+		}
+	}
+}
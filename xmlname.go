@@ -1,6 +1,7 @@
 package dom
 
 import (
+	"fmt"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -107,3 +108,60 @@ var nameChars = &unicode.RangeTable{
 		{0x203F, 0x2040, 1},
 	},
 }
+
+// checkQualifiedName checks whether the qualified name is well-formed, and whether its
+// prefix fits the namespace URI. It returns an ErrorInvalidCharacter for a name with an
+// invalid character, or an ErrorNamespace when the namespace constraints are violated.
+func checkQualifiedName(namespaceURI, qualifiedName string) error {
+	if err := checkQName(qualifiedName); err != nil {
+		return err
+	}
+	name := XMLName(qualifiedName)
+	prefix := name.GetPrefix()
+
+	switch {
+	case prefix != "" && namespaceURI == "":
+		return fmt.Errorf("%v: the prefix '%v' has no namespace URI", ErrorNamespace, prefix)
+	case prefix == "xml" && namespaceURI != XMLNamespaceURI:
+		return fmt.Errorf("%v: the prefix 'xml' must have the namespace URI '%v'", ErrorNamespace, XMLNamespaceURI)
+	case (qualifiedName == "xmlns" || prefix == "xmlns") && namespaceURI != XMLNSNamespaceURI:
+		return fmt.Errorf("%v: the name '%v' must have the namespace URI '%v'", ErrorNamespace, qualifiedName, XMLNSNamespaceURI)
+	case namespaceURI == XMLNSNamespaceURI && qualifiedName != "xmlns" && prefix != "xmlns":
+		return fmt.Errorf("%v: the namespace URI '%v' is reserved for the xmlns prefix", ErrorNamespace, XMLNSNamespaceURI)
+	}
+	return nil
+}
+
+// checkQName checks whether the qualified name is well-formed, without regard to namespaces.
+// It returns an ErrorInvalidCharacter for a name with an invalid character, or an ErrorNamespace
+// when it has more than one colon, or an empty prefix or local name.
+func checkQName(qualifiedName string) error {
+	name := XMLName(qualifiedName)
+	if !name.IsValid() {
+		return fmt.Errorf("%v: '%v'", ErrorInvalidCharacter, qualifiedName)
+	}
+	prefix, localName := name.GetPrefix(), name.GetLocalPart()
+	if strings.Contains(qualifiedName, ":") && (prefix == "" || localName == "" || strings.Contains(localName, ":")) {
+		return fmt.Errorf("%v: '%v' is not a qualified name", ErrorNamespace, qualifiedName)
+	}
+	return nil
+}
+
+// isXMLChar returns true if the rune is a Char as defined by the XML specification, which
+// excludes most control characters, the surrogates, and U+FFFE and U+FFFF.
+func isXMLChar(r rune) bool {
+	return r == 0x9 || r == 0xA || r == 0xD ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= 0x10FFFF)
+}
+
+// hasInvalidChar returns true if the string contains a rune which is not an XML Char.
+func hasInvalidChar(s string) bool {
+	for _, r := range s {
+		if !isXMLChar(r) {
+			return true
+		}
+	}
+	return false
+}
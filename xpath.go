@@ -0,0 +1,314 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+)
+
+// This file contains the public API of the XPath 1.0 implementation, modelled after the
+// DOM Level 3 XPath specification: https://www.w3.org/TR/DOM-Level-3-XPath/xpath.html
+
+// ErrorInvalidExpression is returned when an expression is not a legal XPath expression,
+// for example due to syntax errors, unknown functions or unbound variables.
+var ErrorInvalidExpression = errors.New("INVALID_EXPRESSION_ERR: the expression is not a legal XPath expression")
+
+// ErrorType is returned when the result of an expression can not be converted to the
+// requested type, or when a value is requested which does not match the result type.
+var ErrorType = errors.New("TYPE_ERR: the expression result can not be converted to the requested type")
+
+// XPathResultType defines the types of results an XPath expression can return.
+type XPathResultType uint8
+
+// Enumeration of the result types. XPathAnyType lets the expression decide what type
+// is returned, which is one of the number, string, boolean or unordered node iterator
+// types.
+const (
+	XPathAnyType XPathResultType = iota
+	XPathNumberType
+	XPathStringType
+	XPathBooleanType
+	XPathUnorderedNodeIteratorType
+	XPathOrderedNodeIteratorType
+	XPathUnorderedNodeSnapshotType
+	XPathOrderedNodeSnapshotType
+	XPathAnyUnorderedNodeType
+	XPathFirstOrderedNodeType
+)
+
+// String returns the string representation of the XPathResultType, using the default
+// representation by the W3 specification.
+func (t XPathResultType) String() string {
+	switch t {
+	case XPathAnyType:
+		return "ANY_TYPE"
+	case XPathNumberType:
+		return "NUMBER_TYPE"
+	case XPathStringType:
+		return "STRING_TYPE"
+	case XPathBooleanType:
+		return "BOOLEAN_TYPE"
+	case XPathUnorderedNodeIteratorType:
+		return "UNORDERED_NODE_ITERATOR_TYPE"
+	case XPathOrderedNodeIteratorType:
+		return "ORDERED_NODE_ITERATOR_TYPE"
+	case XPathUnorderedNodeSnapshotType:
+		return "UNORDERED_NODE_SNAPSHOT_TYPE"
+	case XPathOrderedNodeSnapshotType:
+		return "ORDERED_NODE_SNAPSHOT_TYPE"
+	case XPathAnyUnorderedNodeType:
+		return "ANY_UNORDERED_NODE_TYPE"
+	case XPathFirstOrderedNodeType:
+		return "FIRST_ORDERED_NODE_TYPE"
+	default:
+		return "???"
+	}
+}
+
+// XPathEvaluator evaluates XPath expressions against any Node as context.
+type XPathEvaluator interface {
+	// CreateExpression compiles the expression, so it can be evaluated more than once.
+	// Prefixes in the expression are resolved using the resolver, which may be nil if
+	// the expression contains no prefixes.
+	CreateExpression(expression string, resolver XPathNSResolver) (XPathExpression, error)
+	// CreateNSResolver creates a resolver which resolves prefixes like the given Node
+	// does, using its LookupNamespaceURI method.
+	CreateNSResolver(nodeResolver Node) XPathNSResolver
+	// Evaluate compiles and evaluates the expression in one go, and returns the result
+	// of the given type.
+	Evaluate(expression string, contextNode Node, resolver XPathNSResolver, resultType XPathResultType) (XPathResult, error)
+}
+
+// XPathExpression is a compiled XPath expression, which can be evaluated more than once.
+type XPathExpression interface {
+	// Evaluate evaluates the expression with the given Node as context, and returns the
+	// result of the given type.
+	Evaluate(contextNode Node, resultType XPathResultType) (XPathResult, error)
+}
+
+// XPathNSResolver resolves the prefixes in an XPath expression to namespace URIs. Any Node
+// satisfies this interface, so a Node can be used as a resolver directly.
+type XPathNSResolver interface {
+	LookupNamespaceURI(prefix string) (string, bool)
+}
+
+// XPathResult is the result of evaluating an XPath expression. The getters return an
+// ErrorType when the value does not match the result type.
+type XPathResult interface {
+	GetResultType() XPathResultType       // Returns the type of the result.
+	GetNumberValue() (float64, error)     // Returns the value of a number result.
+	GetStringValue() (string, error)      // Returns the value of a string result.
+	GetBooleanValue() (bool, error)       // Returns the value of a boolean result.
+	GetSingleNodeValue() (Node, error)    // Returns the node of a single node result, or nil if there is none.
+	GetInvalidIteratorState() bool        // Returns true if the iterator has become invalid.
+	GetSnapshotLength() (int, error)      // Returns the number of nodes in a snapshot result.
+	IterateNext() (Node, error)           // Returns the next node of an iterator result, or nil if there are no more.
+	SnapshotItem(index int) (Node, error) // Returns the node at the index of a snapshot result, or nil if out of range.
+}
+
+// XPathNamespace represents a namespace node, as returned by the namespace axis. These
+// nodes are created by the XPath implementation, and are not part of the Document. Their
+// parent is nil, the element they belong to is returned by GetOwnerElement.
+type XPathNamespace interface {
+	Node
+
+	GetOwnerElement() Element // Returns the Element on which the namespace is in scope.
+}
+
+type domXPathEvaluator struct{}
+
+// NewXPathEvaluator creates a new XPathEvaluator.
+func NewXPathEvaluator() XPathEvaluator {
+	return &domXPathEvaluator{}
+}
+
+func (e *domXPathEvaluator) CreateExpression(expression string, resolver XPathNSResolver) (XPathExpression, error) {
+	expr, err := compileXPath(expression, resolver)
+	if err != nil {
+		return nil, err
+	}
+	return &domXPathExpression{expression: expression, expr: expr}, nil
+}
+
+func (e *domXPathEvaluator) CreateNSResolver(nodeResolver Node) XPathNSResolver {
+	return &nodeNSResolver{node: nodeResolver}
+}
+
+func (e *domXPathEvaluator) Evaluate(expression string, contextNode Node, resolver XPathNSResolver, resultType XPathResultType) (XPathResult, error) {
+	expr, err := e.CreateExpression(expression, resolver)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Evaluate(contextNode, resultType)
+}
+
+// nodeNSResolver resolves prefixes using a Node. The xml prefix is always bound.
+type nodeNSResolver struct {
+	node Node
+}
+
+func (r *nodeNSResolver) LookupNamespaceURI(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return XMLNamespaceURI, true
+	}
+	// Attributes look up prefixes using their owner element.
+	node := r.node
+	if attr, ok := node.(Attr); ok && attr.GetOwnerElement() != nil {
+		node = attr.GetOwnerElement()
+	}
+	return node.LookupNamespaceURI(prefix)
+}
+
+type domXPathExpression struct {
+	expression string
+	expr       xpathExpr
+}
+
+func (x *domXPathExpression) Evaluate(contextNode Node, resultType XPathResultType) (XPathResult, error) {
+	if contextNode == nil {
+		return nil, fmt.Errorf("%v: the context node is nil", ErrorNotSupported)
+	}
+	switch contextNode.GetNodeType() {
+	case ElementNode, AttributeNode, TextNode, CDATASectionNode, ProcessingInstructionNode,
+		CommentNode, DocumentNode, DocumentFragmentNode, XPathNamespaceNode:
+	default:
+		return nil, fmt.Errorf("%v: %v can not be used as the context node", ErrorNotSupported, contextNode.GetNodeType())
+	}
+
+	ctx := &xpathContext{node: xpathNode(contextNode), position: 1, size: 1, namespaces: make(map[xpathNamespaceKey]Node)}
+	value, err := x.expr.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newXPathResult(value, resultType)
+}
+
+func (x *domXPathExpression) String() string {
+	return x.expression
+}
+
+// domXPathResult holds the value of an evaluated expression. Node-sets are always in
+// document order, so the ordered and unordered result types are the same. Iterators
+// iterate over the nodes which were found at evaluation time.
+type domXPathResult struct {
+	resultType XPathResultType
+	number     float64
+	str        string
+	boolean    bool
+	nodes      []Node
+	next       int // The index of the next node of an iterator.
+}
+
+// newXPathResult converts the value to the requested type, and wraps it in an XPathResult.
+func newXPathResult(value interface{}, resultType XPathResultType) (XPathResult, error) {
+	nodes, isNodeSet := value.([]Node)
+	if resultType == XPathAnyType {
+		switch value.(type) {
+		case float64:
+			resultType = XPathNumberType
+		case string:
+			resultType = XPathStringType
+		case bool:
+			resultType = XPathBooleanType
+		default:
+			resultType = XPathUnorderedNodeIteratorType
+		}
+	}
+
+	r := &domXPathResult{resultType: resultType}
+	switch resultType {
+	case XPathNumberType:
+		r.number = xpathToNumber(value)
+	case XPathStringType:
+		r.str = xpathToString(value)
+	case XPathBooleanType:
+		r.boolean = xpathToBoolean(value)
+	case XPathUnorderedNodeIteratorType, XPathOrderedNodeIteratorType, XPathUnorderedNodeSnapshotType,
+		XPathOrderedNodeSnapshotType, XPathAnyUnorderedNodeType, XPathFirstOrderedNodeType:
+		if !isNodeSet {
+			return nil, fmt.Errorf("%v: the expression does not result in a node-set", ErrorType)
+		}
+		r.nodes = nodes
+	default:
+		return nil, fmt.Errorf("%v: unknown result type %d", ErrorType, resultType)
+	}
+	return r, nil
+}
+
+func (r *domXPathResult) GetResultType() XPathResultType {
+	return r.resultType
+}
+
+// check returns an ErrorType if the result is not one of the given types.
+func (r *domXPathResult) check(types ...XPathResultType) error {
+	for _, t := range types {
+		if r.resultType == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v: the result is of type %v", ErrorType, r.resultType)
+}
+
+func (r *domXPathResult) GetNumberValue() (float64, error) {
+	return r.number, r.check(XPathNumberType)
+}
+
+func (r *domXPathResult) GetStringValue() (string, error) {
+	return r.str, r.check(XPathStringType)
+}
+
+func (r *domXPathResult) GetBooleanValue() (bool, error) {
+	return r.boolean, r.check(XPathBooleanType)
+}
+
+func (r *domXPathResult) GetSingleNodeValue() (Node, error) {
+	if err := r.check(XPathAnyUnorderedNodeType, XPathFirstOrderedNodeType); err != nil {
+		return nil, err
+	}
+	if len(r.nodes) == 0 {
+		return nil, nil
+	}
+	return r.nodes[0], nil
+}
+
+// GetInvalidIteratorState always returns false, since iterators iterate over the nodes
+// found at evaluation time, which can not become invalid.
+func (r *domXPathResult) GetInvalidIteratorState() bool {
+	return false
+}
+
+func (r *domXPathResult) GetSnapshotLength() (int, error) {
+	return len(r.nodes), r.check(XPathUnorderedNodeSnapshotType, XPathOrderedNodeSnapshotType)
+}
+
+func (r *domXPathResult) IterateNext() (Node, error) {
+	if err := r.check(XPathUnorderedNodeIteratorType, XPathOrderedNodeIteratorType); err != nil {
+		return nil, err
+	}
+	if r.next >= len(r.nodes) {
+		return nil, nil
+	}
+	r.next++
+	return r.nodes[r.next-1], nil
+}
+
+func (r *domXPathResult) SnapshotItem(index int) (Node, error) {
+	if err := r.check(XPathUnorderedNodeSnapshotType, XPathOrderedNodeSnapshotType); err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(r.nodes) {
+		return nil, nil
+	}
+	return r.nodes[index], nil
+}
+
+func (r *domXPathResult) String() string {
+	switch r.resultType {
+	case XPathNumberType:
+		return fmt.Sprintf("%v: %v", r.resultType, r.number)
+	case XPathStringType:
+		return fmt.Sprintf("%v: '%v'", r.resultType, r.str)
+	case XPathBooleanType:
+		return fmt.Sprintf("%v: %v", r.resultType, r.boolean)
+	}
+	return fmt.Sprintf("%v: %d nodes", r.resultType, len(r.nodes))
+}
@@ -0,0 +1,767 @@
+package dom
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// This file contains the evaluation of compiled XPath expressions. The values of XPath
+// expressions are represented by the Go types []Node (node-set), string, float64 (number)
+// and bool (boolean).
+//
+// The XPath data model differs slightly from the DOM: entity references are transparent,
+// i.e. their children are regarded as children of their parent, adjacent Text and
+// CDATASection nodes form a single text node (represented by the first one), and the
+// DocumentType is not part of the tree at all.
+
+// xpathExpr is a compiled XPath expression, or a part of one.
+type xpathExpr interface {
+	eval(ctx *xpathContext) (interface{}, error)
+}
+
+// xpathContext is the context in which an expression is evaluated.
+type xpathContext struct {
+	node     Node
+	position int
+	size     int
+
+	// Namespace nodes created during the evaluation, so the same namespace of the same
+	// element is always represented by the same Node.
+	namespaces map[xpathNamespaceKey]Node
+}
+
+type xpathNamespaceKey struct {
+	element Element
+	prefix  string
+}
+
+// with returns a new context for the given node, position and size.
+func (ctx *xpathContext) with(node Node, position, size int) *xpathContext {
+	return &xpathContext{node: node, position: position, size: size, namespaces: ctx.namespaces}
+}
+
+type xpathLiteralExpr string
+
+func (e xpathLiteralExpr) eval(ctx *xpathContext) (interface{}, error) {
+	return string(e), nil
+}
+
+type xpathNumberExpr float64
+
+func (e xpathNumberExpr) eval(ctx *xpathContext) (interface{}, error) {
+	return float64(e), nil
+}
+
+// xpathNegate is the unary minus operator.
+type xpathNegate struct {
+	expr xpathExpr
+}
+
+func (e *xpathNegate) eval(ctx *xpathContext) (interface{}, error) {
+	v, err := e.expr.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return -xpathToNumber(v), nil
+}
+
+// xpathBinary is any of the binary operators, including the union operator |.
+type xpathBinary struct {
+	op    string
+	left  xpathExpr
+	right xpathExpr
+}
+
+func (e *xpathBinary) eval(ctx *xpathContext) (interface{}, error) {
+	left, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The boolean operators only evaluate the right operand when necessary.
+	switch e.op {
+	case "or":
+		if xpathToBoolean(left) {
+			return true, nil
+		}
+	case "and":
+		if !xpathToBoolean(left) {
+			return false, nil
+		}
+	}
+
+	right, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "or", "and":
+		return xpathToBoolean(right), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return xpathCompare(e.op, left, right), nil
+	case "+":
+		return xpathToNumber(left) + xpathToNumber(right), nil
+	case "-":
+		return xpathToNumber(left) - xpathToNumber(right), nil
+	case "*":
+		return xpathToNumber(left) * xpathToNumber(right), nil
+	case "div":
+		return xpathToNumber(left) / xpathToNumber(right), nil
+	case "mod":
+		return math.Mod(xpathToNumber(left), xpathToNumber(right)), nil
+	case "|":
+		leftNodes, ok1 := left.([]Node)
+		rightNodes, ok2 := right.([]Node)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%v: the operands of | must be node-sets", ErrorType)
+		}
+		return xpathUnion(leftNodes, rightNodes), nil
+	}
+	return nil, fmt.Errorf("%v: unknown operator '%v'", ErrorInvalidExpression, e.op)
+}
+
+// xpathCall is a call of a function from the core function library.
+type xpathCall struct {
+	name string
+	fn   *xpathFunction
+	args []xpathExpr
+}
+
+func (e *xpathCall) eval(ctx *xpathContext) (interface{}, error) {
+	args := make([]interface{}, len(e.args))
+	for i, arg := range e.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := e.fn.call(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("%v(): %v", e.name, err)
+	}
+	return v, nil
+}
+
+// xpathFilter is a primary expression followed by predicates.
+type xpathFilter struct {
+	expr       xpathExpr
+	predicates []xpathExpr
+}
+
+func (e *xpathFilter) eval(ctx *xpathContext) (interface{}, error) {
+	v, err := e.expr.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := v.([]Node)
+	if !ok {
+		return nil, fmt.Errorf("%v: predicates can only be applied to node-sets", ErrorType)
+	}
+	for _, predicate := range e.predicates {
+		if nodes, err = xpathPredicate(ctx, nodes, predicate); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// xpathPath is a location path, which starts at the root, the context node, or at the
+// nodes resulting from a filter expression.
+type xpathPath struct {
+	filter   xpathExpr
+	absolute bool
+	steps    []*xpathStep
+}
+
+func (e *xpathPath) eval(ctx *xpathContext) (interface{}, error) {
+	var nodes []Node
+	switch {
+	case e.filter != nil:
+		v, err := e.filter.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if nodes, ok = v.([]Node); !ok {
+			return nil, fmt.Errorf("%v: a location path can only be applied to a node-set", ErrorType)
+		}
+	case e.absolute:
+		nodes = []Node{xpathRoot(ctx.node)}
+	default:
+		nodes = []Node{ctx.node}
+	}
+
+	for _, step := range e.steps {
+		var err error
+		if nodes, err = step.apply(ctx, nodes); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// xpathStep is a single step in a location path, like child::para[1].
+type xpathStep struct {
+	axis       string
+	test       xpathNodeTest
+	predicates []xpathExpr
+}
+
+// apply applies the step to every node in the input, and returns the union of the results
+// in document order.
+func (s *xpathStep) apply(ctx *xpathContext, input []Node) ([]Node, error) {
+	axis := xpathAxes[s.axis]
+	principal := ElementNode
+	switch s.axis {
+	case "attribute":
+		principal = AttributeNode
+	case "namespace":
+		principal = XPathNamespaceNode
+	}
+
+	var result []Node
+	seen := make(map[Node]bool)
+	for _, n := range input {
+		// Select the nodes on the axis, in the order of the axis.
+		var selected []Node
+		for _, candidate := range axis(ctx, n) {
+			if s.test.matches(candidate, principal) {
+				selected = append(selected, candidate)
+			}
+		}
+
+		for _, predicate := range s.predicates {
+			var err error
+			if selected, err = xpathPredicate(ctx, selected, predicate); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, node := range selected {
+			if !seen[node] {
+				seen[node] = true
+				result = append(result, node)
+			}
+		}
+	}
+
+	sortDocumentOrder(result)
+	return result, nil
+}
+
+// xpathPredicate filters the nodes using the predicate. The nodes must be in the order
+// of the axis, which determines the position of each node. A number as the result of the
+// predicate is true if it equals the position.
+func xpathPredicate(ctx *xpathContext, nodes []Node, predicate xpathExpr) ([]Node, error) {
+	var result []Node
+	for i, n := range nodes {
+		v, err := predicate.eval(ctx.with(n, i+1, len(nodes)))
+		if err != nil {
+			return nil, err
+		}
+		if number, ok := v.(float64); ok {
+			if number == float64(i+1) {
+				result = append(result, n)
+			}
+		} else if xpathToBoolean(v) {
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
+
+// xpathNodeTest is either a name test, or a node type test when nodeType is not empty.
+type xpathNodeTest struct {
+	nodeType     string // comment, text, processing-instruction or node.
+	target       string // The literal of processing-instruction('target').
+	namespaceURI string // The namespace URI of the name test.
+	name         string // The local name of the name test, or * for any.
+}
+
+// matches returns true if the node matches the test. Name tests only match nodes of
+// the principal node type of the axis.
+func (t xpathNodeTest) matches(n Node, principal NodeType) bool {
+	switch t.nodeType {
+	case "node":
+		return true
+	case "text":
+		return n.GetNodeType() == TextNode || n.GetNodeType() == CDATASectionNode
+	case "comment":
+		return n.GetNodeType() == CommentNode
+	case "processing-instruction":
+		return n.GetNodeType() == ProcessingInstructionNode && (t.target == "" || n.GetNodeName() == t.target)
+	}
+
+	if n.GetNodeType() != principal {
+		return false
+	}
+	// The test * matches any name, in any namespace.
+	if t.name == "*" && t.namespaceURI == "" {
+		return true
+	}
+	if n.GetNamespaceURI() != t.namespaceURI {
+		return false
+	}
+	return t.name == "*" || n.GetLocalName() == t.name
+}
+
+// xpathAxes contains the axes by name. Each returns the nodes on the axis in the order
+// of the axis, which is reverse document order for the reverse axes.
+var xpathAxes = map[string]func(ctx *xpathContext, n Node) []Node{
+	"ancestor": func(ctx *xpathContext, n Node) []Node {
+		return xpathAncestors(n)
+	},
+	"ancestor-or-self": func(ctx *xpathContext, n Node) []Node {
+		return append([]Node{n}, xpathAncestors(n)...)
+	},
+	"attribute": func(ctx *xpathContext, n Node) []Node {
+		return xpathAttributes(n)
+	},
+	"child": func(ctx *xpathContext, n Node) []Node {
+		return xpathChildren(n)
+	},
+	"descendant": func(ctx *xpathContext, n Node) []Node {
+		return xpathDescendants(n, nil)
+	},
+	"descendant-or-self": func(ctx *xpathContext, n Node) []Node {
+		return xpathDescendants(n, []Node{n})
+	},
+	"following": func(ctx *xpathContext, n Node) []Node {
+		var nodes []Node
+		// The following nodes of an attribute or namespace include the descendants of
+		// its element.
+		if parent := xpathParent(n); n.GetNodeType() == AttributeNode || n.GetNodeType() == XPathNamespaceNode {
+			nodes = xpathDescendants(parent, nil)
+			n = parent
+		}
+		for ; n != nil; n = xpathParent(n) {
+			for _, sibling := range xpathFollowingSiblings(n) {
+				nodes = xpathDescendants(sibling, append(nodes, sibling))
+			}
+		}
+		return nodes
+	},
+	"following-sibling": func(ctx *xpathContext, n Node) []Node {
+		return xpathFollowingSiblings(n)
+	},
+	"namespace": func(ctx *xpathContext, n Node) []Node {
+		return xpathNamespaces(ctx, n)
+	},
+	"parent": func(ctx *xpathContext, n Node) []Node {
+		if parent := xpathParent(n); parent != nil {
+			return []Node{parent}
+		}
+		return nil
+	},
+	"preceding": func(ctx *xpathContext, n Node) []Node {
+		var nodes []Node
+		if n.GetNodeType() == AttributeNode || n.GetNodeType() == XPathNamespaceNode {
+			n = xpathParent(n)
+		}
+		for ; n != nil; n = xpathParent(n) {
+			for _, sibling := range xpathPrecedingSiblings(n) {
+				// The descendants of the sibling precede the sibling itself, in reverse order.
+				descendants := xpathDescendants(sibling, nil)
+				for i := len(descendants) - 1; i >= 0; i-- {
+					nodes = append(nodes, descendants[i])
+				}
+				nodes = append(nodes, sibling)
+			}
+		}
+		return nodes
+	},
+	"preceding-sibling": func(ctx *xpathContext, n Node) []Node {
+		return xpathPrecedingSiblings(n)
+	},
+	"self": func(ctx *xpathContext, n Node) []Node {
+		return []Node{n}
+	},
+}
+
+// xpathNode returns the Node representing the given Node in the XPath data model. This
+// is the node itself, except for text nodes: the first of adjacent text nodes represents
+// them all.
+func xpathNode(n Node) Node {
+	if !isTextNode(n) {
+		return n
+	}
+	siblings := flatChildren(xpathParent(n))
+	i := indexOf(siblings, n)
+	for i > 0 && isTextNode(siblings[i-1]) {
+		i--
+	}
+	if i < 0 {
+		return n
+	}
+	return siblings[i]
+}
+
+// isTextNode returns true for Text and CDATASection nodes.
+func isTextNode(n Node) bool {
+	return n.GetNodeType() == TextNode || n.GetNodeType() == CDATASectionNode
+}
+
+// xpathParent returns the parent in the XPath data model: entity references are skipped,
+// and attributes and namespaces have their element as parent.
+func xpathParent(n Node) Node {
+	parent := treeParent(n)
+	for parent != nil && parent.GetNodeType() == EntityReferenceNode {
+		parent = parent.GetParentNode()
+	}
+	return parent
+}
+
+// xpathRoot returns the root node of the tree containing the node.
+func xpathRoot(n Node) Node {
+	for parent := xpathParent(n); parent != nil; parent = xpathParent(n) {
+		n = parent
+	}
+	return n
+}
+
+// xpathAncestors returns the ancestors of the node, nearest first.
+func xpathAncestors(n Node) []Node {
+	var nodes []Node
+	for parent := xpathParent(n); parent != nil; parent = xpathParent(parent) {
+		nodes = append(nodes, parent)
+	}
+	return nodes
+}
+
+// flatChildren returns the children of the node, where entity references are replaced
+// by their children, and the DocumentType is left out.
+func flatChildren(n Node) []Node {
+	if n == nil {
+		return nil
+	}
+	var nodes []Node
+	for _, child := range n.GetChildNodes() {
+		switch child.GetNodeType() {
+		case EntityReferenceNode:
+			nodes = append(nodes, flatChildren(child)...)
+		case DocumentTypeNode:
+		default:
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+// xpathChildren returns the children of the node in the XPath data model.
+func xpathChildren(n Node) []Node {
+	var nodes []Node
+	for _, child := range flatChildren(n) {
+		// Only the first of adjacent text nodes represents the text node.
+		if isTextNode(child) && len(nodes) > 0 && isTextNode(nodes[len(nodes)-1]) {
+			continue
+		}
+		nodes = append(nodes, child)
+	}
+	return nodes
+}
+
+// xpathDescendants appends the descendants of the node to the nodes, in document order.
+func xpathDescendants(n Node, nodes []Node) []Node {
+	for _, child := range xpathChildren(n) {
+		nodes = append(nodes, child)
+		nodes = xpathDescendants(child, nodes)
+	}
+	return nodes
+}
+
+// xpathFollowingSiblings returns the siblings following the node, nearest first.
+func xpathFollowingSiblings(n Node) []Node {
+	if n.GetNodeType() == AttributeNode || n.GetNodeType() == XPathNamespaceNode {
+		return nil
+	}
+	siblings := xpathChildren(xpathParent(n))
+	if i := indexOf(siblings, n); i >= 0 {
+		return siblings[i+1:]
+	}
+	return nil
+}
+
+// xpathPrecedingSiblings returns the siblings preceding the node, nearest first.
+func xpathPrecedingSiblings(n Node) []Node {
+	if n.GetNodeType() == AttributeNode || n.GetNodeType() == XPathNamespaceNode {
+		return nil
+	}
+	siblings := xpathChildren(xpathParent(n))
+	var nodes []Node
+	for i := indexOf(siblings, n) - 1; i >= 0; i-- {
+		nodes = append(nodes, siblings[i])
+	}
+	return nodes
+}
+
+// xpathAttributes returns the attributes of an element, without the namespace declarations.
+func xpathAttributes(n Node) []Node {
+	if n.GetNodeType() != ElementNode {
+		return nil
+	}
+	var nodes []Node
+	for _, attr := range sortedAttributes(n) {
+		if !isNamespaceDeclaration(attr) {
+			nodes = append(nodes, attr)
+		}
+	}
+	return nodes
+}
+
+// isNamespaceDeclaration returns true for xmlns and xmlns:pfx attributes.
+func isNamespaceDeclaration(attr Node) bool {
+	return attr.GetNodeName() == "xmlns" || strings.HasPrefix(attr.GetNodeName(), "xmlns:") || attr.GetNamespaceURI() == XMLNSNamespaceURI
+}
+
+// xpathNamespaces returns the namespace nodes of an element: one for every namespace in
+// scope of the element, sorted by prefix.
+func xpathNamespaces(ctx *xpathContext, n Node) []Node {
+	elem, ok := n.(Element)
+	if !ok {
+		return nil
+	}
+
+	// Collect the declarations, where the declarations nearest to the element win.
+	inScope := map[string]string{"xml": XMLNamespaceURI}
+	var ancestors []Element
+	for e := elem; e != nil; {
+		ancestors = append(ancestors, e)
+		e, _ = xpathParent(e).(Element)
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		e := ancestors[i]
+		if e.GetNamespaceURI() != "" {
+			inScope[e.GetNamespacePrefix()] = e.GetNamespaceURI()
+		}
+		for _, attr := range sortedAttributes(e) {
+			switch {
+			case attr.GetNodeName() == "xmlns":
+				inScope[""] = attr.GetNodeValue()
+			case strings.HasPrefix(attr.GetNodeName(), "xmlns:"):
+				inScope[attr.GetLocalName()] = attr.GetNodeValue()
+			}
+		}
+	}
+
+	var prefixes []string
+	for prefix, uri := range inScope {
+		// An empty default namespace undeclares the default namespace.
+		if uri != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+
+	var nodes []Node
+	for _, prefix := range prefixes {
+		key := xpathNamespaceKey{element: elem, prefix: prefix}
+		if _, ok := ctx.namespaces[key]; !ok {
+			ctx.namespaces[key] = newXPathNamespace(elem, prefix, inScope[prefix])
+		}
+		nodes = append(nodes, ctx.namespaces[key])
+	}
+	return nodes
+}
+
+// xpathUnion returns the union of two node-sets, in document order.
+func xpathUnion(a, b []Node) []Node {
+	seen := make(map[Node]bool)
+	var nodes []Node
+	for _, n := range append(append([]Node{}, a...), b...) {
+		if !seen[n] {
+			seen[n] = true
+			nodes = append(nodes, n)
+		}
+	}
+	sortDocumentOrder(nodes)
+	return nodes
+}
+
+// xpathStringValue returns the string-value of a node.
+func xpathStringValue(n Node) string {
+	switch n.GetNodeType() {
+	case DocumentNode, DocumentFragmentNode, ElementNode, EntityReferenceNode:
+		var b strings.Builder
+		for _, descendant := range xpathDescendants(n, nil) {
+			if isTextNode(descendant) {
+				b.WriteString(xpathStringValue(descendant))
+			}
+		}
+		return b.String()
+	case TextNode, CDATASectionNode:
+		// The text node consists of all adjacent text nodes.
+		siblings := flatChildren(xpathParent(n))
+		i := indexOf(siblings, n)
+		if i < 0 {
+			return n.GetNodeValue()
+		}
+		var b strings.Builder
+		for ; i < len(siblings) && isTextNode(siblings[i]); i++ {
+			b.WriteString(siblings[i].GetNodeValue())
+		}
+		return b.String()
+	}
+	return n.GetNodeValue()
+}
+
+// xpathToString converts a value to a string.
+func xpathToString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		switch {
+		case math.IsNaN(t):
+			return "NaN"
+		case math.IsInf(t, 1):
+			return "Infinity"
+		case math.IsInf(t, -1):
+			return "-Infinity"
+		case t == 0:
+			// Negative zero is formatted as 0 as well.
+			return "0"
+		}
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []Node:
+		if len(t) == 0 {
+			return ""
+		}
+		return xpathStringValue(t[0])
+	}
+	return ""
+}
+
+// xpathToNumber converts a value to a number.
+func xpathToNumber(v interface{}) float64 {
+	switch t := v.(type) {
+	case float64:
+		return t
+	case bool:
+		if t {
+			return 1
+		}
+		return 0
+	case string:
+		return xpathStringToNumber(t)
+	case []Node:
+		return xpathStringToNumber(xpathToString(t))
+	}
+	return math.NaN()
+}
+
+// xpathStringToNumber converts a string to a number. Only an optional minus sign, digits
+// and an optional decimal point are allowed, surrounded by optional whitespace. Anything
+// else is NaN.
+func xpathStringToNumber(s string) float64 {
+	s = strings.Trim(s, " \t\r\n")
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || digits == "." || strings.Trim(digits, "0123456789.") != "" || strings.Count(digits, ".") > 1 {
+		return math.NaN()
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return n
+}
+
+// xpathToBoolean converts a value to a boolean.
+func xpathToBoolean(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case float64:
+		return t != 0 && !math.IsNaN(t)
+	case string:
+		return t != ""
+	case []Node:
+		return len(t) > 0
+	}
+	return false
+}
+
+// xpathCompare compares two values with one of the operators =, !=, <, <=, > or >=. When
+// node-sets are compared, the comparison is true if it is true for any of the nodes.
+func xpathCompare(op string, left, right interface{}) bool {
+	leftNodes, leftIsSet := left.([]Node)
+	rightNodes, rightIsSet := right.([]Node)
+
+	switch {
+	case leftIsSet && rightIsSet:
+		for _, a := range leftNodes {
+			for _, b := range rightNodes {
+				if xpathCompareValues(op, xpathStringValue(a), xpathStringValue(b)) {
+					return true
+				}
+			}
+		}
+		return false
+	case leftIsSet:
+		return xpathCompareNodeSet(op, leftNodes, right)
+	case rightIsSet:
+		// Swap the operands, so the node-set is on the left.
+		swapped := map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<="}
+		if s, ok := swapped[op]; ok {
+			op = s
+		}
+		return xpathCompareNodeSet(op, rightNodes, left)
+	}
+	return xpathCompareValues(op, left, right)
+}
+
+// xpathCompareNodeSet compares a node-set with a value which is not a node-set.
+func xpathCompareNodeSet(op string, nodes []Node, value interface{}) bool {
+	if b, ok := value.(bool); ok {
+		return xpathCompareValues(op, len(nodes) > 0, b)
+	}
+	for _, n := range nodes {
+		var v interface{} = xpathStringValue(n)
+		if _, ok := value.(float64); ok {
+			v = xpathStringToNumber(v.(string))
+		}
+		if xpathCompareValues(op, v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// xpathCompareValues compares two values which are not node-sets.
+func xpathCompareValues(op string, a, b interface{}) bool {
+	if op == "=" || op == "!=" {
+		var equal bool
+		_, aIsBool := a.(bool)
+		_, bIsBool := b.(bool)
+		_, aIsNumber := a.(float64)
+		_, bIsNumber := b.(float64)
+		switch {
+		case aIsBool || bIsBool:
+			equal = xpathToBoolean(a) == xpathToBoolean(b)
+		case aIsNumber || bIsNumber:
+			equal = xpathToNumber(a) == xpathToNumber(b)
+		default:
+			equal = xpathToString(a) == xpathToString(b)
+		}
+		return equal == (op == "=")
+	}
+
+	x, y := xpathToNumber(a), xpathToNumber(b)
+	switch op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package dom
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// This file contains the XPath 1.0 core function library.
+// See https://www.w3.org/TR/1999/REC-xpath-19991116/#corelib
+
+// xpathFunction is a function of the core function library. The arguments are evaluated
+// before the function is called, and their number is checked when compiling.
+type xpathFunction struct {
+	minArgs int
+	maxArgs int // -1 for any number of arguments.
+	call    func(ctx *xpathContext, args []interface{}) (interface{}, error)
+}
+
+// xpathFunctions contains the functions of the core function library by name.
+var xpathFunctions = map[string]*xpathFunction{
+	// Node-set functions.
+	"last": {0, 0, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return float64(ctx.size), nil
+	}},
+	"position": {0, 0, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return float64(ctx.position), nil
+	}},
+	"count": {1, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		nodes, err := xpathNodeSetArg(args[0])
+		return float64(len(nodes)), err
+	}},
+	"id":            {1, 1, xpathID},
+	"local-name":    {0, 1, xpathNameFunction(xpathLocalName)},
+	"namespace-uri": {0, 1, xpathNameFunction(Node.GetNamespaceURI)},
+	"name":          {0, 1, xpathNameFunction(xpathQualifiedName)},
+
+	// String functions.
+	"string": {0, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return xpathToString(xpathArgOrContext(ctx, args)), nil
+	}},
+	"concat": {2, -1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		var b strings.Builder
+		for _, arg := range args {
+			b.WriteString(xpathToString(arg))
+		}
+		return b.String(), nil
+	}},
+	"starts-with": {2, 2, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return strings.HasPrefix(xpathToString(args[0]), xpathToString(args[1])), nil
+	}},
+	"contains": {2, 2, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return strings.Contains(xpathToString(args[0]), xpathToString(args[1])), nil
+	}},
+	"substring-before": {2, 2, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		s, sep := xpathToString(args[0]), xpathToString(args[1])
+		if i := strings.Index(s, sep); i >= 0 {
+			return s[:i], nil
+		}
+		return "", nil
+	}},
+	"substring-after": {2, 2, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		s, sep := xpathToString(args[0]), xpathToString(args[1])
+		if i := strings.Index(s, sep); i >= 0 {
+			return s[i+len(sep):], nil
+		}
+		return "", nil
+	}},
+	"substring": {2, 3, xpathSubstring},
+	"string-length": {0, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return float64(len([]rune(xpathToString(xpathArgOrContext(ctx, args))))), nil
+	}},
+	"normalize-space": {0, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return strings.Join(strings.Fields(xpathToString(xpathArgOrContext(ctx, args))), " "), nil
+	}},
+	"translate": {3, 3, xpathTranslate},
+
+	// Boolean functions.
+	"boolean": {1, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return xpathToBoolean(args[0]), nil
+	}},
+	"not": {1, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return !xpathToBoolean(args[0]), nil
+	}},
+	"true": {0, 0, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return true, nil
+	}},
+	"false": {0, 0, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return false, nil
+	}},
+	"lang": {1, 1, xpathLang},
+
+	// Number functions.
+	"number": {0, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return xpathToNumber(xpathArgOrContext(ctx, args)), nil
+	}},
+	"sum": {1, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		nodes, err := xpathNodeSetArg(args[0])
+		sum := 0.0
+		for _, n := range nodes {
+			sum += xpathStringToNumber(xpathStringValue(n))
+		}
+		return sum, err
+	}},
+	"floor": {1, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return math.Floor(xpathToNumber(args[0])), nil
+	}},
+	"ceiling": {1, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return math.Ceil(xpathToNumber(args[0])), nil
+	}},
+	"round": {1, 1, func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		return xpathRound(xpathToNumber(args[0])), nil
+	}},
+}
+
+// xpathArgOrContext returns the only argument, or a node-set with the context node if
+// there are no arguments.
+func xpathArgOrContext(ctx *xpathContext, args []interface{}) interface{} {
+	if len(args) == 0 {
+		return []Node{ctx.node}
+	}
+	return args[0]
+}
+
+// xpathNodeSetArg returns the argument as a node-set, or an error if it is not.
+func xpathNodeSetArg(arg interface{}) ([]Node, error) {
+	nodes, ok := arg.([]Node)
+	if !ok {
+		return nil, fmt.Errorf("%v: the argument must be a node-set", ErrorType)
+	}
+	return nodes, nil
+}
+
+// xpathNameFunction returns a function which returns the name of the first node in the
+// node-set argument, or of the context node, or an empty string if there is none.
+func xpathNameFunction(name func(n Node) string) func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return func(ctx *xpathContext, args []interface{}) (interface{}, error) {
+		nodes, err := xpathNodeSetArg(xpathArgOrContext(ctx, args))
+		if err != nil || len(nodes) == 0 {
+			return "", err
+		}
+		return name(nodes[0]), nil
+	}
+}
+
+// xpathLocalName returns the local part of the expanded-name of the node.
+func xpathLocalName(n Node) string {
+	switch n.GetNodeType() {
+	case ElementNode, AttributeNode, XPathNamespaceNode:
+		return n.GetLocalName()
+	case ProcessingInstructionNode:
+		return n.GetNodeName()
+	}
+	return ""
+}
+
+// xpathQualifiedName returns the qualified name of the node.
+func xpathQualifiedName(n Node) string {
+	switch n.GetNodeType() {
+	case ElementNode, AttributeNode, XPathNamespaceNode, ProcessingInstructionNode:
+		return n.GetNodeName()
+	}
+	return ""
+}
+
+// xpathID returns the elements with the given IDs. A node-set argument is the union of
+// id() applied to the string-value of each of its nodes, anything else is converted to
+// a string containing whitespace separated IDs.
+func xpathID(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	ids := make(map[string]bool)
+	if nodes, ok := args[0].([]Node); ok {
+		for _, n := range nodes {
+			for _, id := range strings.Fields(xpathStringValue(n)) {
+				ids[id] = true
+			}
+		}
+	} else {
+		for _, id := range strings.Fields(xpathToString(args[0])) {
+			ids[id] = true
+		}
+	}
+
+	var elements []Node
+	for _, n := range xpathDescendants(xpathRoot(ctx.node), nil) {
+		for _, attr := range sortedAttributes(n) {
+			if attr.(Attr).IsId() && ids[attr.GetNodeValue()] {
+				elements = append(elements, n)
+				// Only the first element with an ID is returned.
+				delete(ids, attr.GetNodeValue())
+				break
+			}
+		}
+	}
+	return elements, nil
+}
+
+// xpathSubstring returns the characters of the string starting at the position of the
+// second argument, with the length of the optional third argument. Positions start at 1,
+// and are rounded.
+func xpathSubstring(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	s := []rune(xpathToString(args[0]))
+	start := xpathRound(xpathToNumber(args[1]))
+	end := math.Inf(1)
+	if len(args) == 3 {
+		end = start + xpathRound(xpathToNumber(args[2]))
+	}
+
+	var b strings.Builder
+	for i, r := range s {
+		// Comparisons with NaN are always false, so NaN selects nothing.
+		if p := float64(i + 1); p >= start && p < end {
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+// xpathTranslate replaces the characters of the string which occur in the second argument
+// by the character at the same position in the third argument, or removes them when
+// there is no such character.
+func xpathTranslate(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	from, to := []rune(xpathToString(args[1])), []rune(xpathToString(args[2]))
+	var b strings.Builder
+	for _, r := range xpathToString(args[0]) {
+		i := strings.IndexRune(string(from), r)
+		if i < 0 {
+			b.WriteRune(r)
+			continue
+		}
+		// Only the first occurrence of a character in the second argument counts.
+		if i = len([]rune(string(from)[:i])); i < len(to) {
+			b.WriteRune(to[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// xpathLang returns true if the language of the context node, given by the nearest
+// xml:lang attribute, is the language of the argument, or a sublanguage of it.
+func xpathLang(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	lang := strings.ToLower(xpathToString(args[0]))
+	for n := ctx.node; n != nil; n = xpathParent(n) {
+		for _, attr := range xpathAttributes(n) {
+			if attr.GetNodeName() == "xml:lang" || attr.GetNamespaceURI() == XMLNamespaceURI && attr.GetLocalName() == "lang" {
+				value := strings.ToLower(attr.GetNodeValue())
+				return value == lang || strings.HasPrefix(value, lang+"-"), nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// xpathRound rounds to the closest integer, where halves are rounded towards positive
+// infinity. Negative numbers rounding to zero result in negative zero.
+func xpathRound(n float64) float64 {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return n
+	}
+	if n < 0 && n >= -0.5 {
+		return math.Copysign(0, -1)
+	}
+	return math.Floor(n + 0.5)
+}
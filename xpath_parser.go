@@ -0,0 +1,604 @@
+package dom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file contains the lexer and parser of XPath 1.0 expressions, which compile an
+// expression into a tree of xpathExpr values. See https://www.w3.org/TR/xpath/ for the
+// grammar.
+
+type xpathTokenKind int
+
+const (
+	xpathEOF          xpathTokenKind = iota
+	xpathPunctuation                 // One of ( ) [ ] . .. @ , ::
+	xpathNameTest                    // *, NCName:* or QName.
+	xpathNodeType                    // comment, text, processing-instruction or node, followed by (.
+	xpathOperator                    // and, or, mod, div, /, //, |, +, -, =, !=, <, <=, >, >= or *.
+	xpathFunctionName                // A QName followed by (.
+	xpathAxisName                    // A name followed by ::.
+	xpathLiteral                     // A string enclosed in quotes.
+	xpathNumber                      // A number.
+	xpathVariable                    // A variable reference, like $name.
+)
+
+type xpathToken struct {
+	kind  xpathTokenKind
+	value string
+	pos   int // Offset of the token in the expression, for error messages.
+}
+
+// xpathLexer splits an expression into tokens, applying the disambiguation rules of
+// the XPath spec: whether * is a name test or an operator, and whether a name is an
+// operator, function name, node type or axis name depends on the surrounding tokens.
+type xpathLexer struct {
+	input  string
+	pos    int
+	tokens []xpathToken
+}
+
+// tokenize splits the input into tokens. The last token is always xpathEOF.
+func (l *xpathLexer) tokenize() ([]xpathToken, error) {
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.input) {
+			l.tokens = append(l.tokens, xpathToken{kind: xpathEOF, pos: l.pos})
+			return l.tokens, nil
+		}
+		if err := l.next(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (l *xpathLexer) skipSpace() {
+	for l.pos < len(l.input) && isSpace(l.input[l.pos]) {
+		l.pos++
+	}
+}
+
+func (l *xpathLexer) emit(kind xpathTokenKind, value string, start int) {
+	l.tokens = append(l.tokens, xpathToken{kind: kind, value: value, pos: start})
+}
+
+// operatorExpected returns true if the previous token is such that a * or name must be
+// interpreted as an operator: there is a previous token, and it's not one of @ :: ( [ ,
+// or an operator.
+func (l *xpathLexer) operatorExpected() bool {
+	if len(l.tokens) == 0 {
+		return false
+	}
+	prev := l.tokens[len(l.tokens)-1]
+	switch prev.kind {
+	case xpathOperator:
+		return false
+	case xpathPunctuation:
+		return prev.value == ")" || prev.value == "]" || prev.value == "." || prev.value == ".."
+	}
+	return true
+}
+
+// next scans the next token.
+func (l *xpathLexer) next() error {
+	start := l.pos
+	rest := l.input[l.pos:]
+	c := rest[0]
+
+	switch {
+	case strings.HasPrefix(rest, "::"):
+		l.pos += 2
+		l.emit(xpathPunctuation, "::", start)
+	case strings.HasPrefix(rest, ".."):
+		l.pos += 2
+		l.emit(xpathPunctuation, "..", start)
+	case c == '.' && (len(rest) == 1 || !isDigit(rest[1])):
+		l.pos++
+		l.emit(xpathPunctuation, ".", start)
+	case strings.ContainsRune("()[]@,", rune(c)):
+		l.pos++
+		l.emit(xpathPunctuation, string(c), start)
+	case strings.HasPrefix(rest, "//"):
+		l.pos += 2
+		l.emit(xpathOperator, "//", start)
+	case strings.HasPrefix(rest, "!="), strings.HasPrefix(rest, "<="), strings.HasPrefix(rest, ">="):
+		l.pos += 2
+		l.emit(xpathOperator, rest[:2], start)
+	case strings.ContainsRune("/|+-=<>", rune(c)):
+		l.pos++
+		l.emit(xpathOperator, string(c), start)
+	case c == '*':
+		l.pos++
+		if l.operatorExpected() {
+			l.emit(xpathOperator, "*", start)
+		} else {
+			l.emit(xpathNameTest, "*", start)
+		}
+	case c == '"' || c == '\'':
+		end := strings.IndexByte(rest[1:], c)
+		if end < 0 {
+			return fmt.Errorf("%v: unterminated literal at offset %d", ErrorInvalidExpression, start)
+		}
+		l.pos += end + 2
+		l.emit(xpathLiteral, rest[1:end+1], start)
+	case isDigit(c) || c == '.':
+		for l.pos < len(l.input) && isDigit(l.input[l.pos]) {
+			l.pos++
+		}
+		if l.pos < len(l.input) && l.input[l.pos] == '.' {
+			l.pos++
+			for l.pos < len(l.input) && isDigit(l.input[l.pos]) {
+				l.pos++
+			}
+		}
+		l.emit(xpathNumber, l.input[start:l.pos], start)
+	case c == '$':
+		l.pos++
+		name := l.qname()
+		if name == "" {
+			return fmt.Errorf("%v: expected a variable name at offset %d", ErrorInvalidExpression, start)
+		}
+		l.emit(xpathVariable, name, start)
+	default:
+		return l.name()
+	}
+	return nil
+}
+
+// name scans a name, which can be an operator name, a name test, a node type, a function
+// name or an axis name.
+func (l *xpathLexer) name() error {
+	start := l.pos
+	ncname := l.ncname()
+	if ncname == "" {
+		r, _ := utf8.DecodeRuneInString(l.input[l.pos:])
+		return fmt.Errorf("%v: unexpected '%c' at offset %d", ErrorInvalidExpression, r, start)
+	}
+
+	if l.operatorExpected() {
+		switch ncname {
+		case "and", "or", "mod", "div":
+			l.emit(xpathOperator, ncname, start)
+			return nil
+		}
+		return fmt.Errorf("%v: expected an operator at offset %d", ErrorInvalidExpression, start)
+	}
+
+	// An axis name is followed by ::
+	if strings.HasPrefix(l.input[l.pos:], "::") {
+		l.emit(xpathAxisName, ncname, start)
+		return nil
+	}
+
+	// The name may be a QName, or a name test like pfx:*
+	name := ncname
+	if strings.HasPrefix(l.input[l.pos:], ":") && !strings.HasPrefix(l.input[l.pos:], "::") {
+		l.pos++
+		if strings.HasPrefix(l.input[l.pos:], "*") {
+			l.pos++
+			l.emit(xpathNameTest, name+":*", start)
+			return nil
+		}
+		local := l.ncname()
+		if local == "" {
+			return fmt.Errorf("%v: expected a local name at offset %d", ErrorInvalidExpression, l.pos)
+		}
+		name += ":" + local
+	}
+
+	// Look ahead: a name followed by ( is a node type or a function name.
+	end := l.pos
+	l.skipSpace()
+	if strings.HasPrefix(l.input[l.pos:], "(") {
+		switch name {
+		case "comment", "text", "processing-instruction", "node":
+			l.emit(xpathNodeType, name, start)
+		default:
+			l.emit(xpathFunctionName, name, start)
+		}
+		return nil
+	}
+	l.pos = end
+	l.emit(xpathNameTest, name, start)
+	return nil
+}
+
+// ncname scans a name without colons, and returns an empty string if there is none.
+func (l *xpathLexer) ncname() string {
+	start := l.pos
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if r == ':' || !unicode.Is(nameStartChars, r) && (l.pos == start || !unicode.Is(nameChars, r)) {
+			break
+		}
+		l.pos += size
+	}
+	return l.input[start:l.pos]
+}
+
+// qname scans a QName, and returns an empty string if there is none.
+func (l *xpathLexer) qname() string {
+	start := l.pos
+	if l.ncname() == "" {
+		return ""
+	}
+	if strings.HasPrefix(l.input[l.pos:], ":") {
+		l.pos++
+		if l.ncname() == "" {
+			l.pos = start
+			return ""
+		}
+	}
+	return l.input[start:l.pos]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// xpathParser is a recursive descent parser for XPath expressions. Prefixes of names are
+// resolved to namespace URIs while parsing, using the resolver.
+type xpathParser struct {
+	tokens   []xpathToken
+	pos      int
+	resolver XPathNSResolver
+}
+
+// compileXPath parses the expression, and returns the tree of the compiled expression.
+func compileXPath(expression string, resolver XPathNSResolver) (xpathExpr, error) {
+	lexer := &xpathLexer{input: expression}
+	tokens, err := lexer.tokenize()
+	if err != nil {
+		return nil, err
+	}
+	p := &xpathParser{tokens: tokens, resolver: resolver}
+	expr, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != xpathEOF {
+		return nil, p.unexpected()
+	}
+	return expr, nil
+}
+
+func (p *xpathParser) peek() xpathToken {
+	return p.tokens[p.pos]
+}
+
+// is returns true if the next token is of the given kind and has one of the values.
+func (p *xpathParser) is(kind xpathTokenKind, values ...string) bool {
+	t := p.peek()
+	if t.kind != kind {
+		return false
+	}
+	for _, v := range values {
+		if t.value == v {
+			return true
+		}
+	}
+	return len(values) == 0
+}
+
+func (p *xpathParser) advance() xpathToken {
+	t := p.tokens[p.pos]
+	if t.kind != xpathEOF {
+		p.pos++
+	}
+	return t
+}
+
+// expect consumes the given punctuation, or returns an error.
+func (p *xpathParser) expect(value string) error {
+	if !p.is(xpathPunctuation, value) {
+		return fmt.Errorf("%v: expected '%v' at offset %d", ErrorInvalidExpression, value, p.peek().pos)
+	}
+	p.advance()
+	return nil
+}
+
+func (p *xpathParser) unexpected() error {
+	t := p.peek()
+	if t.kind == xpathEOF {
+		return fmt.Errorf("%v: unexpected end of expression", ErrorInvalidExpression)
+	}
+	return fmt.Errorf("%v: unexpected '%v' at offset %d", ErrorInvalidExpression, t.value, t.pos)
+}
+
+// resolve resolves the prefix of a QName, and returns the namespace URI and local name.
+func (p *xpathParser) resolve(qname string) (string, string, error) {
+	index := strings.IndexByte(qname, ':')
+	if index < 0 {
+		return "", qname, nil
+	}
+	prefix, local := qname[:index], qname[index+1:]
+	if prefix == "xml" {
+		return XMLNamespaceURI, local, nil
+	}
+	if p.resolver != nil {
+		if uri, ok := p.resolver.LookupNamespaceURI(prefix); ok && uri != "" {
+			return uri, local, nil
+		}
+	}
+	return "", "", fmt.Errorf("%v: prefix '%v' can not be resolved", ErrorNamespace, prefix)
+}
+
+// binary parses a left-associative binary expression, with operands parsed by next.
+func (p *xpathParser) binary(next func() (xpathExpr, error), operators ...string) (xpathExpr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(xpathOperator, operators...) {
+		op := p.advance().value
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &xpathBinary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *xpathParser) expr() (xpathExpr, error) {
+	return p.binary(p.andExpr, "or")
+}
+
+func (p *xpathParser) andExpr() (xpathExpr, error) {
+	return p.binary(p.equalityExpr, "and")
+}
+
+func (p *xpathParser) equalityExpr() (xpathExpr, error) {
+	return p.binary(p.relationalExpr, "=", "!=")
+}
+
+func (p *xpathParser) relationalExpr() (xpathExpr, error) {
+	return p.binary(p.additiveExpr, "<", ">", "<=", ">=")
+}
+
+func (p *xpathParser) additiveExpr() (xpathExpr, error) {
+	return p.binary(p.multiplicativeExpr, "+", "-")
+}
+
+func (p *xpathParser) multiplicativeExpr() (xpathExpr, error) {
+	return p.binary(p.unaryExpr, "*", "div", "mod")
+}
+
+func (p *xpathParser) unaryExpr() (xpathExpr, error) {
+	if p.is(xpathOperator, "-") {
+		p.advance()
+		expr, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &xpathNegate{expr: expr}, nil
+	}
+	return p.binary(p.pathExpr, "|")
+}
+
+// pathExpr parses either a location path, or a filter expression optionally followed by
+// a relative location path.
+func (p *xpathParser) pathExpr() (xpathExpr, error) {
+	t := p.peek()
+	primary := t.kind == xpathVariable || t.kind == xpathLiteral || t.kind == xpathNumber ||
+		t.kind == xpathFunctionName || t.kind == xpathPunctuation && t.value == "("
+	if !primary {
+		return p.locationPath()
+	}
+
+	filter, err := p.filterExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.is(xpathOperator, "/", "//") {
+		return filter, nil
+	}
+	path := &xpathPath{filter: filter}
+	if err := p.relativeLocationPath(path); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+func (p *xpathParser) filterExpr() (xpathExpr, error) {
+	primary, err := p.primaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	predicates, err := p.predicates()
+	if err != nil {
+		return nil, err
+	}
+	if len(predicates) == 0 {
+		return primary, nil
+	}
+	return &xpathFilter{expr: primary, predicates: predicates}, nil
+}
+
+func (p *xpathParser) primaryExpr() (xpathExpr, error) {
+	t := p.advance()
+	switch t.kind {
+	case xpathVariable:
+		return nil, fmt.Errorf("%v: variable '$%v' is not bound", ErrorInvalidExpression, t.value)
+	case xpathLiteral:
+		return xpathLiteralExpr(t.value), nil
+	case xpathNumber:
+		n, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid number '%v'", ErrorInvalidExpression, t.value)
+		}
+		return xpathNumberExpr(n), nil
+	case xpathFunctionName:
+		return p.functionCall(t)
+	}
+
+	// Must be a parenthesized expression.
+	expr, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+func (p *xpathParser) functionCall(name xpathToken) (xpathExpr, error) {
+	fn, ok := xpathFunctions[name.value]
+	if !ok {
+		return nil, fmt.Errorf("%v: unknown function '%v'", ErrorInvalidExpression, name.value)
+	}
+	call := &xpathCall{name: name.value, fn: fn}
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for !p.is(xpathPunctuation, ")") {
+		if len(call.args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+	}
+	p.advance()
+
+	if len(call.args) < fn.minArgs || fn.maxArgs >= 0 && len(call.args) > fn.maxArgs {
+		return nil, fmt.Errorf("%v: wrong number of arguments for function '%v'", ErrorInvalidExpression, name.value)
+	}
+	return call, nil
+}
+
+func (p *xpathParser) predicates() ([]xpathExpr, error) {
+	var predicates []xpathExpr
+	for p.is(xpathPunctuation, "[") {
+		p.advance()
+		predicate, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+// locationPath parses an absolute or relative location path.
+func (p *xpathParser) locationPath() (xpathExpr, error) {
+	path := &xpathPath{}
+	if p.is(xpathOperator, "/") {
+		p.advance()
+		path.absolute = true
+		// The root by itself is a complete path, if no step follows.
+		if !p.stepFollows() {
+			return path, nil
+		}
+	} else if p.is(xpathOperator, "//") {
+		path.absolute = true
+	} else {
+		step, err := p.step()
+		if err != nil {
+			return nil, err
+		}
+		path.steps = append(path.steps, step)
+	}
+	if err := p.relativeLocationPath(path); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// stepFollows returns true if the next token starts a step.
+func (p *xpathParser) stepFollows() bool {
+	t := p.peek()
+	switch t.kind {
+	case xpathNameTest, xpathNodeType, xpathAxisName:
+		return true
+	case xpathPunctuation:
+		return t.value == "." || t.value == ".." || t.value == "@"
+	}
+	return false
+}
+
+// relativeLocationPath parses the steps of a path, and adds them to the path. Each step
+// is preceded by a / or //, except the first step of a relative location path, which is
+// already parsed, and the first step of an absolute path starting with a single /.
+func (p *xpathParser) relativeLocationPath(path *xpathPath) error {
+	first := path.absolute && len(path.steps) == 0 && !p.is(xpathOperator, "//")
+	for first || p.is(xpathOperator, "/", "//") {
+		if !first && p.advance().value == "//" {
+			path.steps = append(path.steps, &xpathStep{axis: "descendant-or-self", test: xpathNodeTest{nodeType: "node"}})
+		}
+		first = false
+		step, err := p.step()
+		if err != nil {
+			return err
+		}
+		path.steps = append(path.steps, step)
+	}
+	return nil
+}
+
+func (p *xpathParser) step() (*xpathStep, error) {
+	if p.is(xpathPunctuation, ".") {
+		p.advance()
+		return &xpathStep{axis: "self", test: xpathNodeTest{nodeType: "node"}}, nil
+	}
+	if p.is(xpathPunctuation, "..") {
+		p.advance()
+		return &xpathStep{axis: "parent", test: xpathNodeTest{nodeType: "node"}}, nil
+	}
+
+	step := &xpathStep{axis: "child"}
+	if p.is(xpathPunctuation, "@") {
+		p.advance()
+		step.axis = "attribute"
+	} else if p.is(xpathAxisName) {
+		step.axis = p.advance().value
+		if _, ok := xpathAxes[step.axis]; !ok {
+			return nil, fmt.Errorf("%v: unknown axis '%v'", ErrorInvalidExpression, step.axis)
+		}
+		p.advance() // ::
+	}
+
+	t := p.peek()
+	switch t.kind {
+	case xpathNameTest:
+		p.advance()
+		step.test.name = "*"
+		if t.value != "*" {
+			var err error
+			if step.test.namespaceURI, step.test.name, err = p.resolve(t.value); err != nil {
+				return nil, err
+			}
+		}
+	case xpathNodeType:
+		p.advance()
+		step.test.nodeType = t.value
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		if t.value == "processing-instruction" && p.is(xpathLiteral) {
+			step.test.target = p.advance().value
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.unexpected()
+	}
+
+	var err error
+	step.predicates, err = p.predicates()
+	return step, err
+}
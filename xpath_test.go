@@ -0,0 +1,480 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+var exampleDocXPath = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE library [<!ATTLIST book id ID #IMPLIED><!ENTITY pub "Acme">]>
+<library xmlns:b="urn:books" xml:lang="en-GB"><b:book id="b1" year="2001"><title>Go</title><price>10</price></b:book><b:book id="b2" year="1999" b:rare="yes"><title xml:lang="nl">XML &amp; <![CDATA[DOM]]></title><price>25.5</price></b:book><!-- comment --><magazine><title>&pub; News</title></magazine><?pi data?></library>`
+
+// xpathMapResolver resolves prefixes using a map.
+type xpathMapResolver map[string]string
+
+func (r xpathMapResolver) LookupNamespaceURI(prefix string) (string, bool) {
+	uri, ok := r[prefix]
+	return uri, ok
+}
+
+// describeNodes returns a short description of each node, for comparison in tests.
+func describeNodes(nodes []Node) string {
+	var s []string
+	for _, n := range nodes {
+		switch n.GetNodeType() {
+		case ElementNode:
+			s = append(s, n.GetNodeName())
+		case AttributeNode:
+			s = append(s, "@"+n.GetNodeName()+"="+n.GetNodeValue())
+		case XPathNamespaceNode:
+			s = append(s, "ns:"+n.GetNodeName())
+		case DocumentNode:
+			s = append(s, "/")
+		default:
+			s = append(s, fmt.Sprintf("%s(%s)", n.GetNodeType(), xpathStringValue(n)))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func TestXPathNodeSets(t *testing.T) {
+	doc := mustParse(t, exampleDocXPath)
+	resolver := xpathMapResolver{"bk": "urn:books"}
+
+	var tests = []struct {
+		expression string
+		expected   string
+	}{
+		{"/", "/"},
+		{"/library", "library"},
+		{"/library/*", "book book magazine"},
+		{"/library/bk:book", "book book"},
+		{"/library/book", ""},
+		{"//title", "title title title"},
+		{"//bk:book[2]/title", "title"},
+		{"//bk:book[@year < 2000]/price", "price"},
+		{"//bk:book[price > 20]/@id", "@id=b2"},
+		{"//bk:book/@*", "@id=b1 @year=2001 @id=b2 @rare=yes @year=1999"},
+		{"//bk:book/@bk:*", "@rare=yes"},
+		{"//@id", "@id=b1 @id=b2"},
+		{"//title[1]", "title title title"},
+		{"(//title)[1]", "title"},
+		{"(//title)[last()]", "title"},
+		{"//title/text()", "TEXT_NODE(Go) TEXT_NODE(XML & DOM) TEXT_NODE(Acme News)"},
+		{"//comment()", "COMMENT_NODE( comment )"},
+		{"//processing-instruction()", "PROCESSING_INSTRUCTION_NODE(data)"},
+		{"//processing-instruction('other')", ""},
+		{"//processing-instruction('pi')", "PROCESSING_INSTRUCTION_NODE(data)"},
+		{"//price/..", "book book"},
+		{"//price/parent::*/@id", "@id=b1 @id=b2"},
+		{"//price/ancestor::*", "library book book"},
+		{"//magazine/ancestor-or-self::node()", "/ library magazine"},
+		{"//magazine/preceding-sibling::*", "book book"},
+		{"//magazine/preceding-sibling::*[1]/@id", "@id=b2"},
+		{"//bk:book[1]/following-sibling::node()", "book COMMENT_NODE( comment ) magazine PROCESSING_INSTRUCTION_NODE(data)"},
+		{"//bk:book[2]/following::*", "magazine title"},
+		{"//bk:book[2]/preceding::*", "book title price"},
+		{"//bk:book[2]/preceding::*[1]", "price"},
+		{"//bk:book[2]/@year/following::price", "price"},
+		{"//bk:book[1]/descendant::*", "title price"},
+		{"//bk:book[1]/descendant-or-self::*", "book title price"},
+		{"//bk:book[1]/self::bk:book/title", "title"},
+		{"//bk:book[1]/self::magazine", ""},
+		{"//title | //price | //title", "title price title price title"},
+		{"//*[@id='b1' or @id='b2'][position() = last()]", "book"},
+		{"//*[title = 'Go']", "book"},
+		{"//*[not(@*)]", "title price price magazine title"},
+		{"//*[lang('en')]", "library book title price book price magazine title"},
+		{"//*[lang('nl')]", "title"},
+		{"id('b2 b1')/title", "title title"},
+		{"id(//@id)/@year", "@year=2001 @year=1999"},
+		{"//title[.='XML & DOM']/../price", "price"},
+		{"//magazine/title[contains(., 'Acme')]", "title"},
+	}
+
+	for _, test := range tests {
+		result, err := NewXPathEvaluator().Evaluate(test.expression, doc, resolver, XPathOrderedNodeSnapshotType)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.expression, err)
+			continue
+		}
+		var nodes []Node
+		length, _ := result.GetSnapshotLength()
+		for i := 0; i < length; i++ {
+			n, _ := result.SnapshotItem(i)
+			nodes = append(nodes, n)
+		}
+		if actual := describeNodes(nodes); actual != test.expected {
+			t.Errorf("%v: expected '%v', got '%v'", test.expression, test.expected, actual)
+		}
+	}
+}
+
+func TestXPathValues(t *testing.T) {
+	doc := mustParse(t, exampleDocXPath)
+
+	var tests = []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"7 mod 3", 1.0},
+		{"-7 mod 3", -1.0},
+		{"7 div 2", 3.5},
+		{"- - 2", 2.0},
+		{"1 div 0", math.Inf(1)},
+		{"count(//title)", 3.0},
+		{"sum(//price)", 35.5},
+		{"sum(//title)", math.NaN()},
+		{"number('  12.5 ')", 12.5},
+		{"number('1e5')", math.NaN()},
+		{"number(true())", 1.0},
+		{"floor(-1.5)", -2.0},
+		{"ceiling(1.2)", 2.0},
+		{"round(2.5)", 3.0},
+		{"round(-2.5)", -2.0},
+		{"string-length('héllo')", 5.0},
+		{"string(1 div 0)", "Infinity"},
+		{"string(0 div 0)", "NaN"},
+		{"string(-0)", "0"},
+		{"string(12.0)", "12"},
+		{"string(0.5)", "0.5"},
+		{"string(//price)", "10"},
+		{"string(//nothing)", ""},
+		{"concat('a', 1, true())", "a1true"},
+		{"substring('12345', 2, 3)", "234"},
+		{"substring('12345', 1.5, 2.6)", "234"},
+		{"substring('12345', 0, 3)", "12"},
+		{"substring('12345', 0 div 0, 3)", ""},
+		{"substring('12345', -42, 1 div 0)", "12345"},
+		{"substring-before('1999/04/01', '/')", "1999"},
+		{"substring-after('1999/04/01', '/')", "04/01"},
+		{"normalize-space('  a \t b  ')", "a b"},
+		{"translate('bar', 'abc', 'ABC')", "BAr"},
+		{"translate('--aaa--', 'a-', 'A')", "AAA"},
+		{"name(//bk:book)", "book"},
+		{"local-name(//@bk:rare)", "rare"},
+		{"namespace-uri(//bk:book)", "urn:books"},
+		{"name(//comment())", ""},
+		{"string(//magazine)", "Acme News"},
+		{"string(//title[2])", ""},
+		{"//price = 25.5", true},
+		{"//price != 10", true},
+		{"//price > 30", false},
+		{"//price = //title", false},
+		{"//nothing = //nothing", false},
+		{"//nothing != ''", false},
+		{"//price = true()", true},
+		{"1 < 2 and 2 <= 2", true},
+		{"'a' = 'a' or 1 div 0", true},
+		{"0 div 0 = 0 div 0", false},
+		{"0 div 0 != 0 div 0", true},
+		{"starts-with('abc', 'ab')", true},
+		{"boolean('')", false},
+		{"boolean(//title)", true},
+		{"not(0)", true},
+		{"lang('en')", false},
+	}
+
+	resolver := xpathMapResolver{"bk": "urn:books"}
+	for _, test := range tests {
+		result, err := NewXPathEvaluator().Evaluate(test.expression, doc, resolver, XPathAnyType)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.expression, err)
+			continue
+		}
+
+		var actual interface{}
+		switch result.GetResultType() {
+		case XPathNumberType:
+			actual, _ = result.GetNumberValue()
+		case XPathStringType:
+			actual, _ = result.GetStringValue()
+		case XPathBooleanType:
+			actual, _ = result.GetBooleanValue()
+		default:
+			t.Errorf("%v: unexpected result type %v", test.expression, result.GetResultType())
+			continue
+		}
+
+		if f, ok := test.expected.(float64); ok && math.IsNaN(f) {
+			if !math.IsNaN(actual.(float64)) {
+				t.Errorf("%v: expected NaN, got '%v'", test.expression, actual)
+			}
+		} else if actual != test.expected {
+			t.Errorf("%v: expected '%v', got '%v'", test.expression, test.expected, actual)
+		}
+	}
+}
+
+func TestXPathContextNode(t *testing.T) {
+	doc := mustParse(t, exampleDocXPath)
+	book := doc.GetElementsByTagName("book")[1]
+	year := book.GetAttributes().GetNamedItem("year")
+
+	var tests = []struct {
+		context    Node
+		expression string
+		expected   string
+	}{
+		{book, "title", "title"},
+		{book, ".", "book"},
+		{book, "/library", "library"},
+		{book, "@year", "@year=1999"},
+		{year, "..", "book"},
+		{year, "../@id", "@id=b2"},
+		{year, "self::node()", "@year=1999"},
+		{year, "following-sibling::node()", ""},
+		{year, "child::node()", ""},
+		{doc.GetDocumentElement().GetLastChild(), "preceding-sibling::comment()", "COMMENT_NODE( comment )"},
+	}
+
+	for _, test := range tests {
+		result, err := NewXPathEvaluator().Evaluate(test.expression, test.context, nil, XPathOrderedNodeIteratorType)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.expression, err)
+			continue
+		}
+		var nodes []Node
+		for n, _ := result.IterateNext(); n != nil; n, _ = result.IterateNext() {
+			nodes = append(nodes, n)
+		}
+		if actual := describeNodes(nodes); actual != test.expected {
+			t.Errorf("%v: expected '%v', got '%v'", test.expression, test.expected, actual)
+		}
+	}
+
+	// Adjacent text nodes are a single text node, represented by the first of them.
+	title := doc.GetElementsByTagName("title")[1]
+	cdata := title.GetLastChild()
+	result, err := NewXPathEvaluator().Evaluate(".", cdata, nil, XPathFirstOrderedNodeType)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	if n, _ := result.GetSingleNodeValue(); n != title.GetFirstChild() {
+		t.Errorf("expected the first text node, got '%v'", n)
+	}
+
+	// Nodes in a fragment, which have no Document as root.
+	frag := doc.CreateDocumentFragment()
+	a, _ := doc.CreateElement("a")
+	b, _ := doc.CreateElement("b")
+	frag.AppendChild(a)
+	frag.AppendChild(b)
+	result, err = NewXPathEvaluator().Evaluate("/*[2]", a, nil, XPathFirstOrderedNodeType)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	if n, _ := result.GetSingleNodeValue(); n != b {
+		t.Errorf("expected '%v', got '%v'", b, n)
+	}
+}
+
+func TestXPathNamespaces(t *testing.T) {
+	doc := NewDocument()
+	root, _ := doc.CreateElementNS("urn:root", "r:root")
+	root.SetAttribute("xmlns:r", "urn:root")
+	root.SetAttribute("xmlns:x", "urn:x")
+	root.SetAttribute("xmlns", "urn:default")
+	child, _ := doc.CreateElementNS("urn:x", "x:child")
+	child.SetAttribute("xmlns", "")
+	child.SetAttribute("x:attr", "value")
+	root.AppendChild(child)
+	doc.AppendChild(root)
+
+	evaluator := NewXPathEvaluator()
+	resolver := evaluator.CreateNSResolver(root)
+
+	result, err := evaluator.Evaluate("/r:root/x:child", doc, resolver, XPathFirstOrderedNodeType)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+	if n, _ := result.GetSingleNodeValue(); n != child {
+		t.Errorf("expected '%v', got '%v'", child, n)
+	}
+
+	// The namespace declarations are not attributes in XPath, but namespace nodes.
+	result, _ = evaluator.Evaluate("count(/r:root/@*)", doc, resolver, XPathNumberType)
+	if count, _ := result.GetNumberValue(); count != 0 {
+		t.Errorf("expected no attributes, got %v", count)
+	}
+
+	var tests = []struct {
+		expression string
+		expected   string
+	}{
+		{"/r:root/namespace::*", "ns: ns:r ns:x ns:xml"},
+		{"/r:root/x:child/namespace::node()", "ns:r ns:x ns:xml"},
+		{"/r:root/x:child/namespace::x", "ns:x"},
+		{"/r:root/namespace::x/..", "r:root"},
+		{"/r:root/x:child/namespace::*[1] | /r:root/namespace::*[1]", "ns: ns:r"},
+		{"/r:root/namespace::* | /r:root/x:child/@*", "ns: ns:r ns:x ns:xml @x:attr=value"},
+	}
+	for _, test := range tests {
+		result, err := evaluator.Evaluate(test.expression, doc, resolver, XPathOrderedNodeSnapshotType)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.expression, err)
+			continue
+		}
+		var nodes []Node
+		length, _ := result.GetSnapshotLength()
+		for i := 0; i < length; i++ {
+			n, _ := result.SnapshotItem(i)
+			nodes = append(nodes, n)
+		}
+		if actual := describeNodes(nodes); actual != test.expected {
+			t.Errorf("%v: expected '%v', got '%v'", test.expression, test.expected, actual)
+		}
+	}
+
+	result, _ = evaluator.Evaluate("string(/r:root/namespace::x)", doc, resolver, XPathStringType)
+	if s, _ := result.GetStringValue(); s != "urn:x" {
+		t.Errorf("expected 'urn:x', got '%v'", s)
+	}
+	result, _ = evaluator.Evaluate("/r:root/namespace::x", doc, resolver, XPathFirstOrderedNodeType)
+	ns, _ := result.GetSingleNodeValue()
+	if ns.(XPathNamespace).GetOwnerElement() != root || ns.GetParentNode() != nil {
+		t.Errorf("expected the namespace node to be owned by the root, without a parent")
+	}
+
+	// Unknown prefixes can not be resolved.
+	_, err = evaluator.CreateExpression("//unknown:child", resolver)
+	if !errors.Is(err, ErrorNamespace) && !strings.Contains(fmt.Sprint(err), "NAMESPACE_ERR") {
+		t.Errorf("expected a namespace error, got '%v'", err)
+	}
+	if _, err = evaluator.CreateExpression("//x:child", nil); err == nil {
+		t.Error("expected error, got none")
+	}
+}
+
+func TestXPathExpressionReuse(t *testing.T) {
+	doc := mustParse(t, exampleDocXPath)
+	expr, err := NewXPathEvaluator().CreateExpression("count(*)", nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		t.FailNow()
+	}
+
+	for _, book := range doc.GetElementsByTagName("book") {
+		result, err := expr.Evaluate(book, XPathNumberType)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if count, _ := result.GetNumberValue(); count != 2 {
+			t.Errorf("expected 2, got %v", count)
+		}
+	}
+
+	result, _ := expr.Evaluate(doc, XPathStringType)
+	if s, _ := result.GetStringValue(); s != "1" {
+		t.Errorf("expected '1', got '%v'", s)
+	}
+}
+
+func TestXPathResultTypes(t *testing.T) {
+	doc := mustParse(t, exampleDocXPath)
+	evaluator := NewXPathEvaluator()
+
+	// Scalars can be converted to any other scalar type.
+	result, _ := evaluator.Evaluate("//price", doc, nil, XPathNumberType)
+	if n, _ := result.GetNumberValue(); n != 10 {
+		t.Errorf("expected 10, got %v", n)
+	}
+	result, _ = evaluator.Evaluate("'0'", doc, nil, XPathBooleanType)
+	if b, _ := result.GetBooleanValue(); !b {
+		t.Error("expected true")
+	}
+
+	// But not to node-sets.
+	if _, err := evaluator.Evaluate("1", doc, nil, XPathUnorderedNodeSnapshotType); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	// The getters must match the result type.
+	result, _ = evaluator.Evaluate("//title", doc, nil, XPathAnyType)
+	if result.GetResultType() != XPathUnorderedNodeIteratorType {
+		t.Errorf("expected %v, got %v", XPathUnorderedNodeIteratorType, result.GetResultType())
+	}
+	if _, err := result.GetNumberValue(); err == nil {
+		t.Error("expected error, got none")
+	}
+	if _, err := result.SnapshotItem(0); err == nil {
+		t.Error("expected error, got none")
+	}
+	if _, err := result.GetSingleNodeValue(); err == nil {
+		t.Error("expected error, got none")
+	}
+	if n, err := result.IterateNext(); err != nil || n == nil {
+		t.Errorf("expected a node, got '%v' (%v)", n, err)
+	}
+	if result.GetInvalidIteratorState() {
+		t.Error("expected a valid iterator")
+	}
+
+	result, _ = evaluator.Evaluate("//nothing", doc, nil, XPathAnyUnorderedNodeType)
+	if n, err := result.GetSingleNodeValue(); err != nil || n != nil {
+		t.Errorf("expected no node, got '%v' (%v)", n, err)
+	}
+	if _, err := result.IterateNext(); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	result, _ = evaluator.Evaluate("//title", doc, nil, XPathOrderedNodeSnapshotType)
+	if n, _ := result.SnapshotItem(3); n != nil {
+		t.Errorf("expected nil, got '%v'", n)
+	}
+
+	// Nodes which can not be used as context.
+	if _, err := evaluator.Evaluate(".", doc.GetDoctype(), nil, XPathAnyType); err == nil {
+		t.Error("expected error, got none")
+	}
+	if _, err := evaluator.Evaluate(".", nil, nil, XPathAnyType); err == nil {
+		t.Error("expected error, got none")
+	}
+}
+
+func TestXPathInvalidExpressions(t *testing.T) {
+	var expressions = []string{
+		"",
+		"/library/",
+		"//",
+		"1 +",
+		"(1",
+		"foo(",
+		"unknown()",
+		"count()",
+		"count(1, 2)",
+		"true(1)",
+		"$var",
+		"child::",
+		"sideways::node()",
+		"//title[",
+		"@",
+		"'unterminated",
+		"1 ! 2",
+		"node(1)",
+		"processing-instruction(1)",
+	}
+
+	for _, expression := range expressions {
+		if _, err := NewXPathEvaluator().CreateExpression(expression, nil); err == nil {
+			t.Errorf("%v: expected error, got none", expression)
+		}
+	}
+
+	// Type errors only occur during evaluation.
+	doc := mustParse(t, exampleDocXPath)
+	for _, expression := range []string{"1 | //title", "count(1)", "(1)[1]", "'a'/b"} {
+		if _, err := NewXPathEvaluator().Evaluate(expression, doc, nil, XPathAnyType); err == nil {
+			t.Errorf("%v: expected error, got none", expression)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package dom
+
+import (
+	"fmt"
+)
+
+// domXPathNamespace is a namespace node, as returned by the namespace axis of an XPath
+// expression. It is read-only, and not part of the tree: its parent is nil.
+type domXPathNamespace struct {
+	ownerElement Element
+	state        nodeState // The state the DOM keeps for this node.
+
+	// XPathNamespace specific things:
+	prefix       string
+	namespaceURI string
+}
+
+func newXPathNamespace(owner Element, prefix, namespaceURI string) *domXPathNamespace {
+	ns := &domXPathNamespace{}
+	ns.ownerElement = owner
+	ns.prefix = prefix
+	ns.namespaceURI = namespaceURI
+	return ns
+}
+
+// GetNodeName returns the prefix of the namespace, or an empty string for the default namespace.
+func (ns *domXPathNamespace) GetNodeName() string {
+	return ns.prefix
+}
+
+func (ns *domXPathNamespace) GetNodeType() NodeType {
+	return XPathNamespaceNode
+}
+
+// GetNodeValue returns the namespace URI which is bound to the prefix.
+func (ns *domXPathNamespace) GetNodeValue() string {
+	return ns.namespaceURI
+}
+
+func (ns *domXPathNamespace) GetLocalName() string {
+	return ns.prefix
+}
+
+func (ns *domXPathNamespace) GetChildNodes() []Node {
+	return nil
+}
+
+// GetParentNode returns nil, since namespace nodes are not part of the tree.
+func (ns *domXPathNamespace) GetParentNode() Node {
+	return nil
+}
+
+func (ns *domXPathNamespace) GetFirstChild() Node {
+	return nil
+}
+
+func (ns *domXPathNamespace) GetLastChild() Node {
+	return nil
+}
+
+func (ns *domXPathNamespace) GetAttributes() NamedNodeMap {
+	return nil
+}
+
+func (ns *domXPathNamespace) HasAttributes() bool {
+	return false
+}
+
+func (ns *domXPathNamespace) GetOwnerDocument() Document {
+	return ns.ownerElement.GetOwnerDocument()
+}
+
+func (ns *domXPathNamespace) AppendChild(child Node) error {
+	return ErrorNoModificationAllowed
+}
+
+func (ns *domXPathNamespace) RemoveChild(oldChild Node) (Node, error) {
+	return nil, ErrorNoModificationAllowed
+}
+
+func (ns *domXPathNamespace) ReplaceChild(newChild, oldChild Node) (Node, error) {
+	return nil, ErrorNoModificationAllowed
+}
+
+func (ns *domXPathNamespace) InsertBefore(newChild, refChild Node) (Node, error) {
+	return nil, ErrorNoModificationAllowed
+}
+
+func (ns *domXPathNamespace) HasChildNodes() bool {
+	return false
+}
+
+func (ns *domXPathNamespace) GetPreviousSibling() Node {
+	return nil
+}
+
+func (ns *domXPathNamespace) GetNextSibling() Node {
+	return nil
+}
+
+func (ns *domXPathNamespace) GetNamespaceURI() string {
+	return ""
+}
+
+func (ns *domXPathNamespace) GetNamespacePrefix() string {
+	return ""
+}
+
+func (ns *domXPathNamespace) LookupPrefix(namespace string) (string, bool) {
+	return ns.ownerElement.LookupPrefix(namespace)
+}
+
+func (ns *domXPathNamespace) LookupNamespaceURI(pfx string) (string, bool) {
+	return ns.ownerElement.LookupNamespaceURI(pfx)
+}
+
+func (ns *domXPathNamespace) IsDefaultNamespace(namespace string) bool {
+	return ns.ownerElement.IsDefaultNamespace(namespace)
+}
+
+func (ns *domXPathNamespace) GetTextContent() string {
+	return ns.namespaceURI
+}
+
+// SetTextContent does nothing, since namespace nodes are read-only.
+func (ns *domXPathNamespace) SetTextContent(content string) {
+	// no-op.
+}
+
+func (ns *domXPathNamespace) CloneNode(deep bool) Node {
+	return ns.cloneNode(nil, deep, NodeCloned)
+}
+
+// cloneNode ignores the owner, since a namespace node belongs to its owner element.
+func (ns *domXPathNamespace) cloneNode(owner Document, deep bool, operation UserDataOperation) Node {
+	clone := newXPathNamespace(ns.ownerElement, ns.prefix, ns.namespaceURI)
+	notifyUserDataHandlers(operation, ns, clone)
+	return clone
+}
+
+func (ns *domXPathNamespace) Normalize() {
+	normalize(ns)
+}
+
+func (ns *domXPathNamespace) IsSameNode(other Node) bool {
+	return isSameNode(ns, other)
+}
+
+func (ns *domXPathNamespace) IsEqualNode(other Node) bool {
+	return isEqualNode(ns, other)
+}
+
+func (ns *domXPathNamespace) CompareDocumentPosition(other Node) DocumentPosition {
+	return compareDocumentPosition(ns, other)
+}
+
+func (ns *domXPathNamespace) SetUserData(key string, data interface{}, handler UserDataHandler) interface{} {
+	return setUserData(ns, key, data, handler)
+}
+
+func (ns *domXPathNamespace) GetUserData(key string) interface{} {
+	return getUserData(ns, key)
+}
+
+func (ns *domXPathNamespace) ImportNode(n Node, deep bool) Node {
+	return importNode(ns.GetOwnerDocument(), n, deep)
+}
+
+func (ns *domXPathNamespace) AddEventListener(eventType string, listener EventListener, useCapture bool) {
+	addEventListener(ns, eventType, listener, useCapture)
+}
+
+func (ns *domXPathNamespace) RemoveEventListener(eventType string, listener EventListener, useCapture bool) {
+	removeEventListener(ns, eventType, listener, useCapture)
+}
+
+func (ns *domXPathNamespace) DispatchEvent(evt Event) (bool, error) {
+	return dispatchEvent(ns, evt)
+}
+
+// Private functions. Namespace nodes can not be moved, so these do nothing.
+func (ns *domXPathNamespace) setParentNode(parent Node) {
+}
+
+func (ns *domXPathNamespace) setOwnerDocument(doc Document) {
+}
+
+// XPathNamespace specifics:
+
+// GetOwnerElement returns the Element on which the namespace is in scope.
+func (ns *domXPathNamespace) GetOwnerElement() Element {
+	return ns.ownerElement
+}
+
+func (ns *domXPathNamespace) getState() *nodeState {
+	return &ns.state
+}
+
+func (ns *domXPathNamespace) String() string {
+	return fmt.Sprintf("%s: xmlns:%s='%s'", ns.GetNodeType(), ns.prefix, ns.namespaceURI)
+}